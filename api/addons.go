@@ -19,12 +19,16 @@
 package api
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/nuts-foundation/nuts-event-octopus/pkg"
 )
 
 func convert(e pkg.Event) Event {
 	return Event{
 		Error:                e.Error,
+		ErrorClass:           e.ErrorClass,
 		InitiatorLegalEntity: Identifier(e.InitiatorLegalEntity),
 		ConsentId:            &e.ConsentID,
 		ExternalId:           e.ExternalID,
@@ -32,9 +36,68 @@ func convert(e pkg.Event) Event {
 		Payload:              e.Payload,
 		RetryCount:           e.RetryCount,
 		Uuid:                 e.UUID,
+		CreatedAt:            e.CreatedAt,
+		UpdatedAt:            e.UpdatedAt,
+		TransitionedAt:       e.TransitionedAt,
+		Version:              e.Version,
 	}
 }
 
+func toQuery(params ListParams) pkg.EventQuery {
+	query := pkg.EventQuery{
+		HasError:      params.HasError,
+		MinRetryCount: params.MinRetryCount,
+		MaxRetryCount: params.MaxRetryCount,
+		Since:         params.Since,
+		Until:         params.Until,
+	}
+
+	if params.Name != nil {
+		query.Name = *params.Name
+	}
+	if params.InitiatorLegalEntity != nil {
+		query.InitiatorLegalEntity = string(*params.InitiatorLegalEntity)
+	}
+	if params.ConsentId != nil {
+		query.ConsentID = *params.ConsentId
+	}
+	if params.TransactionId != nil {
+		query.TransactionID = *params.TransactionId
+	}
+	if params.Sort != nil {
+		query.Sort = *params.Sort
+	}
+	if params.Cursor != nil {
+		query.Cursor = *params.Cursor
+	}
+	if params.Limit != nil {
+		query.Limit = *params.Limit
+	}
+
+	return query
+}
+
+func filterToQuery(filter EventFilter) pkg.EventQuery {
+	query := pkg.EventQuery{
+		MinRetryCount: filter.MinRetryCount,
+		MaxRetryCount: filter.MaxRetryCount,
+		Since:         filter.Since,
+		Until:         filter.Until,
+	}
+
+	if filter.InitiatorLegalEntity != nil {
+		query.InitiatorLegalEntity = string(*filter.InitiatorLegalEntity)
+	}
+	if filter.ConsentId != nil {
+		query.ConsentID = *filter.ConsentId
+	}
+	if filter.TransactionId != nil {
+		query.TransactionID = *filter.TransactionId
+	}
+
+	return query
+}
+
 func convertList(e *[]pkg.Event) []Event {
 	events := make([]Event, len(*e))
 
@@ -44,3 +107,233 @@ func convertList(e *[]pkg.Event) []Event {
 
 	return events
 }
+
+func convertHistory(h []pkg.EventHistory) []EventHistory {
+	history := make([]EventHistory, len(h))
+
+	for i, el := range h {
+		var channel *string
+		if el.Channel != "" {
+			channel = &h[i].Channel
+		}
+		history[i] = EventHistory{
+			Channel:    channel,
+			Error:      el.Error,
+			Name:       el.Name,
+			Rejected:   el.Rejected,
+			RetryCount: el.RetryCount,
+			Timestamp:  el.CreatedAt,
+			Uuid:       el.UUID,
+		}
+	}
+
+	return history
+}
+
+func convertWebhook(w pkg.Webhook) Webhook {
+	webhook := Webhook{
+		CreatedAt: w.CreatedAt,
+		Id:        w.ID,
+		Signed:    w.Secret != "",
+		Url:       w.URL,
+	}
+
+	if names := w.Names(); len(names) > 0 {
+		webhook.EventNames = &names
+	}
+	if w.InitiatorLegalEntity != "" {
+		legalEntity := Identifier(w.InitiatorLegalEntity)
+		webhook.InitiatorLegalEntity = &legalEntity
+	}
+
+	return webhook
+}
+
+func toWebhook(r WebhookRegistration) pkg.Webhook {
+	webhook := pkg.Webhook{
+		URL: r.Url,
+	}
+
+	if r.EventNames != nil {
+		webhook.EventNames = strings.Join(*r.EventNames, ",")
+	}
+	if r.InitiatorLegalEntity != nil {
+		webhook.InitiatorLegalEntity = string(*r.InitiatorLegalEntity)
+	}
+	if r.Secret != nil {
+		webhook.Secret = *r.Secret
+	}
+
+	return webhook
+}
+
+func convertDeadLetter(dl pkg.DeadLetter) DeadLetter {
+	deadLetter := DeadLetter{
+		CreatedAt: dl.CreatedAt,
+		Id:        dl.ID,
+		Reason:    dl.Reason,
+		Sequence:  int64(dl.Sequence),
+		Subject:   dl.Subject,
+	}
+
+	if len(dl.Data) > 0 {
+		data := dl.Data
+		deadLetter.Data = &data
+	}
+
+	return deadLetter
+}
+
+func convertReplayStatus(s pkg.ReplayStatus) ReplayStatus {
+	status := ReplayStatus{
+		FinishedAt:   s.FinishedAt,
+		LastSequence: int64(s.LastSequence),
+		Replayed:     s.Replayed,
+		Running:      s.Running,
+		Sequence:     int64(s.Sequence),
+		StartedAt:    s.StartedAt,
+	}
+
+	if s.Error != "" {
+		status.Error = &s.Error
+	}
+
+	return status
+}
+
+func convertRetentionStatus(s pkg.RetentionStatus) RetentionStatus {
+	status := RetentionStatus{
+		Rules:       make([]RetentionRule, len(s.Rules)),
+		TotalPurged: convertPurged(s.TotalPurged),
+	}
+
+	for i, r := range s.Rules {
+		rule := RetentionRule{Name: r.Name}
+		if r.MaxAge > 0 {
+			maxAge := r.MaxAge.String()
+			rule.MaxAge = &maxAge
+		}
+		if r.MaxCount > 0 {
+			maxCount := r.MaxCount
+			rule.MaxCount = &maxCount
+		}
+		status.Rules[i] = rule
+	}
+
+	if s.LastRun != nil {
+		lastRun := convertRetentionReport(*s.LastRun)
+		status.LastRun = &lastRun
+	}
+
+	return status
+}
+
+func convertRetentionReport(r pkg.RetentionReport) RetentionReport {
+	report := RetentionReport{
+		DryRun:     r.DryRun,
+		StartedAt:  r.StartedAt,
+		FinishedAt: r.FinishedAt,
+		Purged:     convertPurged(r.Purged),
+	}
+
+	if r.Error != "" {
+		report.Error = &r.Error
+	}
+
+	return report
+}
+
+// convertPurged converts the number of purged events per state to a list sorted on state
+func convertPurged(purged map[string]int) []PurgeCount {
+	counts := make([]PurgeCount, 0, len(purged))
+	for name, count := range purged {
+		counts = append(counts, PurgeCount{Name: name, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Name < counts[j].Name
+	})
+
+	return counts
+}
+
+func convertDiagnostics(d pkg.DiagnosticsReport) Diagnostics {
+	diagnostics := Diagnostics{
+		Nats: NatsStatus{
+			Up:        d.Nats.Up,
+			Mode:      d.Nats.Mode,
+			Address:   d.Nats.Address,
+			ClusterId: d.Nats.ClusterID,
+		},
+		Db:          DBStatus{Up: d.DB.Up},
+		Checkpoints: make([]Checkpoint, len(d.Checkpoints)),
+		Channels: ChannelDiagnostics{
+			Channels: make([]ChannelStats, len(d.Channels.Channels)),
+		},
+		EventStates: EventStateDiagnostics{
+			States: make([]StateCount, len(d.EventStates.States)),
+		},
+		RetryQueues: RetryQueueDiagnostics{
+			Events:   convertRetryQueues(d.RetryQueues.Events),
+			Webhooks: convertRetryQueues(d.RetryQueues.Webhooks),
+		},
+		CircuitBreaker: CircuitBreakerStatus{
+			Open:        d.CircuitBreaker.Open,
+			Failures:    d.CircuitBreaker.Failures,
+			Trips:       d.CircuitBreaker.Trips,
+			OpenedAt:    d.CircuitBreaker.OpenedAt,
+			LastFailure: d.CircuitBreaker.LastFailure,
+		},
+	}
+
+	if d.Nats.LastError != "" {
+		diagnostics.Nats.LastError = &d.Nats.LastError
+	}
+	if d.DB.Error != "" {
+		diagnostics.Db.Error = &d.DB.Error
+	}
+	if d.CheckpointError != "" {
+		diagnostics.CheckpointError = &d.CheckpointError
+	}
+	if d.Channels.Error != "" {
+		diagnostics.Channels.Error = &d.Channels.Error
+	}
+	if d.EventStates.Error != "" {
+		diagnostics.EventStates.Error = &d.EventStates.Error
+	}
+	if d.CircuitBreaker.LastError != "" {
+		diagnostics.CircuitBreaker.LastError = &d.CircuitBreaker.LastError
+	}
+
+	for i, c := range d.Checkpoints {
+		diagnostics.Checkpoints[i] = Checkpoint{
+			Sequence:  int64(c.Sequence),
+			Service:   c.Service,
+			Subject:   c.Subject,
+			UpdatedAt: c.UpdatedAt,
+		}
+	}
+	for i, c := range d.Channels.Channels {
+		diagnostics.Channels.Channels[i] = ChannelStats{
+			Name:          c.Name,
+			Messages:      c.Messages,
+			Bytes:         int64(c.Bytes),
+			FirstSequence: int64(c.FirstSequence),
+			LastSequence:  int64(c.LastSequence),
+			Subscriptions: c.Subscriptions,
+			Durables:      c.Durables,
+		}
+	}
+	for i, s := range d.EventStates.States {
+		diagnostics.EventStates.States[i] = StateCount{Name: s.Name, Count: s.Count}
+	}
+
+	return diagnostics
+}
+
+func convertRetryQueues(queues []pkg.RetryQueue) []RetryQueue {
+	converted := make([]RetryQueue, len(queues))
+	for i, q := range queues {
+		converted[i] = RetryQueue{Subject: q.Subject, Delay: q.Delay.String()}
+	}
+	return converted
+}
@@ -19,6 +19,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/labstack/echo/v4"
@@ -30,18 +31,25 @@ type Wrapper struct {
 	Eo *pkg.EventOctopus
 }
 
-// List returns all events from the eventStore
-func (w Wrapper) List(ctx echo.Context) error {
-	events, err := w.Eo.List()
+// List returns a page of events from the eventStore, optionally filtered and sorted
+func (w Wrapper) List(ctx echo.Context, params ListParams) error {
+	page, err := w.Eo.Query(toQuery(params))
+
+	if errors.Is(err, pkg.ErrUnknownSortField) || errors.Is(err, pkg.ErrInvalidCursor) {
+		return ctx.String(400, err.Error())
+	}
 
 	if err != nil {
 		return fmt.Errorf("Error during fetching list of events from DB: %v", err)
 	}
 
-	ce := convertList(events)
+	ce := convertList(&page.Events)
 	resp := EventListResponse{
 		Events: &ce,
 	}
+	if page.Next != "" {
+		resp.Next = &page.Next
+	}
 
 	return ctx.JSON(200, resp)
 }
@@ -79,3 +87,288 @@ func (w Wrapper) GetEventByExternalId(ctx echo.Context, externalId string) error
 
 	return ctx.JSON(200, resp)
 }
+
+// GetEventHistory returns all state transitions of a specific event by its uuid
+func (w Wrapper) GetEventHistory(ctx echo.Context, uuid string) error {
+	history, err := w.Eo.GetEventHistory(uuid)
+
+	if err != nil {
+		return fmt.Errorf("Error while fetching event history from DB: %v", err)
+	}
+
+	if len(history) == 0 {
+		return ctx.NoContent(404)
+	}
+
+	resp := EventHistoryResponse{
+		History: convertHistory(history),
+	}
+
+	return ctx.JSON(200, resp)
+}
+
+// RetryEvent resets and republishes a specific errored event by its uuid
+func (w Wrapper) RetryEvent(ctx echo.Context, uuid string) error {
+	event, err := w.Eo.RetryEvent(uuid)
+
+	if errors.Is(err, pkg.ErrNotErrored) || errors.Is(err, pkg.ErrNoRetryState) || errors.Is(err, pkg.ErrConflict) {
+		return ctx.String(409, err.Error())
+	}
+
+	if err != nil {
+		return fmt.Errorf("Error while retrying event: %v", err)
+	}
+
+	if event == nil {
+		return ctx.NoContent(404)
+	}
+
+	return ctx.JSON(200, convert(*event))
+}
+
+// RetryEvents resets and republishes all errored events matching the filter in the body
+func (w Wrapper) RetryEvents(ctx echo.Context) error {
+	filter := RetryEventsJSONBody{}
+
+	if err := ctx.Bind(&filter); err != nil {
+		return ctx.String(400, fmt.Sprintf("Invalid filter: %v", err))
+	}
+
+	retried, err := w.Eo.RetryEvents(filterToQuery(EventFilter(filter)))
+
+	if err != nil {
+		return fmt.Errorf("Error while retrying events: %v", err)
+	}
+
+	return ctx.JSON(200, RetryResponse{Retried: retried})
+}
+
+// ListWebhooks returns all registered webhooks
+func (w Wrapper) ListWebhooks(ctx echo.Context) error {
+	webhooks, err := w.Eo.Webhooks()
+
+	if err != nil {
+		return fmt.Errorf("Error while fetching webhooks from DB: %v", err)
+	}
+
+	resp := WebhookListResponse{
+		Webhooks: make([]Webhook, len(webhooks)),
+	}
+	for i, wh := range webhooks {
+		resp.Webhooks[i] = convertWebhook(wh)
+	}
+
+	return ctx.JSON(200, resp)
+}
+
+// RegisterWebhook stores a new webhook from the registration in the body
+func (w Wrapper) RegisterWebhook(ctx echo.Context) error {
+	registration := RegisterWebhookJSONBody{}
+
+	if err := ctx.Bind(&registration); err != nil {
+		return ctx.String(400, fmt.Sprintf("Invalid registration: %v", err))
+	}
+
+	webhook, err := w.Eo.RegisterWebhook(toWebhook(WebhookRegistration(registration)))
+
+	if errors.Is(err, pkg.ErrInvalidWebhook) {
+		return ctx.String(400, err.Error())
+	}
+
+	if err != nil {
+		return fmt.Errorf("Error while storing webhook: %v", err)
+	}
+
+	return ctx.JSON(201, convertWebhook(*webhook))
+}
+
+// GetWebhook returns a specific webhook by its id
+func (w Wrapper) GetWebhook(ctx echo.Context, id string) error {
+	webhook, err := w.Eo.GetWebhook(id)
+
+	if err != nil {
+		return fmt.Errorf("Error while fetching webhook from DB: %v", err)
+	}
+
+	if webhook == nil {
+		return ctx.NoContent(404)
+	}
+
+	return ctx.JSON(200, convertWebhook(*webhook))
+}
+
+// DeleteWebhook removes a specific webhook by its id
+func (w Wrapper) DeleteWebhook(ctx echo.Context, id string) error {
+	deleted, err := w.Eo.DeleteWebhook(id)
+
+	if err != nil {
+		return fmt.Errorf("Error while removing webhook from DB: %v", err)
+	}
+
+	if !deleted {
+		return ctx.NoContent(404)
+	}
+
+	return ctx.NoContent(204)
+}
+
+// ListDeadLetters returns all dead letters
+func (w Wrapper) ListDeadLetters(ctx echo.Context) error {
+	deadLetters, err := w.Eo.DeadLetters()
+
+	if err != nil {
+		return fmt.Errorf("Error while fetching dead letters from DB: %v", err)
+	}
+
+	resp := DeadLetterListResponse{
+		DeadLetters: make([]DeadLetter, len(deadLetters)),
+	}
+	for i, dl := range deadLetters {
+		resp.DeadLetters[i] = convertDeadLetter(dl)
+	}
+
+	return ctx.JSON(200, resp)
+}
+
+// GetDeadLetter returns a specific dead letter by its id
+func (w Wrapper) GetDeadLetter(ctx echo.Context, id string) error {
+	deadLetter, err := w.Eo.GetDeadLetter(id)
+
+	if err != nil {
+		return fmt.Errorf("Error while fetching dead letter from DB: %v", err)
+	}
+
+	if deadLetter == nil {
+		return ctx.NoContent(404)
+	}
+
+	return ctx.JSON(200, convertDeadLetter(*deadLetter))
+}
+
+// RequeueDeadLetter publishes the original message of a dead letter to its original subject
+func (w Wrapper) RequeueDeadLetter(ctx echo.Context, id string) error {
+	requeued, err := w.Eo.RequeueDeadLetter(id)
+
+	if err != nil {
+		return fmt.Errorf("Error while requeueing dead letter: %v", err)
+	}
+
+	if !requeued {
+		return ctx.NoContent(404)
+	}
+
+	return ctx.NoContent(204)
+}
+
+// DiscardDeadLetter removes a specific dead letter by its id
+func (w Wrapper) DiscardDeadLetter(ctx echo.Context, id string) error {
+	discarded, err := w.Eo.DiscardDeadLetter(id)
+
+	if err != nil {
+		return fmt.Errorf("Error while removing dead letter from DB: %v", err)
+	}
+
+	if !discarded {
+		return ctx.NoContent(404)
+	}
+
+	return ctx.NoContent(204)
+}
+
+// ListCheckpoints returns the checkpoints of all subscribing services
+func (w Wrapper) ListCheckpoints(ctx echo.Context) error {
+	checkpoints, err := w.Eo.Checkpoints()
+
+	if err != nil {
+		return fmt.Errorf("Error while fetching checkpoints from DB: %v", err)
+	}
+
+	resp := CheckpointListResponse{
+		Checkpoints: make([]Checkpoint, len(checkpoints)),
+	}
+	for i, c := range checkpoints {
+		resp.Checkpoints[i] = Checkpoint{
+			Sequence:  int64(c.Sequence),
+			Service:   c.Service,
+			Subject:   c.Subject,
+			UpdatedAt: c.UpdatedAt,
+		}
+	}
+
+	return ctx.JSON(200, resp)
+}
+
+// ResetCheckpoint removes the checkpoint of a service for a subject
+func (w Wrapper) ResetCheckpoint(ctx echo.Context, service string, subject string) error {
+	reset, err := w.Eo.ResetCheckpoint(service, subject)
+
+	if err != nil {
+		return fmt.Errorf("Error while removing checkpoint from DB: %v", err)
+	}
+
+	if !reset {
+		return ctx.NoContent(404)
+	}
+
+	return ctx.NoContent(204)
+}
+
+// GetReplayStatus returns the progress of the running or last replay
+func (w Wrapper) GetReplayStatus(ctx echo.Context) error {
+	return ctx.JSON(200, convertReplayStatus(w.Eo.ReplayStatus()))
+}
+
+// StartReplay rebuilds the event store from the event log in the background
+func (w Wrapper) StartReplay(ctx echo.Context) error {
+	err := w.Eo.StartReplay()
+
+	if errors.Is(err, pkg.ErrReplayRunning) {
+		return ctx.String(409, err.Error())
+	}
+
+	if err != nil {
+		return fmt.Errorf("Error while starting replay: %v", err)
+	}
+
+	return ctx.JSON(202, convertReplayStatus(w.Eo.ReplayStatus()))
+}
+
+// GetDiagnostics returns the state of Nats, the event store, the channels and the retry queues
+func (w Wrapper) GetDiagnostics(ctx echo.Context) error {
+	return ctx.JSON(200, convertDiagnostics(w.Eo.DiagnosticsReport()))
+}
+
+// GetRetentionStatus returns the retention rules, the last enforcement and the number of purged events
+func (w Wrapper) GetRetentionStatus(ctx echo.Context) error {
+	return ctx.JSON(200, convertRetentionStatus(w.Eo.RetentionStatus()))
+}
+
+// Purge enforces the retention rules, a dry run only reports the events that would be purged
+func (w Wrapper) Purge(ctx echo.Context, params PurgeParams) error {
+	dryRun := params.DryRun != nil && *params.DryRun
+
+	report, err := w.Eo.Purge(dryRun)
+
+	if err != nil {
+		return fmt.Errorf("Error while enforcing retention rules: %v", err)
+	}
+
+	return ctx.JSON(200, convertRetentionReport(report))
+}
+
+// ListTransitions returns the states that can follow each state of the consent request flow
+func (w Wrapper) ListTransitions(ctx echo.Context) error {
+	transitions := pkg.Transitions()
+
+	resp := TransitionListResponse{
+		Transitions: make([]Transition, len(transitions)),
+	}
+	for i, t := range transitions {
+		resp.Transitions[i] = Transition{
+			From: t.From,
+			To:   t.To,
+		}
+	}
+
+	return ctx.JSON(200, resp)
+}
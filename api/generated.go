@@ -6,20 +6,124 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/deepmap/oapi-codegen/pkg/runtime"
 	"github.com/labstack/echo/v4"
 )
 
+// ChannelDiagnostics defines model for ChannelDiagnostics.
+type ChannelDiagnostics struct {
+	Channels []ChannelStats `json:"channels"`
+
+	// reason the channel statistics are not available
+	Error *string `json:"error,omitempty"`
+}
+
+// ChannelStats defines model for ChannelStats.
+type ChannelStats struct {
+	Bytes int64 `json:"bytes"`
+
+	// number of durable subscriptions, including offline ones
+	Durables      int   `json:"durables"`
+	FirstSequence int64 `json:"firstSequence"`
+	LastSequence  int64 `json:"lastSequence"`
+
+	// number of messages stored in the channel
+	Messages      int    `json:"messages"`
+	Name          string `json:"name"`
+	Subscriptions int    `json:"subscriptions"`
+}
+
+// Checkpoint defines model for Checkpoint.
+type Checkpoint struct {
+
+	// sequence number of the last processed event
+	Sequence  int64     `json:"sequence"`
+	Service   string    `json:"service"`
+	Subject   string    `json:"subject"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CheckpointListResponse defines model for CheckpointListResponse.
+type CheckpointListResponse struct {
+	Checkpoints []Checkpoint `json:"checkpoints"`
+}
+
+// CircuitBreakerStatus defines model for CircuitBreakerStatus.
+type CircuitBreakerStatus struct {
+
+	// number of consecutive failures to process a message
+	Failures    int        `json:"failures"`
+	LastError   *string    `json:"lastError,omitempty"`
+	LastFailure *time.Time `json:"lastFailure,omitempty"`
+
+	// true when the event store paused processing messages, they are left for redelivery
+	Open     bool       `json:"open"`
+	OpenedAt *time.Time `json:"openedAt,omitempty"`
+
+	// number of times the breaker opened since start
+	Trips int `json:"trips"`
+}
+
+// DBStatus defines model for DBStatus.
+type DBStatus struct {
+	Error *string `json:"error,omitempty"`
+	Up    bool    `json:"up"`
+}
+
+// DeadLetter defines model for DeadLetter.
+type DeadLetter struct {
+	CreatedAt time.Time `json:"createdAt"`
+
+	// base64 encoded original message
+	Data *[]byte `json:"data,omitempty"`
+	Id   string  `json:"id"`
+
+	// reason the message could not be processed
+	Reason string `json:"reason"`
+
+	// sequence number of the message on its subject
+	Sequence int64 `json:"sequence"`
+
+	// channel the message was received on
+	Subject string `json:"subject"`
+}
+
+// DeadLetterListResponse defines model for DeadLetterListResponse.
+type DeadLetterListResponse struct {
+	DeadLetters []DeadLetter `json:"deadLetters"`
+}
+
+// Diagnostics defines model for Diagnostics.
+type Diagnostics struct {
+	Channels ChannelDiagnostics `json:"channels"`
+
+	// reason the checkpoints could not be read
+	CheckpointError *string               `json:"checkpointError,omitempty"`
+	Checkpoints     []Checkpoint          `json:"checkpoints"`
+	CircuitBreaker  CircuitBreakerStatus  `json:"circuitBreaker"`
+	Db              DBStatus              `json:"db"`
+	EventStates     EventStateDiagnostics `json:"eventStates"`
+	Nats            NatsStatus            `json:"nats"`
+	RetryQueues     RetryQueueDiagnostics `json:"retryQueues"`
+}
+
 // Event defines model for Event.
 type Event struct {
 
 	// V4 UUID assigned by Corda to a record
 	ConsentId *string `json:"consentId,omitempty"`
 
+	// moment the event was first stored
+	CreatedAt time.Time `json:"createdAt"`
+
 	// error reason in case of a functional error
 	Error *string `json:"error,omitempty"`
 
+	// classification of the error, selects the retry policy together with the name
+	ErrorClass *string `json:"errorClass,omitempty"`
+
 	// ID calculated by crypto using BSN and private key of initiatorLegalEntity
 	ExternalId string `json:"externalId"`
 
@@ -36,29 +140,375 @@ type Event struct {
 	// V4 UUID assigned by Corda to a transaction
 	TransactionId *string `json:"transactionId,omitempty"`
 
+	// moment the event entered its current state (name)
+	TransitionedAt time.Time `json:"transitionedAt"`
+
+	// moment the event was last stored
+	UpdatedAt time.Time `json:"updatedAt"`
+
 	// V4 UUID
 	Uuid string `json:"uuid"`
+
+	// incremented on every change of the event
+	Version int `json:"version"`
+}
+
+// EventFilter defines model for EventFilter.
+type EventFilter struct {
+	ConsentId *string `json:"consentId,omitempty"`
+
+	// Generic identifier used for representing BSN, agbcode, etc. It's always constructed as an URN followed by a double colon (:) and then the identifying value of the given URN
+	InitiatorLegalEntity *Identifier `json:"initiatorLegalEntity,omitempty"`
+	MaxRetryCount        *int        `json:"maxRetryCount,omitempty"`
+	MinRetryCount        *int        `json:"minRetryCount,omitempty"`
+
+	// only events updated at or after the given moment
+	Since         *time.Time `json:"since,omitempty"`
+	TransactionId *string    `json:"transactionId,omitempty"`
+
+	// only events updated before the given moment
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// EventHistory defines model for EventHistory.
+type EventHistory struct {
+
+	// channel the event was received on, empty when stored directly
+	Channel *string `json:"channel,omitempty"`
+
+	// error reason in case of a functional error
+	Error *string `json:"error,omitempty"`
+
+	// name of the event after the transition
+	Name string `json:"name"`
+
+	// true when the transition was not valid and did not change the event, the error holds the reason
+	Rejected bool `json:"rejected"`
+
+	// 0 to X
+	RetryCount int `json:"retryCount"`
+
+	// moment the transition was stored
+	Timestamp time.Time `json:"timestamp"`
+
+	// V4 UUID of the event
+	Uuid string `json:"uuid"`
+}
+
+// EventHistoryResponse defines model for EventHistoryResponse.
+type EventHistoryResponse struct {
+	History []EventHistory `json:"history"`
 }
 
 // EventListResponse defines model for EventListResponse.
 type EventListResponse struct {
 	Events *[]Event `json:"events,omitempty"`
+
+	// cursor to the next page, absent when this is the last page
+	Next *string `json:"next,omitempty"`
+}
+
+// EventStateDiagnostics defines model for EventStateDiagnostics.
+type EventStateDiagnostics struct {
+	Error *string `json:"error,omitempty"`
+
+	// number of events per state in the event store
+	States []StateCount `json:"states"`
 }
 
 // Identifier defines model for Identifier.
 type Identifier string
 
+// NatsStatus defines model for NatsStatus.
+type NatsStatus struct {
+	Address   string  `json:"address"`
+	ClusterId string  `json:"clusterId"`
+	LastError *string `json:"lastError,omitempty"`
+
+	// embedded or cluster
+	Mode string `json:"mode"`
+	Up   bool   `json:"up"`
+}
+
+// PurgeCount defines model for PurgeCount.
+type PurgeCount struct {
+	Count int    `json:"count"`
+	Name  string `json:"name"`
+}
+
+// ReplayStatus defines model for ReplayStatus.
+type ReplayStatus struct {
+
+	// reason the replay stopped before the end of the event log
+	Error      *string    `json:"error,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+
+	// sequence number of the last message in the consentRequest channel when the replay started
+	LastSequence int64 `json:"lastSequence"`
+
+	// number of events stored by the replay
+	Replayed int  `json:"replayed"`
+	Running  bool `json:"running"`
+
+	// sequence number of the last replayed message of the consentRequest channel
+	Sequence  int64      `json:"sequence"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+}
+
+// RetentionReport defines model for RetentionReport.
+type RetentionReport struct {
+
+	// true when no events were purged
+	DryRun bool `json:"dryRun"`
+
+	// reason the enforcement failed
+	Error      *string   `json:"error,omitempty"`
+	FinishedAt time.Time `json:"finishedAt"`
+
+	// number of events purged per state, or that would be purged in a dry run
+	Purged    []PurgeCount `json:"purged"`
+	StartedAt time.Time    `json:"startedAt"`
+}
+
+// RetentionRule defines model for RetentionRule.
+type RetentionRule struct {
+
+	// time an event is kept after it entered the state, all events are kept when empty
+	MaxAge *string `json:"maxAge,omitempty"`
+
+	// number of events kept, the events that entered the state first are purged first. All events are kept when 0
+	MaxCount *int `json:"maxCount,omitempty"`
+
+	// name of the state the rule applies to
+	Name string `json:"name"`
+}
+
+// RetentionStatus defines model for RetentionStatus.
+type RetentionStatus struct {
+	LastRun *RetentionReport `json:"lastRun,omitempty"`
+	Rules   []RetentionRule  `json:"rules"`
+
+	// number of events purged per state since the start, dry runs excluded
+	TotalPurged []PurgeCount `json:"totalPurged"`
+}
+
+// RetryQueue defines model for RetryQueue.
+type RetryQueue struct {
+
+	// time a message waits in the queue before it is published again
+	Delay   string `json:"delay"`
+	Subject string `json:"subject"`
+}
+
+// RetryQueueDiagnostics defines model for RetryQueueDiagnostics.
+type RetryQueueDiagnostics struct {
+	Events   []RetryQueue `json:"events"`
+	Webhooks []RetryQueue `json:"webhooks"`
+}
+
+// RetryResponse defines model for RetryResponse.
+type RetryResponse struct {
+
+	// number of events that have been retried
+	Retried int `json:"retried"`
+}
+
+// StateCount defines model for StateCount.
+type StateCount struct {
+	Count int    `json:"count"`
+	Name  string `json:"name"`
+}
+
+// Transition defines model for Transition.
+type Transition struct {
+
+	// name of the current state
+	From string `json:"from"`
+
+	// names of the states that can follow
+	To []string `json:"to"`
+}
+
+// TransitionListResponse defines model for TransitionListResponse.
+type TransitionListResponse struct {
+	Transitions []Transition `json:"transitions"`
+}
+
+// Webhook defines model for Webhook.
+type Webhook struct {
+	CreatedAt  time.Time `json:"createdAt"`
+	EventNames *[]string `json:"eventNames,omitempty"`
+	Id         string    `json:"id"`
+
+	// Generic identifier used for representing BSN, agbcode, etc. It's always constructed as an URN followed by a double colon (:) and then the identifying value of the given URN
+	InitiatorLegalEntity *Identifier `json:"initiatorLegalEntity,omitempty"`
+
+	// true when the notifications are signed, the secret itself is never returned
+	Signed bool   `json:"signed"`
+	Url    string `json:"url"`
+}
+
+// WebhookListResponse defines model for WebhookListResponse.
+type WebhookListResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// WebhookRegistration defines model for WebhookRegistration.
+type WebhookRegistration struct {
+
+	// names of the events to be notified of, all events when empty
+	EventNames *[]string `json:"eventNames,omitempty"`
+
+	// Generic identifier used for representing BSN, agbcode, etc. It's always constructed as an URN followed by a double colon (:) and then the identifying value of the given URN
+	InitiatorLegalEntity *Identifier `json:"initiatorLegalEntity,omitempty"`
+
+	// shared secret for signing the notifications
+	Secret *string `json:"secret,omitempty"`
+
+	// absolute http(s) url the events are posted to
+	Url string `json:"url"`
+}
+
+// ListParams defines parameters for List.
+type ListParams struct {
+
+	// only return events with the given name
+	Name *string `json:"name,omitempty"`
+
+	// only return events initiated by the given legal entity
+	InitiatorLegalEntity *Identifier `json:"initiatorLegalEntity,omitempty"`
+
+	// only return events for the given consentId
+	ConsentId *string `json:"consentId,omitempty"`
+
+	// only return events for the given transactionId
+	TransactionId *string `json:"transactionId,omitempty"`
+
+	// only return events with (true) or without (false) an error
+	HasError *bool `json:"hasError,omitempty"`
+
+	// only return events with at least the given retryCount
+	MinRetryCount *int `json:"minRetryCount,omitempty"`
+
+	// only return events with at most the given retryCount
+	MaxRetryCount *int `json:"maxRetryCount,omitempty"`
+
+	// only return events updated at or after the given moment
+	Since *time.Time `json:"since,omitempty"`
+
+	// only return events updated before the given moment
+	Until *time.Time `json:"until,omitempty"`
+
+	// cursor to the next page as returned in the previous page, the other parameters must be the same
+	Cursor *string `json:"cursor,omitempty"`
+
+	// maximum number of events in the page, defaults to 100, at most 1000
+	Limit *int `json:"limit,omitempty"`
+
+	// field to sort the events on, prefix with a '-' to sort descending
+	Sort *string `json:"sort,omitempty"`
+}
+
+// RetryEventsJSONBody defines parameters for RetryEvents.
+type RetryEventsJSONBody EventFilter
+
+// StreamParams defines parameters for Stream.
+type StreamParams struct {
+
+	// only stream events with the given externalId
+	ExternalId *string `json:"externalId,omitempty"`
+
+	// only stream events initiated by the given legal entity
+	InitiatorLegalEntity *Identifier `json:"initiatorLegalEntity,omitempty"`
+}
+
+// PurgeParams defines parameters for Purge.
+type PurgeParams struct {
+
+	// only report the events that would be purged
+	DryRun *bool `json:"dryRun,omitempty"`
+}
+
+// RegisterWebhookJSONBody defines parameters for RegisterWebhook.
+type RegisterWebhookJSONBody WebhookRegistration
+
+// RetryEventsRequestBody defines body for RetryEvents for application/json ContentType.
+type RetryEventsJSONRequestBody RetryEventsJSONBody
+
+// RegisterWebhookRequestBody defines body for RegisterWebhook for application/json ContentType.
+type RegisterWebhookJSONRequestBody RegisterWebhookJSONBody
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
-	// Return all events currently in store
+	// Return the sequence number of the last event processed per service and subject
+	// (GET /checkpoints)
+	ListCheckpoints(ctx echo.Context) error
+	// Reset the checkpoint of a service for a subject
+	// (DELETE /checkpoints/{service}/{subject})
+	ResetCheckpoint(ctx echo.Context, service string, subject string) error
+	// Return all dead letters, oldest first
+	// (GET /deadletters)
+	ListDeadLetters(ctx echo.Context) error
+	// Discard a dead letter
+	// (DELETE /deadletters/{id})
+	DiscardDeadLetter(ctx echo.Context, id string) error
+	// Find a specific dead letter
+	// (GET /deadletters/{id})
+	GetDeadLetter(ctx echo.Context, id string) error
+	// Publish the original message of a dead letter to its original subject again and remove the dead letter
+	// (POST /deadletters/{id}/requeue)
+	RequeueDeadLetter(ctx echo.Context, id string) error
+	// Return the state of Nats, the event store, the channels and the retry queues
+	// (GET /diagnostics)
+	GetDiagnostics(ctx echo.Context) error
+	// Return a page of the events currently in store, optionally filtered
 	// (GET /events)
-	List(ctx echo.Context) error
+	List(ctx echo.Context, params ListParams) error
 	// Find a specific event by its externalId
 	// (GET /events/by_external_id/{external_id})
 	GetEventByExternalId(ctx echo.Context, externalId string) error
+	// Retry all errored events matching the filter
+	// (POST /events/retry)
+	RetryEvents(ctx echo.Context) error
+	// Follow new events as Server-Sent Events, optionally filtered
+	// (GET /events/stream)
+	Stream(ctx echo.Context, params StreamParams) error
 	// Find a specific event
 	// (GET /events/{uuid})
 	GetEvent(ctx echo.Context, uuid string) error
+	// Return all state transitions of a specific event, oldest first
+	// (GET /events/{uuid}/history)
+	GetEventHistory(ctx echo.Context, uuid string) error
+	// Retry an errored event
+	// (POST /events/{uuid}/retry)
+	RetryEvent(ctx echo.Context, uuid string) error
+	// Return the progress of the running or last replay of the event log
+	// (GET /replay)
+	GetReplayStatus(ctx echo.Context) error
+	// Rebuild the event store from the entire event log
+	// (POST /replay)
+	StartReplay(ctx echo.Context) error
+	// Return the retention rules, the last enforcement and the number of purged events
+	// (GET /retention)
+	GetRetentionStatus(ctx echo.Context) error
+	// Enforce the retention rules
+	// (POST /retention)
+	Purge(ctx echo.Context, params PurgeParams) error
+	// Return the consent request flow: the states that can follow each state
+	// (GET /transitions)
+	ListTransitions(ctx echo.Context) error
+	// Return all registered webhooks
+	// (GET /webhooks)
+	ListWebhooks(ctx echo.Context) error
+	// Register a webhook to be notified of event state changes
+	// (POST /webhooks)
+	RegisterWebhook(ctx echo.Context) error
+	// Remove a webhook, pending deliveries are dropped
+	// (DELETE /webhooks/{id})
+	DeleteWebhook(ctx echo.Context, id string) error
+	// Find a specific webhook
+	// (GET /webhooks/{id})
+	GetWebhook(ctx echo.Context, id string) error
 }
 
 // ServerInterfaceWrapper converts echo contexts to parameters.
@@ -66,12 +516,197 @@ type ServerInterfaceWrapper struct {
 	Handler ServerInterface
 }
 
+// ListCheckpoints converts echo context to params.
+func (w *ServerInterfaceWrapper) ListCheckpoints(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ListCheckpoints(ctx)
+	return err
+}
+
+// ResetCheckpoint converts echo context to params.
+func (w *ServerInterfaceWrapper) ResetCheckpoint(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "service" -------------
+	var service string
+
+	err = runtime.BindStyledParameter("simple", false, "service", ctx.Param("service"), &service)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter service: %s", err))
+	}
+
+	// ------------- Path parameter "subject" -------------
+	var subject string
+
+	err = runtime.BindStyledParameter("simple", false, "subject", ctx.Param("subject"), &subject)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter subject: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ResetCheckpoint(ctx, service, subject)
+	return err
+}
+
+// ListDeadLetters converts echo context to params.
+func (w *ServerInterfaceWrapper) ListDeadLetters(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ListDeadLetters(ctx)
+	return err
+}
+
+// DiscardDeadLetter converts echo context to params.
+func (w *ServerInterfaceWrapper) DiscardDeadLetter(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameter("simple", false, "id", ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DiscardDeadLetter(ctx, id)
+	return err
+}
+
+// GetDeadLetter converts echo context to params.
+func (w *ServerInterfaceWrapper) GetDeadLetter(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameter("simple", false, "id", ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetDeadLetter(ctx, id)
+	return err
+}
+
+// RequeueDeadLetter converts echo context to params.
+func (w *ServerInterfaceWrapper) RequeueDeadLetter(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameter("simple", false, "id", ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RequeueDeadLetter(ctx, id)
+	return err
+}
+
+// GetDiagnostics converts echo context to params.
+func (w *ServerInterfaceWrapper) GetDiagnostics(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetDiagnostics(ctx)
+	return err
+}
+
 // List converts echo context to params.
 func (w *ServerInterfaceWrapper) List(ctx echo.Context) error {
 	var err error
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListParams
+	// ------------- Optional query parameter "name" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "name", ctx.QueryParams(), &params.Name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter name: %s", err))
+	}
+
+	// ------------- Optional query parameter "initiatorLegalEntity" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "initiatorLegalEntity", ctx.QueryParams(), &params.InitiatorLegalEntity)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter initiatorLegalEntity: %s", err))
+	}
+
+	// ------------- Optional query parameter "consentId" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "consentId", ctx.QueryParams(), &params.ConsentId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter consentId: %s", err))
+	}
+
+	// ------------- Optional query parameter "transactionId" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "transactionId", ctx.QueryParams(), &params.TransactionId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter transactionId: %s", err))
+	}
+
+	// ------------- Optional query parameter "hasError" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "hasError", ctx.QueryParams(), &params.HasError)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter hasError: %s", err))
+	}
+
+	// ------------- Optional query parameter "minRetryCount" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "minRetryCount", ctx.QueryParams(), &params.MinRetryCount)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter minRetryCount: %s", err))
+	}
+
+	// ------------- Optional query parameter "maxRetryCount" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "maxRetryCount", ctx.QueryParams(), &params.MaxRetryCount)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter maxRetryCount: %s", err))
+	}
+
+	// ------------- Optional query parameter "since" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "since", ctx.QueryParams(), &params.Since)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter since: %s", err))
+	}
+
+	// ------------- Optional query parameter "until" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "until", ctx.QueryParams(), &params.Until)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter until: %s", err))
+	}
+
+	// ------------- Optional query parameter "cursor" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", ctx.QueryParams(), &params.Cursor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter cursor: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", ctx.QueryParams(), &params.Sort)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter sort: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.List(ctx)
+	err = w.Handler.List(ctx, params)
 	return err
 }
 
@@ -91,6 +726,40 @@ func (w *ServerInterfaceWrapper) GetEventByExternalId(ctx echo.Context) error {
 	return err
 }
 
+// RetryEvents converts echo context to params.
+func (w *ServerInterfaceWrapper) RetryEvents(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RetryEvents(ctx)
+	return err
+}
+
+// Stream converts echo context to params.
+func (w *ServerInterfaceWrapper) Stream(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params StreamParams
+	// ------------- Optional query parameter "externalId" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "externalId", ctx.QueryParams(), &params.ExternalId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter externalId: %s", err))
+	}
+
+	// ------------- Optional query parameter "initiatorLegalEntity" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "initiatorLegalEntity", ctx.QueryParams(), &params.InitiatorLegalEntity)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter initiatorLegalEntity: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Stream(ctx, params)
+	return err
+}
+
 // GetEvent converts echo context to params.
 func (w *ServerInterfaceWrapper) GetEvent(ctx echo.Context) error {
 	var err error
@@ -107,6 +776,142 @@ func (w *ServerInterfaceWrapper) GetEvent(ctx echo.Context) error {
 	return err
 }
 
+// GetEventHistory converts echo context to params.
+func (w *ServerInterfaceWrapper) GetEventHistory(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "uuid" -------------
+	var uuid string
+
+	err = runtime.BindStyledParameter("simple", false, "uuid", ctx.Param("uuid"), &uuid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter uuid: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetEventHistory(ctx, uuid)
+	return err
+}
+
+// RetryEvent converts echo context to params.
+func (w *ServerInterfaceWrapper) RetryEvent(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "uuid" -------------
+	var uuid string
+
+	err = runtime.BindStyledParameter("simple", false, "uuid", ctx.Param("uuid"), &uuid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter uuid: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RetryEvent(ctx, uuid)
+	return err
+}
+
+// GetReplayStatus converts echo context to params.
+func (w *ServerInterfaceWrapper) GetReplayStatus(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetReplayStatus(ctx)
+	return err
+}
+
+// StartReplay converts echo context to params.
+func (w *ServerInterfaceWrapper) StartReplay(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.StartReplay(ctx)
+	return err
+}
+
+// GetRetentionStatus converts echo context to params.
+func (w *ServerInterfaceWrapper) GetRetentionStatus(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetRetentionStatus(ctx)
+	return err
+}
+
+// Purge converts echo context to params.
+func (w *ServerInterfaceWrapper) Purge(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PurgeParams
+	// ------------- Optional query parameter "dryRun" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "dryRun", ctx.QueryParams(), &params.DryRun)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter dryRun: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.Purge(ctx, params)
+	return err
+}
+
+// ListTransitions converts echo context to params.
+func (w *ServerInterfaceWrapper) ListTransitions(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ListTransitions(ctx)
+	return err
+}
+
+// ListWebhooks converts echo context to params.
+func (w *ServerInterfaceWrapper) ListWebhooks(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ListWebhooks(ctx)
+	return err
+}
+
+// RegisterWebhook converts echo context to params.
+func (w *ServerInterfaceWrapper) RegisterWebhook(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RegisterWebhook(ctx)
+	return err
+}
+
+// DeleteWebhook converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteWebhook(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameter("simple", false, "id", ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DeleteWebhook(ctx, id)
+	return err
+}
+
+// GetWebhook converts echo context to params.
+func (w *ServerInterfaceWrapper) GetWebhook(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameter("simple", false, "id", ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetWebhook(ctx, id)
+	return err
+}
+
 // This is a simple interface which specifies echo.Route addition functions which
 // are present on both echo.Echo and echo.Group, since we want to allow using
 // either of them for path registration
@@ -135,9 +940,29 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 		Handler: si,
 	}
 
+	router.GET(baseURL+"/checkpoints", wrapper.ListCheckpoints)
+	router.DELETE(baseURL+"/checkpoints/:service/:subject", wrapper.ResetCheckpoint)
+	router.GET(baseURL+"/deadletters", wrapper.ListDeadLetters)
+	router.DELETE(baseURL+"/deadletters/:id", wrapper.DiscardDeadLetter)
+	router.GET(baseURL+"/deadletters/:id", wrapper.GetDeadLetter)
+	router.POST(baseURL+"/deadletters/:id/requeue", wrapper.RequeueDeadLetter)
+	router.GET(baseURL+"/diagnostics", wrapper.GetDiagnostics)
 	router.GET(baseURL+"/events", wrapper.List)
 	router.GET(baseURL+"/events/by_external_id/:external_id", wrapper.GetEventByExternalId)
+	router.POST(baseURL+"/events/retry", wrapper.RetryEvents)
+	router.GET(baseURL+"/events/stream", wrapper.Stream)
 	router.GET(baseURL+"/events/:uuid", wrapper.GetEvent)
+	router.GET(baseURL+"/events/:uuid/history", wrapper.GetEventHistory)
+	router.POST(baseURL+"/events/:uuid/retry", wrapper.RetryEvent)
+	router.GET(baseURL+"/replay", wrapper.GetReplayStatus)
+	router.POST(baseURL+"/replay", wrapper.StartReplay)
+	router.GET(baseURL+"/retention", wrapper.GetRetentionStatus)
+	router.POST(baseURL+"/retention", wrapper.Purge)
+	router.GET(baseURL+"/transitions", wrapper.ListTransitions)
+	router.GET(baseURL+"/webhooks", wrapper.ListWebhooks)
+	router.POST(baseURL+"/webhooks", wrapper.RegisterWebhook)
+	router.DELETE(baseURL+"/webhooks/:id", wrapper.DeleteWebhook)
+	router.GET(baseURL+"/webhooks/:id", wrapper.GetWebhook)
 
 }
 
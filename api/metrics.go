@@ -0,0 +1,33 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsPath is the path of the Prometheus metrics of the event octopus, below the paths of the engine so it doesn't clash with other engines
+const MetricsPath = "/events/metrics"
+
+// RegisterMetricsHandler adds a route serving the metrics of the gatherer in the Prometheus format to the EchoRouter
+func RegisterMetricsHandler(router EchoRouter, gatherer prometheus.Gatherer) {
+	router.GET(MetricsPath, echo.WrapHandler(promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})))
+}
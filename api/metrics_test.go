@@ -0,0 +1,44 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nuts-foundation/nuts-event-octopus/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterMetricsHandler(t *testing.T) {
+	t.Run("metrics are served in the Prometheus format", func(t *testing.T) {
+		e := echo.New()
+		RegisterMetricsHandler(e, pkg.EventOctopusInstance().Metrics())
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, MetricsPath, nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "nuts_event_octopus_circuit_breaker_open")
+		// the metrics of other engines and the Go runtime are exposed by the node
+		assert.NotContains(t, rec.Body.String(), "go_goroutines")
+	})
+}
@@ -0,0 +1,88 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nuts-foundation/nuts-event-octopus/pkg"
+)
+
+// StreamPath is the path of the Server-Sent Events stream of events
+const StreamPath = "/events/stream"
+
+// streamKeepAlive is the interval of the comments sent to keep idle streams open
+var streamKeepAlive = 30 * time.Second
+
+// Stream pushes new events as Server-Sent Events, optionally filtered by the externalId and initiatorLegalEntity query parameters.
+// The event type of each message is the channel the event was published on, the data holds the Event as JSON.
+func (w Wrapper) Stream(ctx echo.Context, params StreamParams) error {
+	filter := pkg.StreamFilter{}
+	if params.ExternalId != nil {
+		filter.ExternalID = *params.ExternalId
+	}
+	if params.InitiatorLegalEntity != nil {
+		filter.InitiatorLegalEntity = string(*params.InitiatorLegalEntity)
+	}
+
+	events, err := w.Eo.Stream(ctx.Request().Context(), filter)
+	if errors.Is(err, pkg.ErrTooManyStreams) {
+		return ctx.String(503, err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("Error while subscribing to events: %v", err)
+	}
+
+	resp := ctx.Response()
+	resp.Header().Set(echo.HeaderContentType, "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.Header().Set("Connection", "keep-alive")
+	resp.WriteHeader(http.StatusOK)
+	resp.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				// request context is done or the node shuts down
+				return nil
+			}
+			data, err := json.Marshal(convert(e.Event))
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(resp, "event: %s\ndata: %s\n\n", e.Channel, data); err != nil {
+				return err
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(resp, ": keep-alive\n\n"); err != nil {
+				return err
+			}
+		}
+		resp.Flush()
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nuts-foundation/nuts-event-octopus/pkg"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapper_Stream(t *testing.T) {
+	eo := pkg.EventOctopusInstance()
+	// port and db differ from the pkg tests, which may run at the same time
+	eo.Config.NatsPort = 4224
+	eo.Config.Connectionstring = "file:apistream?mode=memory&cache=shared"
+	if err := eo.Configure(); err != nil {
+		t.Fatal(err)
+	}
+	if err := eo.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer eo.Shutdown()
+
+	e := echo.New()
+	RegisterHandlers(e, &Wrapper{Eo: eo})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	publisher, err := eo.EventPublisher("api-stream-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("matching events are pushed as Server-Sent Events", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+StreamPath+"?externalId=stream", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer resp.Body.Close()
+		assert.Equal(t, "text/event-stream", resp.Header.Get(echo.HeaderContentType))
+
+		for _, externalID := range []string{"other", "stream"} {
+			_ = publisher.Publish(pkg.ChannelConsentRequest, pkg.Event{
+				UUID:                 uuid.NewV4().String(),
+				Name:                 pkg.EventConsentRequestConstructed,
+				ExternalID:           externalID,
+				InitiatorLegalEntity: "urn:nuts:entity:test",
+				Payload:              "test",
+			})
+		}
+
+		reader := bufio.NewReader(resp.Body)
+		eventLine, _ := reader.ReadString('\n')
+		dataLine, _ := reader.ReadString('\n')
+
+		assert.Equal(t, "event: "+pkg.ChannelConsentRequest+"\n", eventLine)
+		if assert.True(t, strings.HasPrefix(dataLine, "data: ")) {
+			event := Event{}
+			if assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(dataLine, "data: ")), &event)) {
+				assert.Equal(t, "stream", event.ExternalId)
+			}
+		}
+	})
+}
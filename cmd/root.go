@@ -32,6 +32,15 @@ var e = engine.NewEventOctopusEngine()
 var rootCmd = &cobra.Command{
 	Short: "test command",
 	Run: func(cmd *cobra.Command, args []string) {
+		// only the server starts the engine, subcommands like encrypt open what they need themselves
+		if err := e.Configure(); err != nil {
+			panic(err)
+		}
+
+		if err := e.Start(); err != nil {
+			panic(err)
+		}
+
 		var endWaiter sync.WaitGroup
 		endWaiter.Add(1)
 		var signalChannel chan os.Signal
@@ -50,6 +59,7 @@ func Execute() {
 	c.IgnoredPrefixes = append(c.IgnoredPrefixes, e.ConfigKey)
 	cfg.RegisterEngine(e)
 	c.RegisterFlags(rootCmd, e)
+	rootCmd.AddCommand(e.Cmd)
 
 	if err := c.Load(rootCmd); err != nil {
 		panic(err)
@@ -61,13 +71,5 @@ func Execute() {
 		panic(err)
 	}
 
-	if err := e.Configure(); err != nil {
-		panic(err)
-	}
-
-	if err := e.Start(); err != nil {
-		panic(err)
-	}
-
 	rootCmd.Execute()
 }
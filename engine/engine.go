@@ -22,6 +22,7 @@ import (
 	"github.com/nuts-foundation/nuts-event-octopus/api"
 	"github.com/nuts-foundation/nuts-event-octopus/pkg"
 	engine "github.com/nuts-foundation/nuts-go-core"
+	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
@@ -31,29 +32,86 @@ func NewEventOctopusEngine() *engine.Engine {
 
 	return &engine.Engine{
 		Name:        i.Name,
+		Cmd:         cmd(),
 		Config:      &i.Config,
 		ConfigKey:   "events",
 		Configure:   i.Configure,
 		Diagnostics: i.Diagnostics,
 		FlagSet:     flagSet(),
 		Routes: func(router engine.EchoRouter) {
-			api.RegisterHandlers(router, &api.Wrapper{Eo: i})
+			w := &api.Wrapper{Eo: i}
+			api.RegisterHandlers(router, w)
+			api.RegisterMetricsHandler(router, i.Metrics())
 		},
 		Start:    i.Start,
 		Shutdown: i.Shutdown,
 	}
 }
 
+func cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "event store commands",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "encrypt",
+		Short: "Encrypt the payloads of all stored events, scheduled retries and dead letters with the current encryption key",
+		Long: "Encrypts payloads stored in plain text and re-encrypts the data keys of payloads encrypted with an old key. " +
+			"Run it after enabling encryption and after adding a new key to the key file, old keys can be removed afterwards.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			updated, err := pkg.EventOctopusInstance().EncryptPayloads()
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Encrypted %d events, scheduled messages, dead letters and webhook secrets\n", updated)
+			return nil
+		},
+	})
+
+	return cmd
+}
+
 func flagSet() *pflag.FlagSet {
 	flags := pflag.NewFlagSet("event octopus", pflag.ContinueOnError)
 
 	flags.Int(pkg.ConfigRetryInterval, pkg.ConfigRetryIntervalDefault, "Retry delay in seconds for reconnecting")
 	flags.Int(pkg.ConfigNatsPort, pkg.ConfigNatsPortDefault, "Port for Nats to bind on")
+	flags.Int(pkg.ConfigNatsMonitorPort, pkg.ConfigNatsMonitorPortDefault, "Port of the Nats monitoring endpoint used for channel statistics and replays, a free port on the loopback interface when 0")
 	flags.String(pkg.ConfigConnectionstring, pkg.ConfigConnectionStringDefault, "db connection string for event store")
+	flags.String(pkg.ConfigDbDialect, "", "db dialect for event store: sqlite3 or postgres, derived from the connection string when empty")
 	flags.Bool(pkg.ConfigAutoRecover, false, "Republish unfinished events at startup")
-	flags.Bool(pkg.ConfigPurgeCompleted, false, "Purge completed events at startup")
+	flags.Bool(pkg.ConfigPurgeCompleted, false, "Deprecated, use retentionRules=completed. Purge completed events at startup")
+	flags.String(pkg.ConfigRetentionRules, "", "Comma separated retention rules {name}[={maxAge}][/{maxCount}], e.g. completed=168h,error=2160h/1000")
+	flags.Int(pkg.ConfigRetentionInterval, pkg.ConfigRetentionIntervalDefault, "Interval in seconds for enforcing the retention rules, 0 only enforces them at startup")
+	flags.Bool(pkg.ConfigRetentionDryRun, false, "Only report the events the retention rules would purge")
+	flags.Int(pkg.ConfigDrainTimeout, pkg.ConfigDrainTimeoutDefault, "Seconds to wait at shutdown for events that are being handled, 0 doesn't wait")
+	flags.Int(pkg.ConfigMaxStreams, pkg.ConfigMaxStreamsDefault, "Max number of concurrent event streams, 0 means unlimited")
+	flags.Int(pkg.ConfigCircuitBreakerThreshold, pkg.ConfigCircuitBreakerThresholdDefault, "Consecutive failures that pause processing messages in the event store, 0 never pauses")
+	flags.Int(pkg.ConfigCircuitBreakerTimeout, pkg.ConfigCircuitBreakerTimeoutDefault, "Seconds processing messages is paused when the failure threshold is reached")
+	flags.Bool(pkg.ConfigReplayOnStart, false, "Rebuild the event store from the entire event log at startup")
 	flags.Int(pkg.ConfigMaxRetryCount, pkg.ConfigMaxRetryCountDefault, "Max number of retries for events before giving up (only for recoverable errors")
 	flags.Int(pkg.ConfigIncrementalBackoff, pkg.ConfigIncrementalBackoffDefault, "Incremental backoff per retry queue, queue 0 retries after 1 second, queue 1 after {incrementalBackoff} * {previousDelay}")
+	flags.String(pkg.ConfigRetrySchedule, "", "Comma separated delays of the retry queues (1s,1m,1h), overrides maxRetryCount and incrementalBackoff")
+	flags.Int(pkg.ConfigRetryJitter, 0, "Max percentage of the delay randomly added to the delay of a retry")
+	flags.String(pkg.ConfigRetryPolicies, "", "Retry policies per event: {name}[/{errorClass}]=[{delays}][>{terminal}], separated by ;")
+	flags.Int(pkg.ConfigWebhookMaxRetryCount, pkg.ConfigWebhookMaxRetryCountDefault, "Max number of retries for delivering a webhook notification before giving up")
+	flags.Int(pkg.ConfigWebhookIncrementalBackoff, pkg.ConfigWebhookIncrementalBackoffDefault, "Incremental backoff per webhook retry queue, queue 0 retries after 1 second, queue 1 after {webhookIncrementalBackoff} * {previousDelay}")
+	flags.String(pkg.ConfigNatsStoreType, pkg.ConfigNatsStoreTypeDefault, "Store type for the embedded Nats streaming server: memory, file or sql")
+	flags.String(pkg.ConfigNatsFileStoreDir, pkg.ConfigNatsFileStoreDirDefault, "Directory for the Nats file store, only used when natsStoreType is file")
+	flags.String(pkg.ConfigNatsSQLStoreDriver, "", "Database driver for the Nats sql store (postgres), only used when natsStoreType is sql")
+	flags.String(pkg.ConfigNatsSQLStoreSource, "", "Datasource for the Nats sql store, only used when natsStoreType is sql")
+	flags.Int(pkg.ConfigNatsMaxMsgs, 0, "Max number of messages per Nats channel, 0 uses the Nats default")
+	flags.Int64(pkg.ConfigNatsMaxBytes, 0, "Max number of bytes per Nats channel, 0 uses the Nats default")
+	flags.Int(pkg.ConfigNatsMaxAge, 0, "Max age in seconds of messages in a Nats channel, 0 means unlimited")
+	flags.String(pkg.ConfigNatsURL, "", "Comma separated list of external Nats servers, when set no embedded Nats streaming server is started")
+	flags.String(pkg.ConfigNatsClusterID, pkg.ConfigNatsClusterIDDefault, "Cluster ID of the Nats streaming server")
+	flags.String(pkg.ConfigNatsNodeID, "", "Unique ID of this node, prepended to the Nats client IDs when sharing an external Nats cluster")
+	flags.String(pkg.ConfigNatsCredentials, "", "Credentials file for connecting to an external Nats cluster")
+	flags.String(pkg.ConfigNatsTLSCert, "", "Client certificate file for connecting to an external Nats cluster over TLS")
+	flags.String(pkg.ConfigNatsTLSKey, "", "Client key file for connecting to an external Nats cluster over TLS")
+	flags.String(pkg.ConfigNatsTLSCA, "", "CA file for verifying the servers of an external Nats cluster")
+	flags.String(pkg.ConfigEncryptionKeyFile, "", "Key file for encrypting payloads in the event store, payloads are not encrypted when empty")
 
 	return flags
 }
@@ -1,10 +1,14 @@
 package engine
 
 import (
+	"github.com/nuts-foundation/nuts-event-octopus/pkg"
 	core "github.com/nuts-foundation/nuts-go-core"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -28,4 +32,25 @@ func TestEventOctopusEngine_Configure(t *testing.T) {
 		}
 		assert.NoError(t, NewEventOctopusEngine().Configure())
 	})
-}
\ No newline at end of file
+}
+
+func TestEventOctopusEngine_FlagSet(t *testing.T) {
+	t.Run("all flags map to a config field", func(t *testing.T) {
+		cfg := reflect.ValueOf(pkg.EventOctopusConfig{})
+
+		flagSet().VisitAll(func(f *pflag.Flag) {
+			// nuts-go-core injects values into the field with the capitalized flag name
+			assert.True(t, cfg.FieldByName(strings.Title(f.Name)).IsValid(), f.Name)
+		})
+	})
+}
+
+func TestEventOctopusEngine_Cmd(t *testing.T) {
+	t.Run("has encrypt command", func(t *testing.T) {
+		c, _, err := cmd().Find([]string{"encrypt"})
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, "encrypt", c.Name())
+		}
+	})
+}
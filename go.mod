@@ -8,11 +8,14 @@ require (
 	github.com/golang/mock v1.4.4
 	github.com/jinzhu/gorm v1.9.16
 	github.com/labstack/echo/v4 v4.1.17
+	github.com/lib/pq v1.10.9
 	github.com/nats-io/nats-streaming-server v0.17.0
-	github.com/nats-io/nats.go v1.9.2 // indirect
+	github.com/nats-io/nats.go v1.9.2
 	github.com/nats-io/stan.go v0.6.0
 	github.com/nuts-foundation/nuts-go-core v0.16.0
 	github.com/pelletier/go-toml v1.5.0 // indirect
+	github.com/prometheus/client_golang v0.9.4
+	github.com/prometheus/client_model v0.0.0-20190812154241-14fe0d1b01d4
 	github.com/satori/go.uuid v1.2.0
 	github.com/sirupsen/logrus v1.7.0
 	github.com/spf13/cobra v0.0.7
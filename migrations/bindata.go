@@ -1,8 +1,58 @@
 // Package migrations Code generated by go-bindata. (@generated) DO NOT EDIT.
 // sources:
-// 1_create_table_event.down.sql
-// 1_create_table_event.up.sql
 // bindata.go
+// postgres/10_create_table_scheduled_messages.down.sql
+// postgres/10_create_table_scheduled_messages.up.sql
+// postgres/11_add_retention_index_to_events.down.sql
+// postgres/11_add_retention_index_to_events.up.sql
+// postgres/12_add_log_id_to_subscriber_checkpoints.down.sql
+// postgres/12_add_log_id_to_subscriber_checkpoints.up.sql
+// postgres/13_widen_webhook_secret.down.sql
+// postgres/13_widen_webhook_secret.up.sql
+// postgres/1_create_table_event.down.sql
+// postgres/1_create_table_event.up.sql
+// postgres/2_create_table_event_history.down.sql
+// postgres/2_create_table_event_history.up.sql
+// postgres/3_add_timestamps_to_events.down.sql
+// postgres/3_add_timestamps_to_events.up.sql
+// postgres/4_create_table_webhooks.down.sql
+// postgres/4_create_table_webhooks.up.sql
+// postgres/5_create_table_dead_letters.down.sql
+// postgres/5_create_table_dead_letters.up.sql
+// postgres/6_create_table_subscriber_checkpoints.down.sql
+// postgres/6_create_table_subscriber_checkpoints.up.sql
+// postgres/7_add_rejected_to_event_history.down.sql
+// postgres/7_add_rejected_to_event_history.up.sql
+// postgres/8_add_version_to_events.down.sql
+// postgres/8_add_version_to_events.up.sql
+// postgres/9_add_error_class_to_events.down.sql
+// postgres/9_add_error_class_to_events.up.sql
+// sqlite3/10_create_table_scheduled_messages.down.sql
+// sqlite3/10_create_table_scheduled_messages.up.sql
+// sqlite3/11_add_retention_index_to_events.down.sql
+// sqlite3/11_add_retention_index_to_events.up.sql
+// sqlite3/12_add_log_id_to_subscriber_checkpoints.down.sql
+// sqlite3/12_add_log_id_to_subscriber_checkpoints.up.sql
+// sqlite3/13_widen_webhook_secret.down.sql
+// sqlite3/13_widen_webhook_secret.up.sql
+// sqlite3/1_create_table_event.down.sql
+// sqlite3/1_create_table_event.up.sql
+// sqlite3/2_create_table_event_history.down.sql
+// sqlite3/2_create_table_event_history.up.sql
+// sqlite3/3_add_timestamps_to_events.down.sql
+// sqlite3/3_add_timestamps_to_events.up.sql
+// sqlite3/4_create_table_webhooks.down.sql
+// sqlite3/4_create_table_webhooks.up.sql
+// sqlite3/5_create_table_dead_letters.down.sql
+// sqlite3/5_create_table_dead_letters.up.sql
+// sqlite3/6_create_table_subscriber_checkpoints.down.sql
+// sqlite3/6_create_table_subscriber_checkpoints.up.sql
+// sqlite3/7_add_rejected_to_event_history.down.sql
+// sqlite3/7_add_rejected_to_event_history.up.sql
+// sqlite3/8_add_version_to_events.down.sql
+// sqlite3/8_add_version_to_events.up.sql
+// sqlite3/9_add_error_class_to_events.down.sql
+// sqlite3/9_add_error_class_to_events.up.sql
 package migrations
 
 import (
@@ -79,62 +129,1062 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var __1_create_table_eventDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\x48\x2d\x4b\xcd\x2b\x29\xb6\x06\x04\x00\x00\xff\xff\x27\x3a\x67\xc6\x12\x00\x00\x00")
+var _bindataGo = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x03\x00\x00\x00\x00\x00\x00\x00\x00\x00")
 
-func _1_create_table_eventDownSqlBytes() ([]byte, error) {
+func bindataGoBytes() ([]byte, error) {
+	return bindataRead(
+		_bindataGo,
+		"bindata.go",
+	)
+}
+
+func bindataGo() (*asset, error) {
+	bytes, err := bindataGoBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "bindata.go", size: 4096, mode: os.FileMode(436), modTime: time.Unix(1792308721, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres10_create_table_scheduled_messagesDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x1e\x00\xe1\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x73\x63\x68\x65\x64\x75\x6c\x65\x64\x5f\x6d\x65\x73\x73\x61\x67\x65\x73\x3b\x03\x00\xdd\xe7\xf0\x65\x1e\x00\x00\x00")
+
+func postgres10_create_table_scheduled_messagesDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres10_create_table_scheduled_messagesDownSql,
+		"postgres/10_create_table_scheduled_messages.down.sql",
+	)
+}
+
+func postgres10_create_table_scheduled_messagesDownSql() (*asset, error) {
+	bytes, err := postgres10_create_table_scheduled_messagesDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/10_create_table_scheduled_messages.down.sql", size: 30, mode: os.FileMode(420), modTime: time.Unix(1792304482, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres10_create_table_scheduled_messagesUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x90\xcd\x4a\x03\x31\x14\x46\xf7\x79\x8a\x6f\xd9\x40\x57\x4a\xdd\x74\x95\xd6\xa0\xc1\x99\xb4\xc6\x8c\xb4\xab\x10\x93\x8b\x8e\xa8\x30\x26\x01\x1f\x5f\xc8\xf8\x03\xc3\x30\xeb\xef\xdc\x03\xf7\xec\x8d\x14\x56\xc2\x8a\x5d\x23\x91\xc2\x0b\xc5\xf2\x46\xd1\xbd\x53\x4a\xfe\x99\x12\x56\x0c\x00\xfa\x88\xfd\xad\x30\xab\xcb\x2b\x8e\xa3\x51\xad\x30\x67\xdc\xc9\xf3\xba\x8e\x43\xa1\x42\x78\x14\xa6\x22\x17\x9b\x0d\x87\x3e\x58\xe8\xae\x69\x46\x20\xd1\x50\xe8\x23\x10\x76\xea\x46\x69\x3b\x5d\xcb\xd3\x2b\x85\xbc\x24\x88\x3e\x7b\x58\x79\x9a\x9e\xc6\x42\xce\x67\x58\xd5\xca\x07\x2b\xda\xe3\x64\x0e\x9f\xe4\x33\xc5\x79\x84\xf1\x2d\xfb\x79\x5e\xe9\x6b\x79\x9a\x79\xde\x8d\x7e\xd7\xc7\x2f\x1c\xf4\x6c\x9d\x91\xf8\x57\x75\x5a\xdd\x77\x0b\xc6\xda\xca\xfd\x06\x59\x32\x57\x72\xfd\xd7\x8e\x6f\xd9\xf7\x00\x95\xb0\xf1\xa2\xab\x01\x00\x00")
+
+func postgres10_create_table_scheduled_messagesUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres10_create_table_scheduled_messagesUpSql,
+		"postgres/10_create_table_scheduled_messages.up.sql",
+	)
+}
+
+func postgres10_create_table_scheduled_messagesUpSql() (*asset, error) {
+	bytes, err := postgres10_create_table_scheduled_messagesUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/10_create_table_scheduled_messages.up.sql", size: 427, mode: os.FileMode(420), modTime: time.Unix(1792306367, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres11_add_retention_index_to_eventsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x2c\x00\xd3\xff\x44\x52\x4f\x50\x20\x49\x4e\x44\x45\x58\x20\x65\x76\x65\x6e\x74\x73\x5f\x6e\x61\x6d\x65\x5f\x74\x72\x61\x6e\x73\x69\x74\x69\x6f\x6e\x65\x64\x5f\x61\x74\x5f\x69\x64\x78\x3b\x0a\x03\x00\x79\xf3\xa3\xc3\x2c\x00\x00\x00")
+
+func postgres11_add_retention_index_to_eventsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres11_add_retention_index_to_eventsDownSql,
+		"postgres/11_add_retention_index_to_events.down.sql",
+	)
+}
+
+func postgres11_add_retention_index_to_eventsDownSql() (*asset, error) {
+	bytes, err := postgres11_add_retention_index_to_eventsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/11_add_retention_index_to_events.down.sql", size: 44, mode: os.FileMode(420), modTime: time.Unix(1792307465, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres11_add_retention_index_to_eventsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x50\x00\xaf\xff\x43\x52\x45\x41\x54\x45\x20\x49\x4e\x44\x45\x58\x20\x65\x76\x65\x6e\x74\x73\x5f\x6e\x61\x6d\x65\x5f\x74\x72\x61\x6e\x73\x69\x74\x69\x6f\x6e\x65\x64\x5f\x61\x74\x5f\x69\x64\x78\x20\x4f\x4e\x20\x65\x76\x65\x6e\x74\x73\x20\x28\x6e\x61\x6d\x65\x2c\x20\x74\x72\x61\x6e\x73\x69\x74\x69\x6f\x6e\x65\x64\x5f\x61\x74\x29\x3b\x0a\x03\x00\xd3\x8a\x7b\x90\x50\x00\x00\x00")
+
+func postgres11_add_retention_index_to_eventsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres11_add_retention_index_to_eventsUpSql,
+		"postgres/11_add_retention_index_to_events.up.sql",
+	)
+}
+
+func postgres11_add_retention_index_to_eventsUpSql() (*asset, error) {
+	bytes, err := postgres11_add_retention_index_to_eventsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/11_add_retention_index_to_events.up.sql", size: 80, mode: os.FileMode(420), modTime: time.Unix(1792307465, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres12_add_log_id_to_subscriber_checkpointsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x37\x00\xc8\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x73\x75\x62\x73\x63\x72\x69\x62\x65\x72\x5f\x63\x68\x65\x63\x6b\x70\x6f\x69\x6e\x74\x73\x20\x44\x52\x4f\x50\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x6c\x6f\x67\x5f\x69\x64\x3b\x0a\x03\x00\x8b\x16\xb4\xe8\x37\x00\x00\x00")
+
+func postgres12_add_log_id_to_subscriber_checkpointsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres12_add_log_id_to_subscriber_checkpointsDownSql,
+		"postgres/12_add_log_id_to_subscriber_checkpoints.down.sql",
+	)
+}
+
+func postgres12_add_log_id_to_subscriber_checkpointsDownSql() (*asset, error) {
+	bytes, err := postgres12_add_log_id_to_subscriber_checkpointsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/12_add_log_id_to_subscriber_checkpoints.down.sql", size: 55, mode: os.FileMode(420), modTime: time.Unix(1792308148, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres12_add_log_id_to_subscriber_checkpointsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x57\x00\xa8\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x73\x75\x62\x73\x63\x72\x69\x62\x65\x72\x5f\x63\x68\x65\x63\x6b\x70\x6f\x69\x6e\x74\x73\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x6c\x6f\x67\x5f\x69\x64\x20\x56\x41\x52\x43\x48\x41\x52\x28\x32\x35\x35\x29\x20\x4e\x4f\x54\x20\x4e\x55\x4c\x4c\x20\x44\x45\x46\x41\x55\x4c\x54\x20\x27\x27\x3b\x0a\x03\x00\x6e\xfb\x7a\xb9\x57\x00\x00\x00")
+
+func postgres12_add_log_id_to_subscriber_checkpointsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres12_add_log_id_to_subscriber_checkpointsUpSql,
+		"postgres/12_add_log_id_to_subscriber_checkpoints.up.sql",
+	)
+}
+
+func postgres12_add_log_id_to_subscriber_checkpointsUpSql() (*asset, error) {
+	bytes, err := postgres12_add_log_id_to_subscriber_checkpointsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/12_add_log_id_to_subscriber_checkpoints.up.sql", size: 87, mode: os.FileMode(420), modTime: time.Unix(1792308148, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres13_widen_webhook_secretDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x3c\x00\xc3\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x77\x65\x62\x68\x6f\x6f\x6b\x73\x20\x41\x4c\x54\x45\x52\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x73\x65\x63\x72\x65\x74\x20\x54\x59\x50\x45\x20\x56\x41\x52\x43\x48\x41\x52\x28\x32\x35\x35\x29\x3b\x0a\x03\x00\xb8\x53\xbc\xb9\x3c\x00\x00\x00")
+
+func postgres13_widen_webhook_secretDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres13_widen_webhook_secretDownSql,
+		"postgres/13_widen_webhook_secret.down.sql",
+	)
+}
+
+func postgres13_widen_webhook_secretDownSql() (*asset, error) {
+	bytes, err := postgres13_widen_webhook_secretDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/13_widen_webhook_secret.down.sql", size: 60, mode: os.FileMode(420), modTime: time.Unix(1792308721, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres13_widen_webhook_secretUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x34\x00\xcb\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x77\x65\x62\x68\x6f\x6f\x6b\x73\x20\x41\x4c\x54\x45\x52\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x73\x65\x63\x72\x65\x74\x20\x54\x59\x50\x45\x20\x54\x45\x58\x54\x3b\x0a\x03\x00\xf4\x15\xb9\xf9\x34\x00\x00\x00")
+
+func postgres13_widen_webhook_secretUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres13_widen_webhook_secretUpSql,
+		"postgres/13_widen_webhook_secret.up.sql",
+	)
+}
+
+func postgres13_widen_webhook_secretUpSql() (*asset, error) {
+	bytes, err := postgres13_widen_webhook_secretUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/13_widen_webhook_secret.up.sql", size: 52, mode: os.FileMode(420), modTime: time.Unix(1792308721, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres1_create_table_eventDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x12\x00\xed\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x65\x76\x65\x6e\x74\x73\x3b\x03\x00\x27\x3a\x67\xc6\x12\x00\x00\x00")
+
+func postgres1_create_table_eventDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres1_create_table_eventDownSql,
+		"postgres/1_create_table_event.down.sql",
+	)
+}
+
+func postgres1_create_table_eventDownSql() (*asset, error) {
+	bytes, err := postgres1_create_table_eventDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/1_create_table_event.down.sql", size: 18, mode: os.FileMode(420), modTime: time.Unix(1792300120, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres1_create_table_eventUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x8f\x31\x4b\xc4\x40\x10\x85\xfb\xfc\x8a\x57\x5e\xc0\x4a\x39\x1b\xab\xf5\x58\xf0\x30\x46\x59\x56\xb9\xab\x96\x21\x19\x64\x21\xce\xca\x64\x22\xe6\xdf\x0b\x97\x46\xb7\xb0\xfe\xde\xfb\x66\xde\x21\x78\x17\x3d\xa2\xbb\xef\x3c\xf8\x8b\xc5\x66\xec\x1a\x00\x58\x96\x3c\xe2\xf0\xe0\xc2\xee\xe6\xb6\xc5\x4b\x38\x3e\xb9\x70\xc6\xa3\x3f\x5f\x5d\xb0\xd0\x07\xe3\xcd\x85\x4b\xe2\x7a\xbf\x6f\xd1\x3f\x47\xf4\xaf\x5d\xb7\x71\x65\xd3\x35\x0d\x65\x11\xc3\xb1\x8f\x15\x35\x25\x99\x69\xb0\x5c\x24\xe5\xf1\x8f\x67\xab\x67\xc9\x96\xc9\x8a\xa6\x89\xdf\x69\x4a\x2c\x96\x6d\xfd\xef\x20\x7f\x1b\xab\xd0\x54\xfb\xaa\xd8\x50\x64\x66\xb1\xf4\x6b\xdc\xd6\xff\xa4\x75\x2a\x34\x22\xfa\x53\xfd\x2d\xab\x16\x45\xf4\xa7\xd8\xb4\x77\xcd\xcf\x00\x83\x1c\x9b\xf3\x33\x01\x00\x00")
+
+func postgres1_create_table_eventUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres1_create_table_eventUpSql,
+		"postgres/1_create_table_event.up.sql",
+	)
+}
+
+func postgres1_create_table_eventUpSql() (*asset, error) {
+	bytes, err := postgres1_create_table_eventUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/1_create_table_event.up.sql", size: 307, mode: os.FileMode(420), modTime: time.Unix(1792300124, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres2_create_table_event_historyDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x19\x00\xe6\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x65\x76\x65\x6e\x74\x5f\x68\x69\x73\x74\x6f\x72\x79\x3b\x03\x00\x14\x23\xc3\x11\x19\x00\x00\x00")
+
+func postgres2_create_table_event_historyDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres2_create_table_event_historyDownSql,
+		"postgres/2_create_table_event_history.down.sql",
+	)
+}
+
+func postgres2_create_table_event_historyDownSql() (*asset, error) {
+	bytes, err := postgres2_create_table_event_historyDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/2_create_table_event_history.down.sql", size: 25, mode: os.FileMode(420), modTime: time.Unix(1792300218, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres2_create_table_event_historyUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x5c\x90\xc1\x4a\x03\x31\x14\x45\xf7\xf3\x15\x77\xd9\x01\x57\x4a\xdd\x74\x15\xeb\x03\x83\x99\xb4\xa4\x4f\x69\x57\x21\x34\x0f\x1a\xd0\x0c\xc4\x8c\xd8\xbf\x17\x3a\xa5\x65\x66\x7d\x0e\x17\xce\x5d\x3b\x52\x4c\x60\xf5\x62\x08\xf2\x2b\xb9\xfa\x53\xfa\xa9\x7d\x39\x63\xd1\x00\x40\x8a\xd8\x91\xd3\xca\x60\xeb\x74\xa7\xdc\x01\xef\x74\x78\xb8\xa0\x51\x1f\x86\x14\xb1\x7e\x53\x6e\xf1\xf4\xdc\xc2\x6e\x18\xf6\xc3\x98\xd1\xc8\xe1\x5b\xf0\xa9\xdc\x05\x3f\x2e\x97\x73\x5e\xa4\x96\xb3\x3f\xf6\x43\xae\xd0\x96\x67\x54\x4a\xe9\x0b\x98\xf6\x3c\xda\xc7\x53\xc8\x59\xbe\x26\x83\x57\x52\x24\x54\x89\x3e\x54\xb0\xee\x68\xc7\xaa\xdb\xde\xc6\x9a\x76\xd5\x5c\x33\xb5\x7d\xa5\xfd\x34\xd3\xdf\x2b\x7c\x8a\x7f\xd8\xd8\xf9\x0d\x77\xa1\x5d\x35\xff\x03\x00\xf1\xfd\x4e\x39\x30\x01\x00\x00")
+
+func postgres2_create_table_event_historyUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres2_create_table_event_historyUpSql,
+		"postgres/2_create_table_event_history.up.sql",
+	)
+}
+
+func postgres2_create_table_event_historyUpSql() (*asset, error) {
+	bytes, err := postgres2_create_table_event_historyUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/2_create_table_event_history.up.sql", size: 304, mode: os.FileMode(420), modTime: time.Unix(1792300218, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres3_add_timestamps_to_eventsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\x48\x2d\x4b\xcd\x2b\x29\x8e\x2f\x29\x4a\xcc\x2b\xce\x2c\xc9\xcc\xcf\x4b\x4d\x89\x4f\x2c\x89\xcf\x4c\xa9\xb0\xe6\xc2\x54\x56\x5a\x90\x92\x58\x82\x57\x45\x72\x51\x2a\x8a\x0a\x47\x9f\x10\xd7\x20\x85\x10\x47\x27\x1f\x57\xa8\x12\x05\xb0\xb1\xce\xfe\x3e\xa1\xbe\x7e\x0a\x68\xf6\x12\x54\x8f\x70\x00\x41\xa5\x08\x97\x58\x73\x01\x06\x00\x31\x73\xe1\x67\xf1\x00\x00\x00")
+
+func postgres3_add_timestamps_to_eventsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres3_add_timestamps_to_eventsDownSql,
+		"postgres/3_add_timestamps_to_events.down.sql",
+	)
+}
+
+func postgres3_add_timestamps_to_eventsDownSql() (*asset, error) {
+	bytes, err := postgres3_add_timestamps_to_eventsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/3_add_timestamps_to_events.down.sql", size: 241, mode: os.FileMode(420), modTime: time.Unix(1792300421, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres3_add_timestamps_to_eventsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xbc\x93\x4f\x6f\x82\x30\x18\xc6\xef\x7c\x8a\xe7\x08\x89\x92\xdd\x99\x26\x1d\x74\x99\x09\x14\x03\x25\xf3\x46\x50\x6a\x68\xa6\xd4\x00\xee\xcf\xb7\x5f\x14\x14\xc6\x18\x8b\x17\x13\x2f\xed\xe3\xef\xed\xaf\x6f\x5f\x88\xcb\x69\x00\x4e\x9e\x5c\x0a\xf1\x2e\xf2\xaa\x04\x71\x1c\xd8\xbe\x1b\x79\x0c\x9b\x42\x24\x95\x48\xe3\xa4\x02\x5f\x78\x34\xe4\xc4\x5b\x5a\xda\x38\x73\x3c\xa4\x37\x33\x55\x91\xe4\xa5\xac\xa4\xca\x7f\x81\xda\x74\x8a\x75\xb2\x79\xdb\xca\xdd\x0e\xdb\x42\xed\x51\x65\xa2\x2e\x81\x4c\x96\x95\x2a\xbe\xf0\x91\x89\x42\xe0\xa0\xca\x52\xae\x77\x42\x8b\x96\x0e\xe1\xd7\x63\x42\xca\x35\x00\xdd\xbb\xcc\x60\xfb\xc4\xa5\xa1\x4d\xf5\x73\x74\xfa\xe9\x21\x75\xa9\xcd\xe1\x2d\x98\x9e\x99\xed\x9f\x0d\x3c\x07\xbe\x57\x17\x8b\x2f\x07\x66\x78\x7d\xa1\x01\x45\x66\xd6\xfb\xc7\xa3\x4c\x31\x6b\x4e\x34\x4f\x2b\x63\x72\xad\x6c\x47\x41\x40\x19\x8f\xaf\x97\x6a\xb2\x4e\xa3\x46\x85\xc8\xea\x4e\x42\xfd\x57\xb8\x43\x9b\x40\x98\x83\xcc\xcc\x93\xbd\x68\x83\xf3\xaa\x0e\x64\x8a\xf9\x80\x45\xbf\x3f\xca\x94\xe9\xa0\x81\x6a\x0c\xd4\xb8\x81\xaa\x0d\x1e\xe7\x5d\x05\x63\x82\x07\x63\xb4\x6d\x96\x36\x38\xd7\xe7\xad\x66\xb2\xdb\x0e\x21\xa4\x1c\xcc\xe7\x60\x91\xeb\x5a\xff\x82\x9d\xe9\xb8\x0d\xec\xbf\xe2\x4f\x5a\xb3\x03\x7a\xfa\x38\x16\xcc\xa1\xab\x86\x8f\x5b\xc9\x58\xa6\x9f\xf0\xd9\xa5\xb0\xde\x26\x86\x35\x88\xb6\x9a\x7d\xb4\x4d\xfe\x40\x7b\xa2\x7d\xbe\x17\x1b\x96\xf6\x3d\x00\xbe\x93\x66\xaf\xac\x04\x00\x00")
+
+func postgres3_add_timestamps_to_eventsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres3_add_timestamps_to_eventsUpSql,
+		"postgres/3_add_timestamps_to_events.up.sql",
+	)
+}
+
+func postgres3_add_timestamps_to_eventsUpSql() (*asset, error) {
+	bytes, err := postgres3_add_timestamps_to_eventsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/3_add_timestamps_to_events.up.sql", size: 1196, mode: os.FileMode(420), modTime: time.Unix(1792300421, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres4_create_table_webhooksDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x14\x00\xeb\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x77\x65\x62\x68\x6f\x6f\x6b\x73\x3b\x03\x00\xf6\x9d\xb6\xea\x14\x00\x00\x00")
+
+func postgres4_create_table_webhooksDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres4_create_table_webhooksDownSql,
+		"postgres/4_create_table_webhooks.down.sql",
+	)
+}
+
+func postgres4_create_table_webhooksDownSql() (*asset, error) {
+	bytes, err := postgres4_create_table_webhooksDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/4_create_table_webhooks.down.sql", size: 20, mode: os.FileMode(420), modTime: time.Unix(1792300986, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres4_create_table_webhooksUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\xcd\xc1\x6b\x83\x30\x14\xc7\xf1\xbb\x7f\xc5\xef\x68\x60\x87\xb1\xcd\x31\xd8\x29\x93\xc0\x64\xea\x24\xcb\x4a\x3d\x85\x54\x1f\x6d\xa8\x8d\x10\x5f\x5b\xfa\xdf\x17\x14\xda\x4b\xaf\xef\xf3\xe5\xfd\x72\xad\xa4\x51\x30\xf2\xab\x54\x38\xd3\x66\x37\x8e\xfb\x09\x69\x02\x00\xbe\x47\xfe\x2d\x75\xfa\xfa\x2e\xd0\xe8\xa2\x92\xba\xc5\x8f\x6a\x9f\x66\x3c\xc6\x01\x2b\xa9\xe7\xe0\xe5\xf9\xed\x43\xa0\xfe\x35\xa8\xff\xcb\x72\x71\x3a\x51\x60\x1b\xdc\x81\x26\x18\xb5\x36\xcb\xd5\x07\xcf\xde\xf1\x18\xed\x40\x5b\x37\x58\x0a\xec\xf9\x72\x7f\x94\x65\x62\x09\x27\xea\x22\xf1\x03\xe8\x22\x39\xa6\xde\x3a\x86\x29\x2a\xf5\x67\x64\xd5\xdc\xa6\x13\xf1\x99\x5c\x07\x00\xea\x41\xc8\x47\xd2\x00\x00\x00")
+
+func postgres4_create_table_webhooksUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres4_create_table_webhooksUpSql,
+		"postgres/4_create_table_webhooks.up.sql",
+	)
+}
+
+func postgres4_create_table_webhooksUpSql() (*asset, error) {
+	bytes, err := postgres4_create_table_webhooksUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/4_create_table_webhooks.up.sql", size: 210, mode: os.FileMode(420), modTime: time.Unix(1792300986, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres5_create_table_dead_lettersDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x18\x00\xe7\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x64\x65\x61\x64\x5f\x6c\x65\x74\x74\x65\x72\x73\x3b\x03\x00\x55\xc8\x62\xd6\x18\x00\x00\x00")
+
+func postgres5_create_table_dead_lettersDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres5_create_table_dead_lettersDownSql,
+		"postgres/5_create_table_dead_letters.down.sql",
+	)
+}
+
+func postgres5_create_table_dead_lettersDownSql() (*asset, error) {
+	bytes, err := postgres5_create_table_dead_lettersDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/5_create_table_dead_letters.down.sql", size: 24, mode: os.FileMode(420), modTime: time.Unix(1792301235, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres5_create_table_dead_lettersUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x5c\xcb\xb1\xae\x82\x40\x10\x85\xe1\x9e\xa7\x38\x25\x24\xb7\xba\x06\x1b\xab\x81\x4c\x74\x23\x20\x59\x47\x23\x15\x59\xd9\x29\x34\x06\x23\x2c\xef\x6f\x82\xc6\x82\xf2\xe4\xfb\x4f\x6e\x99\x84\x21\x94\x15\x0c\xaf\xce\xb7\x0f\x0d\x41\x87\x11\x71\x04\x00\x37\x8f\x7c\x47\x36\x5e\xad\x13\xd4\xd6\x94\x64\x1b\xec\xb9\xf9\x9b\x71\x9c\xae\x77\xed\x02\xce\x64\xe7\xe8\x3f\x4d\x13\x54\x07\x41\x75\x2a\x8a\x6f\xa2\xaf\x49\xfb\x4e\x91\x99\xad\xa9\x64\xa1\x83\xba\xf1\xd9\x43\xf8\xb2\x14\xef\x82\x43\xd6\x08\xd3\x67\x77\x83\xba\xa0\xbe\x75\x01\x62\x4a\x3e\x0a\x95\xf5\xef\x12\x25\x9b\xe8\x3d\x00\xcd\xd4\x8d\x2d\xc9\x00\x00\x00")
+
+func postgres5_create_table_dead_lettersUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres5_create_table_dead_lettersUpSql,
+		"postgres/5_create_table_dead_letters.up.sql",
+	)
+}
+
+func postgres5_create_table_dead_lettersUpSql() (*asset, error) {
+	bytes, err := postgres5_create_table_dead_lettersUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/5_create_table_dead_letters.up.sql", size: 201, mode: os.FileMode(420), modTime: time.Unix(1792301280, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres6_create_table_subscriber_checkpointsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x22\x00\xdd\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x73\x75\x62\x73\x63\x72\x69\x62\x65\x72\x5f\x63\x68\x65\x63\x6b\x70\x6f\x69\x6e\x74\x73\x3b\x03\x00\x72\x78\x5b\x07\x22\x00\x00\x00")
+
+func postgres6_create_table_subscriber_checkpointsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres6_create_table_subscriber_checkpointsDownSql,
+		"postgres/6_create_table_subscriber_checkpoints.down.sql",
+	)
+}
+
+func postgres6_create_table_subscriber_checkpointsDownSql() (*asset, error) {
+	bytes, err := postgres6_create_table_subscriber_checkpointsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/6_create_table_subscriber_checkpoints.down.sql", size: 34, mode: os.FileMode(420), modTime: time.Unix(1792301778, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres6_create_table_subscriber_checkpointsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\xce\x3f\x0b\x82\x40\x1c\xc6\xf1\xdd\x57\xf1\x8c\x0a\x4e\x81\x53\xd3\x29\x47\x1d\xa9\xc9\x75\x05\x4e\xa2\x3f\x7f\x90\x05\x66\xf7\xa7\xd7\xdf\x50\x2d\x0e\xcd\xcf\x07\x9e\x6f\xa1\xa5\x30\x12\x46\xe4\xa5\x84\x0b\x83\x23\x3b\x0d\x6c\x3b\xba\x32\xdd\x97\xc7\x34\x7b\x87\x38\x02\x00\xc7\xf6\x35\x11\xe3\x22\x74\xb1\x17\x3a\xde\x64\x59\x82\xfa\x68\x50\x9f\xcb\x32\xfd\x90\x30\xdc\x98\xfc\x5f\xc2\xcf\xc0\x33\x31\x72\xb5\x53\xb5\x59\xad\x61\x19\x7b\xcf\x63\xd7\x7b\x18\x55\xc9\x93\x11\x55\xb3\x22\x8d\x56\x95\xd0\x2d\x0e\xb2\x45\xfc\x6d\x4a\x7f\xcf\x49\x94\x6c\xa3\xf7\x00\x30\x67\x56\x50\xd3\x00\x00\x00")
+
+func postgres6_create_table_subscriber_checkpointsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres6_create_table_subscriber_checkpointsUpSql,
+		"postgres/6_create_table_subscriber_checkpoints.up.sql",
+	)
+}
+
+func postgres6_create_table_subscriber_checkpointsUpSql() (*asset, error) {
+	bytes, err := postgres6_create_table_subscriber_checkpointsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/6_create_table_subscriber_checkpoints.up.sql", size: 211, mode: os.FileMode(420), modTime: time.Unix(1792301778, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres7_add_rejected_to_event_historyDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x2f\x00\xd0\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x65\x76\x65\x6e\x74\x5f\x68\x69\x73\x74\x6f\x72\x79\x20\x44\x52\x4f\x50\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x72\x65\x6a\x65\x63\x74\x65\x64\x3b\x03\x00\xfc\x5d\x91\x66\x2f\x00\x00\x00")
+
+func postgres7_add_rejected_to_event_historyDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres7_add_rejected_to_event_historyDownSql,
+		"postgres/7_add_rejected_to_event_history.down.sql",
+	)
+}
+
+func postgres7_add_rejected_to_event_historyDownSql() (*asset, error) {
+	bytes, err := postgres7_add_rejected_to_event_historyDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/7_add_rejected_to_event_history.down.sql", size: 47, mode: os.FileMode(420), modTime: time.Unix(1792302158, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres7_add_rejected_to_event_historyUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x4e\x00\xb1\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x65\x76\x65\x6e\x74\x5f\x68\x69\x73\x74\x6f\x72\x79\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x72\x65\x6a\x65\x63\x74\x65\x64\x20\x42\x4f\x4f\x4c\x45\x41\x4e\x20\x4e\x4f\x54\x20\x4e\x55\x4c\x4c\x20\x44\x45\x46\x41\x55\x4c\x54\x20\x46\x41\x4c\x53\x45\x3b\x0a\x03\x00\x94\xf0\x36\x43\x4e\x00\x00\x00")
+
+func postgres7_add_rejected_to_event_historyUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres7_add_rejected_to_event_historyUpSql,
+		"postgres/7_add_rejected_to_event_history.up.sql",
+	)
+}
+
+func postgres7_add_rejected_to_event_historyUpSql() (*asset, error) {
+	bytes, err := postgres7_add_rejected_to_event_historyUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/7_add_rejected_to_event_history.up.sql", size: 78, mode: os.FileMode(420), modTime: time.Unix(1792302158, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres8_add_version_to_eventsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x27\x00\xd8\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x65\x76\x65\x6e\x74\x73\x20\x44\x52\x4f\x50\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x76\x65\x72\x73\x69\x6f\x6e\x3b\x03\x00\xe8\x3b\xf9\xa5\x27\x00\x00\x00")
+
+func postgres8_add_version_to_eventsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres8_add_version_to_eventsDownSql,
+		"postgres/8_add_version_to_events.down.sql",
+	)
+}
+
+func postgres8_add_version_to_eventsDownSql() (*asset, error) {
+	bytes, err := postgres8_add_version_to_eventsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/8_add_version_to_events.down.sql", size: 39, mode: os.FileMode(420), modTime: time.Unix(1792302388, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres8_add_version_to_eventsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x3e\x00\xc1\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x65\x76\x65\x6e\x74\x73\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x76\x65\x72\x73\x69\x6f\x6e\x20\x49\x4e\x54\x20\x4e\x4f\x54\x20\x4e\x55\x4c\x4c\x20\x44\x45\x46\x41\x55\x4c\x54\x20\x30\x3b\x0a\x03\x00\xcc\x71\x0c\xa0\x3e\x00\x00\x00")
+
+func postgres8_add_version_to_eventsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres8_add_version_to_eventsUpSql,
+		"postgres/8_add_version_to_events.up.sql",
+	)
+}
+
+func postgres8_add_version_to_eventsUpSql() (*asset, error) {
+	bytes, err := postgres8_add_version_to_eventsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/8_add_version_to_events.up.sql", size: 62, mode: os.FileMode(420), modTime: time.Unix(1792302388, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres9_add_error_class_to_eventsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x2b\x00\xd4\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x65\x76\x65\x6e\x74\x73\x20\x44\x52\x4f\x50\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x65\x72\x72\x6f\x72\x5f\x63\x6c\x61\x73\x73\x3b\x03\x00\x5c\xe3\xe7\x10\x2b\x00\x00\x00")
+
+func postgres9_add_error_class_to_eventsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres9_add_error_class_to_eventsDownSql,
+		"postgres/9_add_error_class_to_events.down.sql",
+	)
+}
+
+func postgres9_add_error_class_to_eventsDownSql() (*asset, error) {
+	bytes, err := postgres9_add_error_class_to_eventsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/9_add_error_class_to_events.down.sql", size: 43, mode: os.FileMode(420), modTime: time.Unix(1792304230, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _postgres9_add_error_class_to_eventsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x38\x00\xc7\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x65\x76\x65\x6e\x74\x73\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x65\x72\x72\x6f\x72\x5f\x63\x6c\x61\x73\x73\x20\x56\x41\x52\x43\x48\x41\x52\x28\x32\x35\x35\x29\x3b\x0a\x03\x00\xc6\x63\x6b\x6b\x38\x00\x00\x00")
+
+func postgres9_add_error_class_to_eventsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_postgres9_add_error_class_to_eventsUpSql,
+		"postgres/9_add_error_class_to_events.up.sql",
+	)
+}
+
+func postgres9_add_error_class_to_eventsUpSql() (*asset, error) {
+	bytes, err := postgres9_add_error_class_to_eventsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "postgres/9_add_error_class_to_events.up.sql", size: 56, mode: os.FileMode(420), modTime: time.Unix(1792304230, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite310_create_table_scheduled_messagesDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x1e\x00\xe1\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x73\x63\x68\x65\x64\x75\x6c\x65\x64\x5f\x6d\x65\x73\x73\x61\x67\x65\x73\x3b\x03\x00\xdd\xe7\xf0\x65\x1e\x00\x00\x00")
+
+func sqlite310_create_table_scheduled_messagesDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite310_create_table_scheduled_messagesDownSql,
+		"sqlite3/10_create_table_scheduled_messages.down.sql",
+	)
+}
+
+func sqlite310_create_table_scheduled_messagesDownSql() (*asset, error) {
+	bytes, err := sqlite310_create_table_scheduled_messagesDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/10_create_table_scheduled_messages.down.sql", size: 30, mode: os.FileMode(420), modTime: time.Unix(1792304482, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite310_create_table_scheduled_messagesUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x90\x41\x4b\x03\x31\x10\x46\xef\xfb\x2b\xbe\x63\x03\x3d\x29\xf5\xd2\x53\xda\x0e\x1a\xdc\xa6\x1a\xb2\xd2\x9e\x42\x4c\x06\xad\xa8\xb0\x26\x01\x7f\xbe\x90\x55\x0b\xcb\xb2\xe7\xef\xcd\x83\x79\x5b\x43\xd2\x12\xac\xdc\xb4\x84\x14\x5e\x39\x96\x77\x8e\xee\x83\x53\xf2\x2f\x9c\xb0\x68\x00\xe0\x1c\xb1\xbd\x93\x66\x71\x7d\x23\xf0\x60\xd4\x5e\x9a\x13\xee\xe9\xb4\xac\x63\x5f\xb8\x30\x9e\xa4\xa9\xc8\xd5\x6a\x25\xa0\x0f\x16\xba\x6b\xdb\x01\x48\xdc\x17\xfe\x0c\x8c\x8d\xba\x55\xda\x8e\xd7\xf2\xfc\xc6\x21\xcf\x09\xa2\xcf\x1e\x96\x8e\xe3\xd3\x58\xd8\xf9\x8c\x9d\xb4\x64\xd5\x9e\x46\x6b\xf8\x62\x9f\x39\x4e\x12\x8d\x58\x37\xbf\xaf\x2b\xbd\xa3\xe3\xc4\xeb\x6e\xb0\xbb\x73\xfc\xc6\x41\x4f\xb6\x19\x88\x8b\xaa\xd3\xea\xb1\x9b\x31\xd6\x52\xee\x2f\xc7\x9c\xb9\x92\xcb\xff\x72\x62\xdd\xfc\x0c\x00\xc6\x4f\x20\x67\xa9\x01\x00\x00")
+
+func sqlite310_create_table_scheduled_messagesUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite310_create_table_scheduled_messagesUpSql,
+		"sqlite3/10_create_table_scheduled_messages.up.sql",
+	)
+}
+
+func sqlite310_create_table_scheduled_messagesUpSql() (*asset, error) {
+	bytes, err := sqlite310_create_table_scheduled_messagesUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/10_create_table_scheduled_messages.up.sql", size: 425, mode: os.FileMode(420), modTime: time.Unix(1792306367, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite311_add_retention_index_to_eventsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x2c\x00\xd3\xff\x44\x52\x4f\x50\x20\x49\x4e\x44\x45\x58\x20\x65\x76\x65\x6e\x74\x73\x5f\x6e\x61\x6d\x65\x5f\x74\x72\x61\x6e\x73\x69\x74\x69\x6f\x6e\x65\x64\x5f\x61\x74\x5f\x69\x64\x78\x3b\x0a\x03\x00\x79\xf3\xa3\xc3\x2c\x00\x00\x00")
+
+func sqlite311_add_retention_index_to_eventsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite311_add_retention_index_to_eventsDownSql,
+		"sqlite3/11_add_retention_index_to_events.down.sql",
+	)
+}
+
+func sqlite311_add_retention_index_to_eventsDownSql() (*asset, error) {
+	bytes, err := sqlite311_add_retention_index_to_eventsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/11_add_retention_index_to_events.down.sql", size: 44, mode: os.FileMode(420), modTime: time.Unix(1792307465, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite311_add_retention_index_to_eventsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x50\x00\xaf\xff\x43\x52\x45\x41\x54\x45\x20\x49\x4e\x44\x45\x58\x20\x65\x76\x65\x6e\x74\x73\x5f\x6e\x61\x6d\x65\x5f\x74\x72\x61\x6e\x73\x69\x74\x69\x6f\x6e\x65\x64\x5f\x61\x74\x5f\x69\x64\x78\x20\x4f\x4e\x20\x65\x76\x65\x6e\x74\x73\x20\x28\x6e\x61\x6d\x65\x2c\x20\x74\x72\x61\x6e\x73\x69\x74\x69\x6f\x6e\x65\x64\x5f\x61\x74\x29\x3b\x0a\x03\x00\xd3\x8a\x7b\x90\x50\x00\x00\x00")
+
+func sqlite311_add_retention_index_to_eventsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite311_add_retention_index_to_eventsUpSql,
+		"sqlite3/11_add_retention_index_to_events.up.sql",
+	)
+}
+
+func sqlite311_add_retention_index_to_eventsUpSql() (*asset, error) {
+	bytes, err := sqlite311_add_retention_index_to_eventsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/11_add_retention_index_to_events.up.sql", size: 80, mode: os.FileMode(420), modTime: time.Unix(1792307465, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite312_add_log_id_to_subscriber_checkpointsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x84\x90\xbd\x4e\xc3\x40\x10\x84\xfb\x7b\x8a\x29\x6d\xc9\x15\x52\x2a\x57\x17\x67\x81\x13\xe7\x73\xb4\x59\x90\x52\x59\xf1\x79\x25\x0c\x28\x09\xfe\xe1\xf9\x29\xf8\x11\xb2\x64\x53\xef\xa7\xd9\x6f\xa6\x60\xb2\x42\x10\xbb\xf5\x84\x61\x6a\x86\xd8\x77\x8d\xf6\x75\x7c\xd6\xf8\x7a\xbd\x74\xe7\x71\xa8\x2f\x6f\x2d\x12\x03\x00\x83\xf6\x1f\x5d\x54\x3c\x59\x2e\xee\x2d\x27\x37\x9b\x4d\x8a\x50\x09\xc2\xa3\xf7\xd9\x17\x32\x35\x2f\x1a\xc7\x55\x44\xdf\x27\x3d\x47\xc5\xd6\xdd\xb9\x20\xb3\xeb\x74\x6d\x4f\xa3\xb6\xf5\x69\xc4\xce\x0a\x89\x2b\x69\x46\xec\xd9\x95\x96\x8f\x78\xa0\x23\x92\x6f\xa5\xec\xe7\x71\x6a\xd2\xdc\xb8\x70\x20\x16\xb8\x20\xd5\x5a\xa9\x03\x79\x2a\x04\xf3\x88\xec\xd7\x30\xfb\x6b\x73\xcb\x55\xb9\x90\x96\x9b\x1d\x57\xfb\xd5\x15\x73\x63\xbd\x10\xff\xbf\x34\x53\xb0\x25\x61\xd1\x3c\x37\x9f\x03\x00\x10\x3a\xe9\x55\xb4\x01\x00\x00")
+
+func sqlite312_add_log_id_to_subscriber_checkpointsDownSqlBytes() ([]byte, error) {
 	return bindataRead(
-		__1_create_table_eventDownSql,
-		"1_create_table_event.down.sql",
+		_sqlite312_add_log_id_to_subscriber_checkpointsDownSql,
+		"sqlite3/12_add_log_id_to_subscriber_checkpoints.down.sql",
 	)
 }
 
-func _1_create_table_eventDownSql() (*asset, error) {
-	bytes, err := _1_create_table_eventDownSqlBytes()
+func sqlite312_add_log_id_to_subscriber_checkpointsDownSql() (*asset, error) {
+	bytes, err := sqlite312_add_log_id_to_subscriber_checkpointsDownSqlBytes()
 	if err != nil {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "1_create_table_event.down.sql", size: 18, mode: os.FileMode(420), modTime: time.Unix(1563875080, 0)}
+	info := bindataFileInfo{name: "sqlite3/12_add_log_id_to_subscriber_checkpoints.down.sql", size: 436, mode: os.FileMode(420), modTime: time.Unix(1792308148, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var __1_create_table_eventUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x5c\xcf\xc1\x4a\xc4\x30\x10\xc6\xf1\x7b\x9f\x62\x8e\x1b\xf0\xb4\xb2\x5e\x3c\xc5\x25\xe0\x62\xad\x12\xa2\xd8\x53\x18\xda\x41\x02\x71\x22\xd3\xa9\xd8\xb7\x17\x1a\x10\xcd\xf9\xf7\xe7\x63\xe6\xec\x9d\x0d\x0e\x82\xbd\xeb\x1d\xd0\x17\xb1\x2e\x70\xe8\x00\x00\xd6\x35\xcd\x70\xbe\xb7\xfe\x70\x7d\x63\xe0\xd9\x5f\x1e\xad\x1f\xe1\xc1\x8d\x57\x3b\x33\x7e\x10\xbc\x5a\x5f\x8b\xa3\x81\xe1\x29\xc0\xf0\xd2\xf7\x95\x85\x54\xb6\x38\x95\x95\x15\x2e\x43\x68\x54\x05\x79\xc1\x49\x53\xe1\x98\xe6\xdf\x99\xe3\xe9\x64\x6a\x90\x38\x69\x42\x2d\x12\x33\xbd\x63\x8e\xc4\x9a\x74\xfb\x17\x36\x93\xf4\xad\x24\x8c\xb9\xdd\x6b\xb2\xa9\xf0\x42\xac\xf1\xcf\x6f\x15\x3e\x71\xcb\x05\x67\x08\xee\xad\xbd\x96\x44\x8a\xec\xd0\x99\xdb\xee\x27\x00\x00\xff\xff\xc6\x5e\x74\xf3\x32\x01\x00\x00")
+var _sqlite312_add_log_id_to_subscriber_checkpointsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x57\x00\xa8\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x73\x75\x62\x73\x63\x72\x69\x62\x65\x72\x5f\x63\x68\x65\x63\x6b\x70\x6f\x69\x6e\x74\x73\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x6c\x6f\x67\x5f\x69\x64\x20\x56\x41\x52\x43\x48\x41\x52\x28\x32\x35\x35\x29\x20\x4e\x4f\x54\x20\x4e\x55\x4c\x4c\x20\x44\x45\x46\x41\x55\x4c\x54\x20\x27\x27\x3b\x0a\x03\x00\x6e\xfb\x7a\xb9\x57\x00\x00\x00")
 
-func _1_create_table_eventUpSqlBytes() ([]byte, error) {
+func sqlite312_add_log_id_to_subscriber_checkpointsUpSqlBytes() ([]byte, error) {
 	return bindataRead(
-		__1_create_table_eventUpSql,
-		"1_create_table_event.up.sql",
+		_sqlite312_add_log_id_to_subscriber_checkpointsUpSql,
+		"sqlite3/12_add_log_id_to_subscriber_checkpoints.up.sql",
 	)
 }
 
-func _1_create_table_eventUpSql() (*asset, error) {
-	bytes, err := _1_create_table_eventUpSqlBytes()
+func sqlite312_add_log_id_to_subscriber_checkpointsUpSql() (*asset, error) {
+	bytes, err := sqlite312_add_log_id_to_subscriber_checkpointsUpSqlBytes()
 	if err != nil {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "1_create_table_event.up.sql", size: 306, mode: os.FileMode(420), modTime: time.Unix(1564139972, 0)}
+	info := bindataFileInfo{name: "sqlite3/12_add_log_id_to_subscriber_checkpoints.up.sql", size: 87, mode: os.FileMode(420), modTime: time.Unix(1792308148, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var _bindataGo = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x01\x00\x00\xff\xff\x00\x00\x00\x00\x00\x00\x00\x00")
+var _sqlite313_widen_webhook_secretDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x6a\x00\x95\xff\x2d\x2d\x20\x53\x51\x4c\x69\x74\x65\x20\x64\x6f\x65\x73\x20\x6e\x6f\x74\x20\x65\x6e\x66\x6f\x72\x63\x65\x20\x74\x68\x65\x20\x73\x69\x7a\x65\x20\x6f\x66\x20\x56\x41\x52\x43\x48\x41\x52\x20\x63\x6f\x6c\x75\x6d\x6e\x73\x2c\x20\x74\x68\x65\x20\x73\x65\x63\x72\x65\x74\x20\x63\x6f\x6c\x75\x6d\x6e\x20\x61\x6c\x72\x65\x61\x64\x79\x20\x68\x6f\x6c\x64\x73\x20\x65\x6e\x63\x72\x79\x70\x74\x65\x64\x20\x73\x65\x63\x72\x65\x74\x73\x0a\x03\x00\x65\x82\xc5\xb4\x6a\x00\x00\x00")
 
-func bindataGoBytes() ([]byte, error) {
+func sqlite313_widen_webhook_secretDownSqlBytes() ([]byte, error) {
 	return bindataRead(
-		_bindataGo,
-		"bindata.go",
+		_sqlite313_widen_webhook_secretDownSql,
+		"sqlite3/13_widen_webhook_secret.down.sql",
 	)
 }
 
-func bindataGo() (*asset, error) {
-	bytes, err := bindataGoBytes()
+func sqlite313_widen_webhook_secretDownSql() (*asset, error) {
+	bytes, err := sqlite313_widen_webhook_secretDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/13_widen_webhook_secret.down.sql", size: 106, mode: os.FileMode(420), modTime: time.Unix(1792308721, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite313_widen_webhook_secretUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x6a\x00\x95\xff\x2d\x2d\x20\x53\x51\x4c\x69\x74\x65\x20\x64\x6f\x65\x73\x20\x6e\x6f\x74\x20\x65\x6e\x66\x6f\x72\x63\x65\x20\x74\x68\x65\x20\x73\x69\x7a\x65\x20\x6f\x66\x20\x56\x41\x52\x43\x48\x41\x52\x20\x63\x6f\x6c\x75\x6d\x6e\x73\x2c\x20\x74\x68\x65\x20\x73\x65\x63\x72\x65\x74\x20\x63\x6f\x6c\x75\x6d\x6e\x20\x61\x6c\x72\x65\x61\x64\x79\x20\x68\x6f\x6c\x64\x73\x20\x65\x6e\x63\x72\x79\x70\x74\x65\x64\x20\x73\x65\x63\x72\x65\x74\x73\x0a\x03\x00\x65\x82\xc5\xb4\x6a\x00\x00\x00")
+
+func sqlite313_widen_webhook_secretUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite313_widen_webhook_secretUpSql,
+		"sqlite3/13_widen_webhook_secret.up.sql",
+	)
+}
+
+func sqlite313_widen_webhook_secretUpSql() (*asset, error) {
+	bytes, err := sqlite313_widen_webhook_secretUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/13_widen_webhook_secret.up.sql", size: 106, mode: os.FileMode(420), modTime: time.Unix(1792308721, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite31_create_table_eventDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x12\x00\xed\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x65\x76\x65\x6e\x74\x73\x3b\x03\x00\x27\x3a\x67\xc6\x12\x00\x00\x00")
+
+func sqlite31_create_table_eventDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite31_create_table_eventDownSql,
+		"sqlite3/1_create_table_event.down.sql",
+	)
+}
+
+func sqlite31_create_table_eventDownSql() (*asset, error) {
+	bytes, err := sqlite31_create_table_eventDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/1_create_table_event.down.sql", size: 18, mode: os.FileMode(436), modTime: time.Unix(1610438053, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite31_create_table_eventUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x8f\x31\x4b\xc4\x40\x10\x85\xfb\xfc\x8a\x57\x5e\xc0\x4a\x39\x1b\xab\xf5\x58\xf0\x30\x46\x59\x56\xb9\xab\x96\x21\x19\x64\x21\xce\xca\x64\x22\xe6\xdf\x0b\x97\x46\xb7\xb0\xfe\xde\xfb\x66\xde\x21\x78\x17\x3d\xa2\xbb\xef\x3c\xf8\x8b\xc5\x66\xec\x1a\x00\x58\x96\x3c\xe2\xf0\xe0\xc2\xee\xe6\xb6\xc5\x4b\x38\x3e\xb9\x70\xc6\xa3\x3f\x5f\x5d\xb0\xd0\x07\xe3\xcd\x85\x4b\xe2\x7a\xbf\x6f\xd1\x3f\x47\xf4\xaf\x5d\xb7\x71\x65\xd3\x35\x0d\x65\x11\xc3\xb1\x8f\x15\x35\x25\x99\x69\xb0\x5c\x24\xe5\xf1\x8f\x67\xab\x67\xc9\x96\xc9\x8a\xa6\x89\xdf\x69\x4a\x2c\x96\x6d\xfd\xef\x20\x7f\x1b\xab\xd0\x54\xfb\xaa\xd8\x50\x64\x66\xb1\xf4\x6b\xdc\xd6\xff\xa4\x75\x2a\x34\x22\xfa\x53\xfd\x2d\xab\x16\x45\xf4\xa7\xd8\xb4\x77\xcd\xcf\x00\x83\x1c\x9b\xf3\x33\x01\x00\x00")
+
+func sqlite31_create_table_eventUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite31_create_table_eventUpSql,
+		"sqlite3/1_create_table_event.up.sql",
+	)
+}
+
+func sqlite31_create_table_eventUpSql() (*asset, error) {
+	bytes, err := sqlite31_create_table_eventUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/1_create_table_event.up.sql", size: 307, mode: os.FileMode(436), modTime: time.Unix(1792307346, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite32_create_table_event_historyDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x19\x00\xe6\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x65\x76\x65\x6e\x74\x5f\x68\x69\x73\x74\x6f\x72\x79\x3b\x03\x00\x14\x23\xc3\x11\x19\x00\x00\x00")
+
+func sqlite32_create_table_event_historyDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite32_create_table_event_historyDownSql,
+		"sqlite3/2_create_table_event_history.down.sql",
+	)
+}
+
+func sqlite32_create_table_event_historyDownSql() (*asset, error) {
+	bytes, err := sqlite32_create_table_event_historyDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/2_create_table_event_history.down.sql", size: 25, mode: os.FileMode(420), modTime: time.Unix(1792300218, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite32_create_table_event_historyUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x5c\x90\xc1\x6a\xc3\x30\x10\x44\xef\xfe\x8a\x39\xc6\xd0\x53\x4b\x7a\xc9\x49\x75\x96\x56\xd4\x96\x8b\xd8\x94\xe4\x24\x44\xb4\x10\x41\x2b\x83\x2a\x97\xe6\xef\x4b\x13\xd3\x60\x9f\xdf\xec\x0c\x6f\x1b\x4b\x8a\x09\xac\x9e\x5a\x82\x7c\x4b\x2a\xee\x14\xbf\xca\x90\xcf\x58\x55\x00\x10\x03\xb4\x61\x7a\x26\x8b\x37\xab\x3b\x65\x0f\x78\xa5\x03\xd4\x8e\x7b\x6d\x1a\x4b\x1d\x19\xbe\xbb\x24\xaf\xd7\xe3\x18\x03\x9a\x17\x65\x57\x0f\x8f\x35\x4c\xcf\x30\xbb\xb6\xbd\x26\x92\xff\x14\xbc\x2b\x7b\xc1\xf7\xeb\xf5\x92\x67\x29\xf9\xec\x8e\xc3\x98\xca\xdf\xe8\x82\x4a\xce\x43\x06\xd3\x7e\xda\x3b\x9e\x7c\x4a\xf2\x31\x2b\x9c\x48\x16\x5f\x24\x38\x5f\xb0\x55\x4c\xac\x3b\xfa\xef\xaa\xea\x4d\x35\x49\x6b\xb3\xa5\xfd\x5c\xda\xdd\x24\x5c\x0c\x3f\xe8\xcd\xf2\x29\xb7\x40\xbd\xa9\x7e\x07\x00\x05\x6a\x56\xdb\x3e\x01\x00\x00")
+
+func sqlite32_create_table_event_historyUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite32_create_table_event_historyUpSql,
+		"sqlite3/2_create_table_event_history.up.sql",
+	)
+}
+
+func sqlite32_create_table_event_historyUpSql() (*asset, error) {
+	bytes, err := sqlite32_create_table_event_historyUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/2_create_table_event_history.up.sql", size: 318, mode: os.FileMode(420), modTime: time.Unix(1792300218, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite33_add_timestamps_to_eventsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x91\xc1\x6e\xf2\x30\x10\x84\xef\x79\x8a\x3d\x82\xe4\xd3\xff\x8b\x5e\x72\x72\xc1\x55\xa3\x86\x04\x19\xb7\x82\x93\x65\xc5\xab\xca\x52\x6a\x23\x67\x83\xe0\xed\x2b\x42\x90\x4c\x50\x39\xcf\xec\xee\x7c\xb3\x2b\x59\x6f\xa0\xa8\x56\x62\x07\x78\x44\x4f\x9d\xa6\x68\x7c\xe7\xc8\x05\x8f\x56\x1b\xd2\xce\x9e\xf2\xec\xd1\xd6\x1f\xac\xa1\xa7\x8e\x26\xe2\x9d\x63\x29\x05\x57\x02\x14\x7f\x2d\xc5\xcd\x13\x5a\x0b\xb3\x0c\x00\xa0\xef\x9d\x85\xe5\x3b\x97\xb3\xff\x2f\x73\xd8\xc8\x62\xcd\xe5\x1e\x3e\xc4\x9e\x0d\xb2\x37\x3f\x08\x5f\x5c\x0e\x8e\x7f\x8b\xc5\x1c\xaa\x5a\x41\xf5\x59\x96\x57\x3d\x22\xc5\xb3\x6e\x42\xef\x09\x8a\x4a\x4d\xd4\x81\xc9\x34\x17\x28\xed\xec\xdd\x9e\xeb\xb8\xf3\x8e\x9c\xa1\x10\x75\x8b\xdf\xa6\xd5\xe8\xc9\xd1\xf9\xd9\x41\x3c\x11\x46\x6f\xda\xe9\xbe\x89\xad\x09\xbe\x43\x4f\x3a\x81\xbb\xce\x1f\xcc\xb9\x0d\xc6\x82\x12\xbb\x69\x5a\x8c\x31\xc4\x41\xc8\xe6\x79\x56\x54\x5b\x21\xd5\x05\xaa\x4e\x5b\xdb\x8a\x52\x2c\xd5\x50\x1b\x1b\xda\x61\x69\x07\x6c\x82\xcc\xfe\x20\x64\x29\x07\x4b\xd2\xb2\x5b\x40\x36\xc6\x79\x93\xf5\x7a\xbc\x3f\x3e\x3b\x7d\x64\x9e\xf1\x52\x09\xf9\xf8\x5c\x29\x2a\xbe\x16\xa0\x6a\xc0\x23\x7a\xea\xf2\xec\x77\x00\x71\x13\x24\x5e\x72\x02\x00\x00")
+
+func sqlite33_add_timestamps_to_eventsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite33_add_timestamps_to_eventsDownSql,
+		"sqlite3/3_add_timestamps_to_events.down.sql",
+	)
+}
+
+func sqlite33_add_timestamps_to_eventsDownSql() (*asset, error) {
+	bytes, err := sqlite33_add_timestamps_to_eventsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/3_add_timestamps_to_events.down.sql", size: 626, mode: os.FileMode(420), modTime: time.Unix(1792307380, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite33_add_timestamps_to_eventsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xbc\x92\xcd\x8e\xb2\x30\x18\x85\xf7\x5c\xc5\x59\x42\xa2\xe4\xdb\xf3\x69\xd2\x81\x77\x32\x26\xfc\x18\xa8\x19\x77\x04\xa5\x86\x66\x94\x1a\xc0\xf9\xb9\xfb\x09\x82\x03\x69\x1c\x92\xd9\x98\xb8\xa9\x87\xa7\xef\xd3\xd3\x32\x9f\x53\x0c\xce\x9e\x7c\x82\x78\x17\x65\x53\x83\x79\x1e\xdc\xc8\xdf\x04\x21\xf6\x95\xc8\x1a\x91\xa7\x59\x03\x8f\x71\xe2\xab\x80\x1c\x63\x1a\xb9\x9c\xf3\xbf\x22\x4d\x95\x95\xb5\x6c\xa4\x2a\x75\xce\x98\xcf\xb1\xcb\xf6\x6f\x07\x79\x3c\xe2\x50\xa9\x13\x9a\x42\x74\x9e\x28\x64\xdd\xa8\xea\x0b\x1f\x85\xa8\x04\xce\xaa\xae\xe5\xee\x28\x8c\xcd\xba\xc5\x6f\x53\x12\xe2\x06\x80\xf1\x41\x16\x70\x23\xe6\x53\xe2\x92\x79\x8d\xda\x9f\x99\x90\x4f\x2e\x47\xb0\x0a\xcd\xc2\x1e\x3e\xb6\xf0\x1c\x47\x41\xb7\x59\x7a\x1b\x58\xe0\xf5\x85\x62\x42\x61\x77\xff\x5f\x2e\x32\xc7\xa2\x9f\x68\xb7\x2b\x6b\xf6\xb3\xb3\xbb\x89\x63\x0a\x79\xda\x9e\x27\xe1\x2c\x58\xf7\xd9\xa8\xa6\x49\x21\xb6\x7d\x90\x90\x7e\x09\x0f\xa8\x09\x2c\xf4\x50\xd8\x65\x76\x12\x43\x70\x5d\x75\x81\xcc\xb1\xbc\x63\xa1\xf7\xa3\x6c\x99\xdf\x35\x50\xbd\x81\x9a\x36\x50\x9d\xc1\xff\xe5\x58\xc1\x9a\xe1\x9f\x35\x59\x9b\x63\x18\x6e\x4c\xed\x5b\x5b\x85\x1e\x6d\x7b\x38\x1d\x5a\x49\x65\xfe\x89\x28\xec\x03\x98\xa3\xbe\x9c\xbb\xe8\xf0\x26\x74\x74\x48\x7e\x41\xb5\xdb\xd3\x79\x2d\xb6\x1c\xe3\x7b\x00\x82\x82\x73\x03\xf8\x03\x00\x00")
+
+func sqlite33_add_timestamps_to_eventsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite33_add_timestamps_to_eventsUpSql,
+		"sqlite3/3_add_timestamps_to_events.up.sql",
+	)
+}
+
+func sqlite33_add_timestamps_to_eventsUpSql() (*asset, error) {
+	bytes, err := sqlite33_add_timestamps_to_eventsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/3_add_timestamps_to_events.up.sql", size: 1016, mode: os.FileMode(420), modTime: time.Unix(1792300421, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite34_create_table_webhooksDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x14\x00\xeb\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x77\x65\x62\x68\x6f\x6f\x6b\x73\x3b\x03\x00\xf6\x9d\xb6\xea\x14\x00\x00\x00")
+
+func sqlite34_create_table_webhooksDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite34_create_table_webhooksDownSql,
+		"sqlite3/4_create_table_webhooks.down.sql",
+	)
+}
+
+func sqlite34_create_table_webhooksDownSql() (*asset, error) {
+	bytes, err := sqlite34_create_table_webhooksDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/4_create_table_webhooks.down.sql", size: 20, mode: os.FileMode(420), modTime: time.Unix(1792300986, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite34_create_table_webhooksUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\xcd\xc1\x6b\xc2\x30\x14\xc7\xf1\x7b\xff\x8a\xdf\xb1\x81\x1d\xc6\xb6\x8e\xc1\x4e\x59\x17\x58\x59\x5b\x25\x44\xb1\xa7\x10\xdb\x87\x06\x6b\x0a\xe9\x53\xf1\xbf\x17\x5a\xd0\x8b\xd7\xf7\xf9\xf2\x7e\xb9\x56\xd2\x28\x18\xf9\x53\x2a\x5c\x68\xbb\x1f\x86\xc3\x88\x34\x01\x00\xdf\x21\xff\x93\x3a\x7d\xff\x14\x58\xea\xa2\x92\xba\xc1\xbf\x6a\x5e\x26\x3c\xc5\x1e\x6b\xa9\xa7\xe0\xed\xf5\xe3\x4b\xa0\x5e\x18\xd4\xab\xb2\x9c\x9d\xce\x14\xd8\x06\x77\xa4\x11\x46\x6d\xcc\x7c\xf5\xc1\xb3\x77\x3c\x44\xdb\xd3\xce\xf5\x96\x02\x7b\xbe\x3e\x1e\x65\x99\x98\xc3\x91\xda\x48\xfc\x04\xda\x48\x8e\xa9\xb3\x8e\xf1\x2b\x8d\x32\x45\xa5\xee\xcb\x89\xf8\x4e\x6e\x03\x00\xde\x62\xae\x36\xd1\x00\x00\x00")
+
+func sqlite34_create_table_webhooksUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite34_create_table_webhooksUpSql,
+		"sqlite3/4_create_table_webhooks.up.sql",
+	)
+}
+
+func sqlite34_create_table_webhooksUpSql() (*asset, error) {
+	bytes, err := sqlite34_create_table_webhooksUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/4_create_table_webhooks.up.sql", size: 209, mode: os.FileMode(420), modTime: time.Unix(1792300986, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite35_create_table_dead_lettersDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x18\x00\xe7\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x64\x65\x61\x64\x5f\x6c\x65\x74\x74\x65\x72\x73\x3b\x03\x00\x55\xc8\x62\xd6\x18\x00\x00\x00")
+
+func sqlite35_create_table_dead_lettersDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite35_create_table_dead_lettersDownSql,
+		"sqlite3/5_create_table_dead_letters.down.sql",
+	)
+}
+
+func sqlite35_create_table_dead_lettersDownSql() (*asset, error) {
+	bytes, err := sqlite35_create_table_dead_lettersDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/5_create_table_dead_letters.down.sql", size: 24, mode: os.FileMode(420), modTime: time.Unix(1792301235, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite35_create_table_dead_lettersUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x5c\xcb\xb1\xce\x82\x30\x14\xc5\xf1\x9d\xa7\x38\x23\x24\xdf\xf4\x19\x5c\x9c\x5a\xbc\xd1\xc6\x02\xa6\xb9\x1a\x99\x48\xa5\x77\xd0\x18\x8c\x50\xde\xdf\x04\x8d\x03\xe3\xc9\xef\x7f\x0a\x47\x8a\x09\xac\xb4\x25\x04\xf1\xa1\x7d\x48\x8c\x32\x8c\x48\x13\x00\xb8\x05\x14\x7b\xe5\xd2\xd5\x3a\xc3\xd1\x99\x52\xb9\x06\x07\x6a\xfe\x66\x1c\xa7\xeb\x5d\xba\x88\xb3\x72\x73\xf4\x9f\xe7\x19\xaa\x9a\x51\x9d\xac\xfd\x26\xf2\x9a\xa4\xef\x04\xda\xec\x4c\xc5\x0b\x1d\xc4\x8f\xcf\x1e\x4c\x97\xa5\x04\x1f\x3d\xb4\xad\xf5\x67\x76\x83\xf8\x28\xa1\xf5\x11\x5b\xc5\xc4\xa6\xa4\xdf\x21\xc9\x36\xc9\x7b\x00\x3f\xb1\xdc\x22\xc7\x00\x00\x00")
+
+func sqlite35_create_table_dead_lettersUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite35_create_table_dead_lettersUpSql,
+		"sqlite3/5_create_table_dead_letters.up.sql",
+	)
+}
+
+func sqlite35_create_table_dead_lettersUpSql() (*asset, error) {
+	bytes, err := sqlite35_create_table_dead_lettersUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/5_create_table_dead_letters.up.sql", size: 199, mode: os.FileMode(420), modTime: time.Unix(1792301280, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite36_create_table_subscriber_checkpointsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x22\x00\xdd\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x73\x75\x62\x73\x63\x72\x69\x62\x65\x72\x5f\x63\x68\x65\x63\x6b\x70\x6f\x69\x6e\x74\x73\x3b\x03\x00\x72\x78\x5b\x07\x22\x00\x00\x00")
+
+func sqlite36_create_table_subscriber_checkpointsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite36_create_table_subscriber_checkpointsDownSql,
+		"sqlite3/6_create_table_subscriber_checkpoints.down.sql",
+	)
+}
+
+func sqlite36_create_table_subscriber_checkpointsDownSql() (*asset, error) {
+	bytes, err := sqlite36_create_table_subscriber_checkpointsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/6_create_table_subscriber_checkpoints.down.sql", size: 34, mode: os.FileMode(420), modTime: time.Unix(1792301778, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite36_create_table_subscriber_checkpointsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\xce\xc1\xca\x82\x40\x14\xc5\xf1\xbd\x4f\x71\x96\x0a\xae\x3e\x70\xf5\xad\x46\x1b\x6a\x48\x2d\x86\x5b\xe0\x4a\xf4\x7a\x21\x0b\xcc\x9c\x99\x9e\xbf\x45\xb5\x71\xd1\xfa\xfc\xe0\xfc\x0b\xab\x15\x69\x90\xca\x4b\x0d\x17\x7a\xc7\xcb\xd8\xcb\xd2\xf2\x45\xf8\x36\xdf\xc7\xc9\x3b\xc4\x11\x00\x38\x59\x9e\x23\x0b\xce\xca\x16\x3b\x65\xe3\xbf\x2c\x4b\x50\x1f\x08\xf5\xa9\x2c\xd3\x37\x09\xfd\x55\xd8\xff\x24\xf2\x08\x32\xb1\x20\x37\x5b\x53\xd3\x6a\x0d\xf3\xd0\x79\x19\xda\xce\x63\xa3\x48\x93\xa9\xf4\x4a\x1c\xad\xa9\x94\x6d\xb0\xd7\x0d\xe2\x4f\x52\xfa\x3d\x4e\xa2\xe4\x3f\x7a\x0d\x00\x96\xbb\x6a\x74\xd2\x00\x00\x00")
+
+func sqlite36_create_table_subscriber_checkpointsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite36_create_table_subscriber_checkpointsUpSql,
+		"sqlite3/6_create_table_subscriber_checkpoints.up.sql",
+	)
+}
+
+func sqlite36_create_table_subscriber_checkpointsUpSql() (*asset, error) {
+	bytes, err := sqlite36_create_table_subscriber_checkpointsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/6_create_table_subscriber_checkpoints.up.sql", size: 210, mode: os.FileMode(420), modTime: time.Unix(1792301778, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite37_add_rejected_to_event_historyDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x84\x91\xcf\x6a\xf3\x30\x10\xc4\xef\x7a\x8a\x3d\x26\xa0\xd3\xf7\x91\x5e\x7c\x52\x9d\x6d\x2b\x6a\x4b\x61\xb3\x29\xc9\xc9\x98\x48\x10\x43\x6a\x83\xaa\x94\xe6\xed\x8b\xff\x34\x89\x4d\x4a\x8f\xe2\x37\xcc\xcc\x6a\x96\x64\x57\xa0\xcd\x12\xb7\xe0\x3f\x7d\x1d\x8b\x43\xf5\x11\x9b\x70\x2e\xfa\xd7\xe9\x54\xb9\xa2\x72\x5f\x89\x48\x09\x15\x23\xb0\x7a\xcc\x70\x22\x6d\x8e\x0e\x66\x02\x00\xa0\x72\xa0\x0d\xe3\x33\x12\xac\x48\xe7\x8a\x76\xf0\x8a\x3b\x50\x1b\xb6\xda\xa4\x84\x39\x1a\x96\x9d\xf2\x6a\x0f\xe9\x8b\xa2\xd9\xff\x87\x39\x18\xcb\x60\x36\x59\xd6\x2b\xea\xf2\xdd\xc3\x9b\xa2\x0e\xff\x5b\x2c\xa6\x3c\xf8\x18\xce\xc5\xbe\x39\xd5\xb1\x0d\x9d\x50\x1f\x42\x13\x80\x71\x3b\xe4\xed\x0f\x65\x5d\xfb\xe3\xc8\x70\x20\xc1\x97\xd1\xbb\xa2\x8c\xb0\x54\x8c\xac\x73\xbc\x78\x89\x79\x22\xb4\x59\x23\x71\x1b\x61\xef\xdc\xbd\xc6\x0c\x53\x86\xca\xc9\x01\xb6\x27\xc9\xae\xbc\xbc\xad\x28\xfb\x46\xf2\xa7\x88\xbc\xcd\x7d\x22\x9b\x8f\xbd\x13\xd1\x0d\x73\xe7\xb7\x13\xa1\x32\x46\xfa\x75\x08\x42\xa3\x72\x84\x69\xd9\xcb\x80\x7f\x6f\x0d\xd6\x8c\x39\xcc\xae\x82\x79\x22\xbe\x07\x00\xb3\x72\xcc\x2d\x33\x02\x00\x00")
+
+func sqlite37_add_rejected_to_event_historyDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite37_add_rejected_to_event_historyDownSql,
+		"sqlite3/7_add_rejected_to_event_history.down.sql",
+	)
+}
+
+func sqlite37_add_rejected_to_event_historyDownSql() (*asset, error) {
+	bytes, err := sqlite37_add_rejected_to_event_historyDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/7_add_rejected_to_event_history.down.sql", size: 563, mode: os.FileMode(420), modTime: time.Unix(1792302158, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite37_add_rejected_to_event_historyUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x4e\x00\xb1\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x65\x76\x65\x6e\x74\x5f\x68\x69\x73\x74\x6f\x72\x79\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x72\x65\x6a\x65\x63\x74\x65\x64\x20\x42\x4f\x4f\x4c\x45\x41\x4e\x20\x4e\x4f\x54\x20\x4e\x55\x4c\x4c\x20\x44\x45\x46\x41\x55\x4c\x54\x20\x46\x41\x4c\x53\x45\x3b\x0a\x03\x00\x94\xf0\x36\x43\x4e\x00\x00\x00")
+
+func sqlite37_add_rejected_to_event_historyUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite37_add_rejected_to_event_historyUpSql,
+		"sqlite3/7_add_rejected_to_event_history.up.sql",
+	)
+}
+
+func sqlite37_add_rejected_to_event_historyUpSql() (*asset, error) {
+	bytes, err := sqlite37_add_rejected_to_event_historyUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/7_add_rejected_to_event_history.up.sql", size: 78, mode: os.FileMode(420), modTime: time.Unix(1792302158, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite38_add_version_to_eventsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x92\x4f\x6f\xe2\x30\x10\xc5\xef\xf9\x14\x73\x24\x92\x4f\xbb\x62\x2f\x39\x79\x61\xaa\x46\xcd\x1f\x64\xdc\x0a\x4e\x96\x95\x58\x95\xa5\xd4\x41\xc6\xa9\xe0\xdb\x57\x24\x21\x31\xa6\x70\xfe\xbd\x67\xcf\x7b\x33\x6b\x56\x6e\x20\x2d\xd6\xb8\x03\xf5\xad\x8c\x3b\x0a\x67\xa5\x39\x6a\xa7\x5b\xa3\x6a\x21\x9d\xd0\xf5\x29\x89\xee\x65\xdd\xa1\x96\xee\xa9\xa2\xb2\xea\x46\xb1\x62\x48\x39\x02\xa7\xff\x33\xbc\x6a\xda\xa6\x86\x45\x04\x00\xd0\x75\xba\x86\xd5\x2b\x65\x8b\xbf\xff\x62\xd8\xb0\x34\xa7\x6c\x0f\x6f\xb8\x27\x3d\x36\xf2\x4b\xc1\x07\x65\xbd\xe2\xcf\x72\x19\x43\x51\x72\x28\xde\xb3\x6c\xe0\x56\x39\x7b\x16\x55\xdb\x19\x07\x69\xc1\x03\xda\x67\x92\xd5\x25\x94\xd0\xf5\xcd\x3b\x83\x5d\x1b\xed\xb4\x74\xad\x15\x8d\xfa\x94\x8d\x50\xc6\x69\x77\x7e\xf6\xa1\x3a\x39\x65\x8d\x6c\xc2\xf7\x02\x59\xd5\x9a\xa3\x32\x4e\x78\xe1\x06\xff\x41\x9e\x9b\x56\xd6\xc0\x71\x17\x4e\xab\xac\x6d\x6d\x0f\xc6\x37\xa6\x22\x61\x4d\x39\xf2\x34\xc7\x01\xcc\x3b\x08\x40\xb0\xc3\x89\x46\x71\x12\xa5\xc5\x16\x19\xbf\x94\x54\xfa\x5b\xd8\x62\x86\x2b\xde\xaf\x81\xf4\x6d\x13\xbf\x53\x12\x54\x48\x1e\x34\x46\xfc\x5e\x88\x97\x9e\x5c\x03\x93\x21\x1e\xf1\x52\x11\x2f\x08\xb9\x9b\xfd\x85\x95\xf9\x38\xe8\x78\x65\xfe\x05\x25\x11\xcd\x38\xb2\xfb\xab\x62\x58\xd0\x1c\x61\x0a\x39\xdd\xdf\x93\x1b\x85\xb2\x18\x01\x2c\x66\x12\xff\x6e\x9d\x67\x0e\xad\x33\x79\x60\x0d\x22\x86\xfe\x00\xc7\x49\xf4\x33\x00\xde\xfe\x05\xab\xa6\x03\x00\x00")
+
+func sqlite38_add_version_to_eventsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite38_add_version_to_eventsDownSql,
+		"sqlite3/8_add_version_to_events.down.sql",
+	)
+}
+
+func sqlite38_add_version_to_eventsDownSql() (*asset, error) {
+	bytes, err := sqlite38_add_version_to_eventsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/8_add_version_to_events.down.sql", size: 934, mode: os.FileMode(420), modTime: time.Unix(1792307380, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite38_add_version_to_eventsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x3e\x00\xc1\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x65\x76\x65\x6e\x74\x73\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x76\x65\x72\x73\x69\x6f\x6e\x20\x49\x4e\x54\x20\x4e\x4f\x54\x20\x4e\x55\x4c\x4c\x20\x44\x45\x46\x41\x55\x4c\x54\x20\x30\x3b\x0a\x03\x00\xcc\x71\x0c\xa0\x3e\x00\x00\x00")
+
+func sqlite38_add_version_to_eventsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite38_add_version_to_eventsUpSql,
+		"sqlite3/8_add_version_to_events.up.sql",
+	)
+}
+
+func sqlite38_add_version_to_eventsUpSql() (*asset, error) {
+	bytes, err := sqlite38_add_version_to_eventsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/8_add_version_to_events.up.sql", size: 62, mode: os.FileMode(420), modTime: time.Unix(1792302388, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite39_add_error_class_to_eventsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x92\xcd\x0e\xda\x30\x10\x84\xef\x79\x8a\x3d\x12\xc9\x87\xaa\x15\xbd\xe4\xe4\x12\xa3\x46\xcd\x0f\x32\xa6\x82\x93\x65\x25\x56\x65\x29\xb5\x91\xe3\x20\x78\xfb\x8a\x24\x24\xc6\x14\xce\xdf\x8c\xbd\x33\xbb\x29\xad\x76\x90\x95\x29\x39\x82\xbc\x48\xed\x3a\xee\xac\xd0\x9d\x72\xca\x68\xd9\x70\xe1\xb8\x6a\xae\x49\xf4\x2a\xeb\xcf\x8d\x70\x1f\x15\xb5\x95\x4f\x8a\x0d\x25\x98\x11\x60\xf8\x47\x4e\x1e\x1a\xd3\x36\xb0\x8a\x00\x00\xfa\x5e\x35\xb0\xf9\x89\xe9\xea\xdb\xf7\x18\x76\x34\x2b\x30\x3d\xc1\x2f\x72\x42\x03\xd6\xe2\xaf\x84\xdf\x98\x0e\x8a\xaf\xeb\x75\x0c\x65\xc5\xa0\x3c\xe4\xf9\xc8\xad\x74\xf6\xc6\x6b\xd3\x6b\x07\x59\xc9\x02\x3a\x64\x12\xf5\x3d\x14\x57\xcd\xd3\x3b\xa3\x5d\x69\xe5\x94\x70\xc6\xf2\x56\xfe\x11\x2d\x97\xda\x29\x77\xfb\xf4\xa1\xbc\x3a\x69\xb5\x68\xc3\xf7\x02\x59\x6d\x74\x27\xb5\xe3\x5e\xb8\xd1\x7f\x16\xb7\xd6\x88\x06\x18\x39\x86\xd3\x4a\x6b\x8d\x1d\xc0\xf4\xc6\x5c\x24\xa4\x98\x11\x96\x15\x64\x04\xcb\x0e\x02\x10\xec\x30\xa0\x17\x69\x3b\x65\xf4\x53\x4f\x90\x92\x2d\x3e\xe4\x0c\xbe\x44\x71\x12\x65\xe5\x9e\x50\x76\x17\x54\xfe\xa6\xf6\x24\x27\x1b\x36\xac\x0a\x0d\x1b\x41\x7e\xef\x28\xa8\x19\xbd\x69\x15\xf9\xdd\x21\xaf\x21\xf4\x28\x05\x8d\x15\x20\x2f\x39\xf2\xc2\xa2\x30\x1f\x9a\x23\x6d\x69\x55\x4c\x13\x4f\x27\xe9\x9f\x5b\x12\xe1\x9c\x11\xfa\x7a\x82\x94\x94\xb8\x20\x30\xa7\x9d\x8f\xf5\xc3\x41\x43\x55\x4e\x00\x56\x0b\x89\xff\x6f\x5d\x86\x0f\xad\x0b\x79\x63\x0d\xb2\x86\xfe\x00\xc7\x49\xf4\x6f\x00\x2e\x98\xaf\x80\xd3\x03\x00\x00")
+
+func sqlite39_add_error_class_to_eventsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite39_add_error_class_to_eventsDownSql,
+		"sqlite3/9_add_error_class_to_events.down.sql",
+	)
+}
+
+func sqlite39_add_error_class_to_eventsDownSql() (*asset, error) {
+	bytes, err := sqlite39_add_error_class_to_eventsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sqlite3/9_add_error_class_to_events.down.sql", size: 979, mode: os.FileMode(420), modTime: time.Unix(1792307380, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlite39_add_error_class_to_eventsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x38\x00\xc7\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x65\x76\x65\x6e\x74\x73\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x65\x72\x72\x6f\x72\x5f\x63\x6c\x61\x73\x73\x20\x56\x41\x52\x43\x48\x41\x52\x28\x32\x35\x35\x29\x3b\x0a\x03\x00\xc6\x63\x6b\x6b\x38\x00\x00\x00")
+
+func sqlite39_add_error_class_to_eventsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlite39_add_error_class_to_eventsUpSql,
+		"sqlite3/9_add_error_class_to_events.up.sql",
+	)
+}
+
+func sqlite39_add_error_class_to_eventsUpSql() (*asset, error) {
+	bytes, err := sqlite39_add_error_class_to_eventsUpSqlBytes()
 	if err != nil {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "bindata.go", size: 0, mode: os.FileMode(420), modTime: time.Unix(1564139975, 0)}
+	info := bindataFileInfo{name: "sqlite3/9_add_error_class_to_events.up.sql", size: 56, mode: os.FileMode(420), modTime: time.Unix(1792304230, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -191,9 +1241,59 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"1_create_table_event.down.sql": _1_create_table_eventDownSql,
-	"1_create_table_event.up.sql":   _1_create_table_eventUpSql,
-	"bindata.go":                    bindataGo,
+	"bindata.go":                                                bindataGo,
+	"postgres/10_create_table_scheduled_messages.down.sql":      postgres10_create_table_scheduled_messagesDownSql,
+	"postgres/10_create_table_scheduled_messages.up.sql":        postgres10_create_table_scheduled_messagesUpSql,
+	"postgres/11_add_retention_index_to_events.down.sql":        postgres11_add_retention_index_to_eventsDownSql,
+	"postgres/11_add_retention_index_to_events.up.sql":          postgres11_add_retention_index_to_eventsUpSql,
+	"postgres/12_add_log_id_to_subscriber_checkpoints.down.sql": postgres12_add_log_id_to_subscriber_checkpointsDownSql,
+	"postgres/12_add_log_id_to_subscriber_checkpoints.up.sql":   postgres12_add_log_id_to_subscriber_checkpointsUpSql,
+	"postgres/13_widen_webhook_secret.down.sql":                 postgres13_widen_webhook_secretDownSql,
+	"postgres/13_widen_webhook_secret.up.sql":                   postgres13_widen_webhook_secretUpSql,
+	"postgres/1_create_table_event.down.sql":                    postgres1_create_table_eventDownSql,
+	"postgres/1_create_table_event.up.sql":                      postgres1_create_table_eventUpSql,
+	"postgres/2_create_table_event_history.down.sql":            postgres2_create_table_event_historyDownSql,
+	"postgres/2_create_table_event_history.up.sql":              postgres2_create_table_event_historyUpSql,
+	"postgres/3_add_timestamps_to_events.down.sql":              postgres3_add_timestamps_to_eventsDownSql,
+	"postgres/3_add_timestamps_to_events.up.sql":                postgres3_add_timestamps_to_eventsUpSql,
+	"postgres/4_create_table_webhooks.down.sql":                 postgres4_create_table_webhooksDownSql,
+	"postgres/4_create_table_webhooks.up.sql":                   postgres4_create_table_webhooksUpSql,
+	"postgres/5_create_table_dead_letters.down.sql":             postgres5_create_table_dead_lettersDownSql,
+	"postgres/5_create_table_dead_letters.up.sql":               postgres5_create_table_dead_lettersUpSql,
+	"postgres/6_create_table_subscriber_checkpoints.down.sql":   postgres6_create_table_subscriber_checkpointsDownSql,
+	"postgres/6_create_table_subscriber_checkpoints.up.sql":     postgres6_create_table_subscriber_checkpointsUpSql,
+	"postgres/7_add_rejected_to_event_history.down.sql":         postgres7_add_rejected_to_event_historyDownSql,
+	"postgres/7_add_rejected_to_event_history.up.sql":           postgres7_add_rejected_to_event_historyUpSql,
+	"postgres/8_add_version_to_events.down.sql":                 postgres8_add_version_to_eventsDownSql,
+	"postgres/8_add_version_to_events.up.sql":                   postgres8_add_version_to_eventsUpSql,
+	"postgres/9_add_error_class_to_events.down.sql":             postgres9_add_error_class_to_eventsDownSql,
+	"postgres/9_add_error_class_to_events.up.sql":               postgres9_add_error_class_to_eventsUpSql,
+	"sqlite3/10_create_table_scheduled_messages.down.sql":       sqlite310_create_table_scheduled_messagesDownSql,
+	"sqlite3/10_create_table_scheduled_messages.up.sql":         sqlite310_create_table_scheduled_messagesUpSql,
+	"sqlite3/11_add_retention_index_to_events.down.sql":         sqlite311_add_retention_index_to_eventsDownSql,
+	"sqlite3/11_add_retention_index_to_events.up.sql":           sqlite311_add_retention_index_to_eventsUpSql,
+	"sqlite3/12_add_log_id_to_subscriber_checkpoints.down.sql":  sqlite312_add_log_id_to_subscriber_checkpointsDownSql,
+	"sqlite3/12_add_log_id_to_subscriber_checkpoints.up.sql":    sqlite312_add_log_id_to_subscriber_checkpointsUpSql,
+	"sqlite3/13_widen_webhook_secret.down.sql":                  sqlite313_widen_webhook_secretDownSql,
+	"sqlite3/13_widen_webhook_secret.up.sql":                    sqlite313_widen_webhook_secretUpSql,
+	"sqlite3/1_create_table_event.down.sql":                     sqlite31_create_table_eventDownSql,
+	"sqlite3/1_create_table_event.up.sql":                       sqlite31_create_table_eventUpSql,
+	"sqlite3/2_create_table_event_history.down.sql":             sqlite32_create_table_event_historyDownSql,
+	"sqlite3/2_create_table_event_history.up.sql":               sqlite32_create_table_event_historyUpSql,
+	"sqlite3/3_add_timestamps_to_events.down.sql":               sqlite33_add_timestamps_to_eventsDownSql,
+	"sqlite3/3_add_timestamps_to_events.up.sql":                 sqlite33_add_timestamps_to_eventsUpSql,
+	"sqlite3/4_create_table_webhooks.down.sql":                  sqlite34_create_table_webhooksDownSql,
+	"sqlite3/4_create_table_webhooks.up.sql":                    sqlite34_create_table_webhooksUpSql,
+	"sqlite3/5_create_table_dead_letters.down.sql":              sqlite35_create_table_dead_lettersDownSql,
+	"sqlite3/5_create_table_dead_letters.up.sql":                sqlite35_create_table_dead_lettersUpSql,
+	"sqlite3/6_create_table_subscriber_checkpoints.down.sql":    sqlite36_create_table_subscriber_checkpointsDownSql,
+	"sqlite3/6_create_table_subscriber_checkpoints.up.sql":      sqlite36_create_table_subscriber_checkpointsUpSql,
+	"sqlite3/7_add_rejected_to_event_history.down.sql":          sqlite37_add_rejected_to_event_historyDownSql,
+	"sqlite3/7_add_rejected_to_event_history.up.sql":            sqlite37_add_rejected_to_event_historyUpSql,
+	"sqlite3/8_add_version_to_events.down.sql":                  sqlite38_add_version_to_eventsDownSql,
+	"sqlite3/8_add_version_to_events.up.sql":                    sqlite38_add_version_to_eventsUpSql,
+	"sqlite3/9_add_error_class_to_events.down.sql":              sqlite39_add_error_class_to_eventsDownSql,
+	"sqlite3/9_add_error_class_to_events.up.sql":                sqlite39_add_error_class_to_eventsUpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -237,9 +1337,63 @@ type bintree struct {
 }
 
 var _bintree = &bintree{nil, map[string]*bintree{
-	"1_create_table_event.down.sql": &bintree{_1_create_table_eventDownSql, map[string]*bintree{}},
-	"1_create_table_event.up.sql":   &bintree{_1_create_table_eventUpSql, map[string]*bintree{}},
-	"bindata.go":                    &bintree{bindataGo, map[string]*bintree{}},
+	"bindata.go": &bintree{bindataGo, map[string]*bintree{}},
+	"postgres": &bintree{nil, map[string]*bintree{
+		"10_create_table_scheduled_messages.down.sql":      &bintree{postgres10_create_table_scheduled_messagesDownSql, map[string]*bintree{}},
+		"10_create_table_scheduled_messages.up.sql":        &bintree{postgres10_create_table_scheduled_messagesUpSql, map[string]*bintree{}},
+		"11_add_retention_index_to_events.down.sql":        &bintree{postgres11_add_retention_index_to_eventsDownSql, map[string]*bintree{}},
+		"11_add_retention_index_to_events.up.sql":          &bintree{postgres11_add_retention_index_to_eventsUpSql, map[string]*bintree{}},
+		"12_add_log_id_to_subscriber_checkpoints.down.sql": &bintree{postgres12_add_log_id_to_subscriber_checkpointsDownSql, map[string]*bintree{}},
+		"12_add_log_id_to_subscriber_checkpoints.up.sql":   &bintree{postgres12_add_log_id_to_subscriber_checkpointsUpSql, map[string]*bintree{}},
+		"13_widen_webhook_secret.down.sql":                 &bintree{postgres13_widen_webhook_secretDownSql, map[string]*bintree{}},
+		"13_widen_webhook_secret.up.sql":                   &bintree{postgres13_widen_webhook_secretUpSql, map[string]*bintree{}},
+		"1_create_table_event.down.sql":                    &bintree{postgres1_create_table_eventDownSql, map[string]*bintree{}},
+		"1_create_table_event.up.sql":                      &bintree{postgres1_create_table_eventUpSql, map[string]*bintree{}},
+		"2_create_table_event_history.down.sql":            &bintree{postgres2_create_table_event_historyDownSql, map[string]*bintree{}},
+		"2_create_table_event_history.up.sql":              &bintree{postgres2_create_table_event_historyUpSql, map[string]*bintree{}},
+		"3_add_timestamps_to_events.down.sql":              &bintree{postgres3_add_timestamps_to_eventsDownSql, map[string]*bintree{}},
+		"3_add_timestamps_to_events.up.sql":                &bintree{postgres3_add_timestamps_to_eventsUpSql, map[string]*bintree{}},
+		"4_create_table_webhooks.down.sql":                 &bintree{postgres4_create_table_webhooksDownSql, map[string]*bintree{}},
+		"4_create_table_webhooks.up.sql":                   &bintree{postgres4_create_table_webhooksUpSql, map[string]*bintree{}},
+		"5_create_table_dead_letters.down.sql":             &bintree{postgres5_create_table_dead_lettersDownSql, map[string]*bintree{}},
+		"5_create_table_dead_letters.up.sql":               &bintree{postgres5_create_table_dead_lettersUpSql, map[string]*bintree{}},
+		"6_create_table_subscriber_checkpoints.down.sql":   &bintree{postgres6_create_table_subscriber_checkpointsDownSql, map[string]*bintree{}},
+		"6_create_table_subscriber_checkpoints.up.sql":     &bintree{postgres6_create_table_subscriber_checkpointsUpSql, map[string]*bintree{}},
+		"7_add_rejected_to_event_history.down.sql":         &bintree{postgres7_add_rejected_to_event_historyDownSql, map[string]*bintree{}},
+		"7_add_rejected_to_event_history.up.sql":           &bintree{postgres7_add_rejected_to_event_historyUpSql, map[string]*bintree{}},
+		"8_add_version_to_events.down.sql":                 &bintree{postgres8_add_version_to_eventsDownSql, map[string]*bintree{}},
+		"8_add_version_to_events.up.sql":                   &bintree{postgres8_add_version_to_eventsUpSql, map[string]*bintree{}},
+		"9_add_error_class_to_events.down.sql":             &bintree{postgres9_add_error_class_to_eventsDownSql, map[string]*bintree{}},
+		"9_add_error_class_to_events.up.sql":               &bintree{postgres9_add_error_class_to_eventsUpSql, map[string]*bintree{}},
+	}},
+	"sqlite3": &bintree{nil, map[string]*bintree{
+		"10_create_table_scheduled_messages.down.sql":      &bintree{sqlite310_create_table_scheduled_messagesDownSql, map[string]*bintree{}},
+		"10_create_table_scheduled_messages.up.sql":        &bintree{sqlite310_create_table_scheduled_messagesUpSql, map[string]*bintree{}},
+		"11_add_retention_index_to_events.down.sql":        &bintree{sqlite311_add_retention_index_to_eventsDownSql, map[string]*bintree{}},
+		"11_add_retention_index_to_events.up.sql":          &bintree{sqlite311_add_retention_index_to_eventsUpSql, map[string]*bintree{}},
+		"12_add_log_id_to_subscriber_checkpoints.down.sql": &bintree{sqlite312_add_log_id_to_subscriber_checkpointsDownSql, map[string]*bintree{}},
+		"12_add_log_id_to_subscriber_checkpoints.up.sql":   &bintree{sqlite312_add_log_id_to_subscriber_checkpointsUpSql, map[string]*bintree{}},
+		"13_widen_webhook_secret.down.sql":                 &bintree{sqlite313_widen_webhook_secretDownSql, map[string]*bintree{}},
+		"13_widen_webhook_secret.up.sql":                   &bintree{sqlite313_widen_webhook_secretUpSql, map[string]*bintree{}},
+		"1_create_table_event.down.sql":                    &bintree{sqlite31_create_table_eventDownSql, map[string]*bintree{}},
+		"1_create_table_event.up.sql":                      &bintree{sqlite31_create_table_eventUpSql, map[string]*bintree{}},
+		"2_create_table_event_history.down.sql":            &bintree{sqlite32_create_table_event_historyDownSql, map[string]*bintree{}},
+		"2_create_table_event_history.up.sql":              &bintree{sqlite32_create_table_event_historyUpSql, map[string]*bintree{}},
+		"3_add_timestamps_to_events.down.sql":              &bintree{sqlite33_add_timestamps_to_eventsDownSql, map[string]*bintree{}},
+		"3_add_timestamps_to_events.up.sql":                &bintree{sqlite33_add_timestamps_to_eventsUpSql, map[string]*bintree{}},
+		"4_create_table_webhooks.down.sql":                 &bintree{sqlite34_create_table_webhooksDownSql, map[string]*bintree{}},
+		"4_create_table_webhooks.up.sql":                   &bintree{sqlite34_create_table_webhooksUpSql, map[string]*bintree{}},
+		"5_create_table_dead_letters.down.sql":             &bintree{sqlite35_create_table_dead_lettersDownSql, map[string]*bintree{}},
+		"5_create_table_dead_letters.up.sql":               &bintree{sqlite35_create_table_dead_lettersUpSql, map[string]*bintree{}},
+		"6_create_table_subscriber_checkpoints.down.sql":   &bintree{sqlite36_create_table_subscriber_checkpointsDownSql, map[string]*bintree{}},
+		"6_create_table_subscriber_checkpoints.up.sql":     &bintree{sqlite36_create_table_subscriber_checkpointsUpSql, map[string]*bintree{}},
+		"7_add_rejected_to_event_history.down.sql":         &bintree{sqlite37_add_rejected_to_event_historyDownSql, map[string]*bintree{}},
+		"7_add_rejected_to_event_history.up.sql":           &bintree{sqlite37_add_rejected_to_event_historyUpSql, map[string]*bintree{}},
+		"8_add_version_to_events.down.sql":                 &bintree{sqlite38_add_version_to_eventsDownSql, map[string]*bintree{}},
+		"8_add_version_to_events.up.sql":                   &bintree{sqlite38_add_version_to_eventsUpSql, map[string]*bintree{}},
+		"9_add_error_class_to_events.down.sql":             &bintree{sqlite39_add_error_class_to_eventsDownSql, map[string]*bintree{}},
+		"9_add_error_class_to_events.up.sql":               &bintree{sqlite39_add_error_class_to_eventsUpSql, map[string]*bintree{}},
+	}},
 }}
 
 // RestoreAsset restores an asset under the given directory
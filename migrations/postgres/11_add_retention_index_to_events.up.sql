@@ -0,0 +1 @@
+CREATE INDEX events_name_transitioned_at_idx ON events (name, transitioned_at);
@@ -0,0 +1 @@
+ALTER TABLE subscriber_checkpoints DROP COLUMN log_id;
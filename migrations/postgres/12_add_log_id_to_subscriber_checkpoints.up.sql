@@ -0,0 +1 @@
+ALTER TABLE subscriber_checkpoints ADD COLUMN log_id VARCHAR(255) NOT NULL DEFAULT '';
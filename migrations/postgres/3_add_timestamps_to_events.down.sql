@@ -0,0 +1,6 @@
+DROP INDEX events_transitioned_at_idx;
+DROP INDEX events_updated_at_idx;
+DROP INDEX events_created_at_idx;
+ALTER TABLE events DROP COLUMN transitioned_at;
+ALTER TABLE events DROP COLUMN updated_at;
+ALTER TABLE events DROP COLUMN created_at;
@@ -0,0 +1 @@
+DROP TABLE webhooks;
\ No newline at end of file
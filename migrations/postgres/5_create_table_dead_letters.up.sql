@@ -0,0 +1,8 @@
+CREATE TABLE dead_letters (
+    id CHAR(36) PRIMARY KEY,
+    subject VARCHAR(255) NOT NULL,
+    sequence BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    data BYTEA,
+    created_at TIMESTAMP NOT NULL
+);
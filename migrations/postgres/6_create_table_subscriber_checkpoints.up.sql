@@ -0,0 +1,7 @@
+CREATE TABLE subscriber_checkpoints (
+    service VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    sequence BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (service, subject)
+);
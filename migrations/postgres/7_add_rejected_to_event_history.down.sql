@@ -0,0 +1 @@
+ALTER TABLE event_history DROP COLUMN rejected;
\ No newline at end of file
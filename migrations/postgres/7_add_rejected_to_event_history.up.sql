@@ -0,0 +1 @@
+ALTER TABLE event_history ADD COLUMN rejected BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1 @@
+ALTER TABLE events ADD COLUMN version INT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE events DROP COLUMN error_class;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE events ADD COLUMN error_class VARCHAR(255);
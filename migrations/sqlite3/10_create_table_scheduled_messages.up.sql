@@ -0,0 +1,11 @@
+CREATE TABLE scheduled_messages (
+    id CHAR(36) PRIMARY KEY,
+    queue VARCHAR(255) NOT NULL,
+    sequence BIGINT NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    data TEXT NOT NULL,
+    due_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX scheduled_messages_due_at_idx ON scheduled_messages (due_at);
+CREATE UNIQUE INDEX scheduled_messages_queue_sequence_idx ON scheduled_messages (queue, sequence);
@@ -0,0 +1 @@
+DROP INDEX events_name_transitioned_at_idx;
@@ -0,0 +1,10 @@
+CREATE TABLE subscriber_checkpoints_old (
+    service VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    sequence BIGINT NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (service, subject)
+);
+INSERT INTO subscriber_checkpoints_old SELECT service, subject, sequence, updated_at FROM subscriber_checkpoints;
+DROP TABLE subscriber_checkpoints;
+ALTER TABLE subscriber_checkpoints_old RENAME TO subscriber_checkpoints;
@@ -0,0 +1 @@
+-- SQLite does not enforce the size of VARCHAR columns, the secret column already holds encrypted secrets
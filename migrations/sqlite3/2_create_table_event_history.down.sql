@@ -0,0 +1 @@
+DROP TABLE event_history;
\ No newline at end of file
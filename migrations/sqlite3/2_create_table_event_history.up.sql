@@ -0,0 +1,10 @@
+CREATE TABLE event_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_uuid CHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    retry_count INT NOT NULL,
+    error TEXT,
+    channel VARCHAR(255),
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX event_history_event_uuid_idx ON event_history (event_uuid);
@@ -0,0 +1,20 @@
+ALTER TABLE events ADD COLUMN created_at DATETIME;
+ALTER TABLE events ADD COLUMN updated_at DATETIME;
+ALTER TABLE events ADD COLUMN transitioned_at DATETIME;
+
+-- backfill from the event history where possible
+UPDATE events SET
+    created_at = COALESCE(
+        (SELECT MIN(h.created_at) FROM event_history h WHERE h.event_uuid = events.uuid),
+        CURRENT_TIMESTAMP),
+    updated_at = COALESCE(
+        (SELECT MAX(h.created_at) FROM event_history h WHERE h.event_uuid = events.uuid),
+        CURRENT_TIMESTAMP),
+    transitioned_at = COALESCE(
+        (SELECT MIN(h.created_at) FROM event_history h WHERE h.event_uuid = events.uuid AND h.name = events.name AND h.id > COALESCE(
+            (SELECT MAX(o.id) FROM event_history o WHERE o.event_uuid = events.uuid AND o.name <> events.name), 0)),
+        CURRENT_TIMESTAMP);
+
+CREATE INDEX events_created_at_idx ON events (created_at);
+CREATE INDEX events_updated_at_idx ON events (updated_at);
+CREATE INDEX events_transitioned_at_idx ON events (transitioned_at);
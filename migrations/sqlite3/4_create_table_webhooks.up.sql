@@ -0,0 +1,8 @@
+CREATE TABLE webhooks (
+    id CHAR(36) PRIMARY KEY,
+    url VARCHAR(2048) NOT NULL,
+    event_names TEXT,
+    initiator_legal_entity VARCHAR(255),
+    secret VARCHAR(255),
+    created_at DATETIME NOT NULL
+);
@@ -0,0 +1 @@
+DROP TABLE dead_letters;
\ No newline at end of file
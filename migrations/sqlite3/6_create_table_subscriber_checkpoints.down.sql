@@ -0,0 +1 @@
+DROP TABLE subscriber_checkpoints;
\ No newline at end of file
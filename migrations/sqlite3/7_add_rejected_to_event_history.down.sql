@@ -0,0 +1,14 @@
+DROP INDEX event_history_event_uuid_idx;
+CREATE TABLE event_history_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_uuid CHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    retry_count INT NOT NULL,
+    error TEXT,
+    channel VARCHAR(255),
+    created_at DATETIME NOT NULL
+);
+INSERT INTO event_history_old SELECT id, event_uuid, name, retry_count, error, channel, created_at FROM event_history;
+DROP TABLE event_history;
+ALTER TABLE event_history_old RENAME TO event_history;
+CREATE INDEX event_history_event_uuid_idx ON event_history (event_uuid);
@@ -0,0 +1,24 @@
+DROP INDEX events_transitioned_at_idx;
+DROP INDEX events_updated_at_idx;
+DROP INDEX events_created_at_idx;
+CREATE TABLE events_old (
+    uuid CHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    retry_count INT NOT NULL,
+    transaction_id VARCHAR(255),
+    initiator_legal_entity VARCHAR(255) NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    consent_id CHAR(36),
+    payload TEXT NOT NULL,
+    error TEXT,
+    created_at DATETIME,
+    updated_at DATETIME,
+    transitioned_at DATETIME,
+    version INT NOT NULL DEFAULT 0
+);
+INSERT INTO events_old SELECT uuid, name, retry_count, transaction_id, initiator_legal_entity, external_id, consent_id, payload, error, created_at, updated_at, transitioned_at, version FROM events;
+DROP TABLE events;
+ALTER TABLE events_old RENAME TO events;
+CREATE INDEX events_created_at_idx ON events (created_at);
+CREATE INDEX events_updated_at_idx ON events (updated_at);
+CREATE INDEX events_transitioned_at_idx ON events (transitioned_at);
@@ -87,17 +87,41 @@ func (mr *MockEventOctopusClientMockRecorder) EventPublisher(clientID interface{
 }
 
 // Subscribe mocks base method
-func (m *MockEventOctopusClient) Subscribe(service, subject string, callbacks map[string]pkg.EventHandlerCallback) error {
+func (m *MockEventOctopusClient) Subscribe(service, subject string, callbacks map[string]pkg.EventHandlerCallback, opts ...pkg.SubscribeOption) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Subscribe", service, subject, callbacks)
+	varargs := []interface{}{service, subject, callbacks}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Subscribe", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Subscribe indicates an expected call of Subscribe
-func (mr *MockEventOctopusClientMockRecorder) Subscribe(service, subject, callbacks interface{}) *gomock.Call {
+func (mr *MockEventOctopusClientMockRecorder) Subscribe(service, subject, callbacks interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockEventOctopusClient)(nil).Subscribe), service, subject, callbacks)
+	varargs := append([]interface{}{service, subject, callbacks}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockEventOctopusClient)(nil).Subscribe), varargs...)
+}
+
+// SubscribeHandlers mocks base method
+func (m *MockEventOctopusClient) SubscribeHandlers(service, subject string, handlers map[string]pkg.EventHandler, opts ...pkg.SubscribeOption) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{service, subject, handlers}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SubscribeHandlers", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SubscribeHandlers indicates an expected call of SubscribeHandlers
+func (mr *MockEventOctopusClientMockRecorder) SubscribeHandlers(service, subject, handlers interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{service, subject, handlers}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeHandlers", reflect.TypeOf((*MockEventOctopusClient)(nil).SubscribeHandlers), varargs...)
 }
 
 // Diagnostics mocks base method
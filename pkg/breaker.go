@@ -0,0 +1,173 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// circuitBreaker stops the event store from processing messages after consecutive failures, so a failing DB or Nats
+// connection isn't hammered by redeliveries. Messages that are not processed are left for redelivery by Nats.
+// When the breaker is open for the timeout, one message is let through to test whether the failure is gone.
+type circuitBreaker struct {
+	mutex     sync.Mutex
+	threshold int
+	timeout   time.Duration
+	failures  int
+	trips     int
+	open      bool
+	openedAt  time.Time
+	lastError error
+	lastAt    time.Time
+}
+
+// CircuitBreakerStatus is the state of the circuit breaker of the event store
+type CircuitBreakerStatus struct {
+	Open bool `json:"open"`
+	// Failures is the number of consecutive failures
+	Failures int `json:"failures"`
+	// Trips is the number of times the breaker opened since start
+	Trips     int        `json:"trips"`
+	OpenedAt  *time.Time `json:"openedAt,omitempty"`
+	LastError string     `json:"lastError,omitempty"`
+	// LastFailure is the time of the last failure
+	LastFailure *time.Time `json:"lastFailure,omitempty"`
+}
+
+// Name returns the name of the CircuitBreakerStatus
+func (cs CircuitBreakerStatus) Name() string {
+	return "Circuit breaker"
+}
+
+// String returns the state of the breaker and the last failure
+func (cs CircuitBreakerStatus) String() string {
+	state := "closed"
+	if cs.Open {
+		state = fmt.Sprintf("open since %s", cs.OpenedAt.Format(time.RFC3339))
+	}
+
+	s := fmt.Sprintf("%s, %d consecutive failures, %d trips", state, cs.Failures, cs.Trips)
+	if cs.LastError != "" {
+		s = fmt.Sprintf("%s, last failure at %s: %s", s, cs.LastFailure.Format(time.RFC3339), cs.LastError)
+	}
+
+	return s
+}
+
+func (octopus *EventOctopus) configureCircuitBreaker() error {
+	if octopus.Config.CircuitBreakerThreshold < 0 {
+		return fmt.Errorf("invalid %s: %d, must be 0 or more", ConfigCircuitBreakerThreshold, octopus.Config.CircuitBreakerThreshold)
+	}
+	if octopus.Config.CircuitBreakerTimeout < 0 {
+		return fmt.Errorf("invalid %s: %d, must be 0 or more", ConfigCircuitBreakerTimeout, octopus.Config.CircuitBreakerTimeout)
+	}
+
+	octopus.breaker.mutex.Lock()
+	defer octopus.breaker.mutex.Unlock()
+
+	octopus.breaker.threshold = octopus.Config.CircuitBreakerThreshold
+	octopus.breaker.timeout = time.Duration(octopus.Config.CircuitBreakerTimeout) * time.Second
+
+	return nil
+}
+
+// allow returns false when the breaker is open, after the timeout it lets a single message through
+func (b *circuitBreaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.open {
+		return true
+	}
+
+	if now().Sub(b.openedAt) >= b.timeout {
+		// restart the timeout, so only this message tests the failure
+		b.openedAt = now()
+		return true
+	}
+
+	return false
+}
+
+// success resets the consecutive failures and closes the breaker
+func (b *circuitBreaker) success() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures = 0
+	if b.open {
+		b.open = false
+		circuitBreakerOpen.Set(0)
+		logrus.Info("Circuit breaker closed, processing messages again")
+	}
+}
+
+// failure counts the error and opens the breaker when the threshold is reached, a threshold of 0 never opens it
+func (b *circuitBreaker) failure(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	b.lastError = err
+	b.lastAt = now()
+
+	if b.open {
+		b.openedAt = now()
+		return
+	}
+
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = now()
+		b.trips++
+		circuitBreakerOpen.Set(1)
+		logrus.WithError(err).Warnf("Circuit breaker opened after %d consecutive failures, pausing message processing for %s", b.failures, b.timeout)
+	}
+}
+
+func (b *circuitBreaker) status() CircuitBreakerStatus {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	status := CircuitBreakerStatus{
+		Open:     b.open,
+		Failures: b.failures,
+		Trips:    b.trips,
+	}
+	if b.open {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	if b.lastError != nil {
+		lastAt := b.lastAt
+		status.LastError = b.lastError.Error()
+		status.LastFailure = &lastAt
+	}
+
+	return status
+}
+
+// CircuitBreakerStatus returns the state of the circuit breaker of the event store
+func (octopus *EventOctopus) CircuitBreakerStatus() CircuitBreakerStatus {
+	return octopus.breaker.status()
+}
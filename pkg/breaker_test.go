@@ -0,0 +1,231 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	natsClient "github.com/nats-io/stan.go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	failure := errors.New("b00m!")
+
+	t.Run("never opens with a threshold of 0", func(t *testing.T) {
+		b := circuitBreaker{}
+		for j := 0; j < 10; j++ {
+			b.failure(failure)
+		}
+
+		assert.True(t, b.allow())
+		assert.False(t, b.status().Open)
+		assert.Equal(t, 10, b.status().Failures)
+	})
+
+	t.Run("opens after consecutive failures", func(t *testing.T) {
+		b := circuitBreaker{threshold: 2, timeout: time.Hour}
+		b.failure(failure)
+		assert.True(t, b.allow())
+		b.failure(failure)
+
+		assert.False(t, b.allow())
+		status := b.status()
+		assert.True(t, status.Open)
+		assert.Equal(t, 1, status.Trips)
+		assert.Equal(t, "b00m!", status.LastError)
+		assert.NotNil(t, status.OpenedAt)
+		assert.Equal(t, float64(1), testutil.ToFloat64(circuitBreakerOpen))
+	})
+
+	t.Run("success resets the failures", func(t *testing.T) {
+		b := circuitBreaker{threshold: 2, timeout: time.Hour}
+		b.failure(failure)
+		b.success()
+		b.failure(failure)
+
+		assert.True(t, b.allow())
+		assert.Equal(t, 1, b.status().Failures)
+	})
+
+	t.Run("lets a single message through after the timeout", func(t *testing.T) {
+		b := circuitBreaker{threshold: 1, timeout: 10 * time.Millisecond}
+		b.failure(failure)
+		assert.False(t, b.allow())
+
+		time.Sleep(20 * time.Millisecond)
+
+		assert.True(t, b.allow())
+		assert.False(t, b.allow())
+	})
+
+	t.Run("a failure after the timeout keeps it open", func(t *testing.T) {
+		b := circuitBreaker{threshold: 1, timeout: 10 * time.Millisecond}
+		b.failure(failure)
+		time.Sleep(20 * time.Millisecond)
+		b.allow()
+
+		b.failure(failure)
+
+		assert.False(t, b.allow())
+		assert.Equal(t, 1, b.status().Trips)
+	})
+
+	t.Run("a success after the timeout closes it", func(t *testing.T) {
+		b := circuitBreaker{threshold: 1, timeout: 10 * time.Millisecond}
+		b.failure(failure)
+		time.Sleep(20 * time.Millisecond)
+		b.allow()
+
+		b.success()
+
+		assert.True(t, b.allow())
+		assert.False(t, b.status().Open)
+		assert.Equal(t, float64(0), testutil.ToFloat64(circuitBreakerOpen))
+	})
+
+	t.Run("String", func(t *testing.T) {
+		openedAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		status := CircuitBreakerStatus{Open: true, Failures: 3, Trips: 1, OpenedAt: &openedAt, LastError: "b00m!", LastFailure: &openedAt}
+
+		assert.Equal(t, "open since 2020-01-02T03:04:05Z, 3 consecutive failures, 1 trips, last failure at 2020-01-02T03:04:05Z: b00m!", status.String())
+		assert.Equal(t, "closed, 0 consecutive failures, 0 trips", CircuitBreakerStatus{}.String())
+	})
+}
+
+func TestEventOctopus_configureCircuitBreaker(t *testing.T) {
+	t.Run("negative threshold", func(t *testing.T) {
+		i := testEventOctopus()
+		i.Config.CircuitBreakerThreshold = -1
+
+		assert.Error(t, i.configureCircuitBreaker())
+	})
+
+	t.Run("negative timeout", func(t *testing.T) {
+		i := testEventOctopus()
+		i.Config.CircuitBreakerTimeout = -1
+
+		assert.Error(t, i.configureCircuitBreaker())
+	})
+}
+
+func TestEventOctopus_MessageFailures(t *testing.T) {
+	ackWait := storeAckWait
+	storeAckWait = time.Second
+	defer func() { storeAckWait = ackWait }()
+
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:message_failures?mode=memory&cache=shared"
+	i.Config.CircuitBreakerThreshold = 1
+	i.Config.CircuitBreakerTimeout = 1
+	if err := i.configure(); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	publisher, _ := i.EventPublisher("message-failures")
+
+	// waitFor polls the condition for at most 5 seconds, enough for a redelivery after the ack wait and breaker timeout
+	waitFor := func(condition func() bool) bool {
+		for j := 0; j < 100; j++ {
+			if condition() {
+				return true
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		return false
+	}
+
+	t.Run("a failing DB leaves the event for redelivery", func(t *testing.T) {
+		before := testutil.ToFloat64(messageFailures.WithLabelValues(ChannelConsentRequest))
+		if err := i.Db.Exec("ALTER TABLE events RENAME TO events_unavailable").Error; err != nil {
+			t.Fatal(err)
+		}
+
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = publisher.Publish(ChannelConsentRequest, e)
+
+		assert.True(t, waitFor(func() bool {
+			return testutil.ToFloat64(messageFailures.WithLabelValues(ChannelConsentRequest)) > before
+		}), "failure not counted")
+
+		// the node is still up and reports the failure
+		status := i.DiagnosticsReport().CircuitBreaker
+		assert.True(t, status.Open)
+		assert.Contains(t, status.LastError, e.UUID)
+		assert.Equal(t, float64(1), testutil.ToFloat64(circuitBreakerOpen))
+
+		if err := i.Db.Exec("ALTER TABLE events_unavailable RENAME TO events").Error; err != nil {
+			t.Fatal(err)
+		}
+
+		assert.True(t, waitFor(func() bool {
+			stored, _ := i.GetEvent(e.UUID)
+			return stored != nil
+		}), "event not stored after redelivery")
+		assert.False(t, i.CircuitBreakerStatus().Open)
+		assert.Equal(t, float64(0), testutil.ToFloat64(circuitBreakerOpen))
+	})
+
+	t.Run("a failing publication leaves the retry message for redelivery", func(t *testing.T) {
+		before := testutil.ToFloat64(messageFailures.WithLabelValues(ChannelConsentRetry))
+		retried := make(chan *natsClient.Msg, 10)
+		sc, _ := i.client(ClientID)
+		sub, err := sc.Subscribe(ChannelConsentRetry+"-0", func(msg *natsClient.Msg) {
+			retried <- msg
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer sub.Unsubscribe()
+
+		// the event store publishes retries with a closed connection
+		closed, err := i.Config.connect("message-failures-closed")
+		if !assert.NoError(t, err) {
+			return
+		}
+		_ = closed.Close()
+		i.stanClients[ClientID] = closed
+
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = publisher.Publish(ChannelConsentRetry, e)
+
+		assert.True(t, waitFor(func() bool {
+			return testutil.ToFloat64(messageFailures.WithLabelValues(ChannelConsentRetry)) > before
+		}), "failure not counted")
+		assert.True(t, i.CircuitBreakerStatus().Open)
+
+		i.stanClients[ClientID] = sc
+
+		select {
+		case <-retried:
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "event not retried after redelivery")
+		}
+	})
+}
@@ -0,0 +1,243 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	natsClient "github.com/nats-io/stan.go"
+	"github.com/sirupsen/logrus"
+)
+
+// Checkpoint is the sequence number of the last event on a subject processed by a service
+type Checkpoint struct {
+	Service  string `gorm:"PRIMARY_KEY" json:"service"`
+	Subject  string `gorm:"PRIMARY_KEY" json:"subject"`
+	Sequence uint64 `gorm:"not null" json:"sequence"`
+	// LogID identifies the event log the sequence belongs to, sequences of a new log start at 1 again
+	LogID     string    `gorm:"not null" json:"logId"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TableName returns the name of the subscriber checkpoint table
+func (Checkpoint) TableName() string {
+	return "subscriber_checkpoints"
+}
+
+// Checkpoints returns the checkpoints of all services
+func (octopus *EventOctopus) Checkpoints() ([]Checkpoint, error) {
+	var checkpoints []Checkpoint
+
+	err := octopus.Db.Debug().Order("service").Order("subject").Find(&checkpoints).Error
+
+	return checkpoints, err
+}
+
+// GetCheckpoint returns the checkpoint of the service for the subject or nil when the service did not process any event yet
+func (octopus *EventOctopus) GetCheckpoint(service, subject string) (*Checkpoint, error) {
+	checkpoint := &Checkpoint{}
+
+	err := octopus.Db.Debug().Where("service = ? AND subject = ?", service, subject).First(checkpoint).Error
+
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+// ResetCheckpoint removes the checkpoint, the next subscription of the service only receives the events published after subscribing.
+// It returns false when the checkpoint did not exist.
+func (octopus *EventOctopus) ResetCheckpoint(service, subject string) (bool, error) {
+	db := octopus.Db.Debug().Where("service = ? AND subject = ?", service, subject).Delete(Checkpoint{})
+
+	return db.RowsAffected > 0, db.Error
+}
+
+// logID identifies the event log. The embedded in-memory store starts a new log on every start,
+// the other stores keep their log as long as the cluster ID stays the same.
+func (octopus *EventOctopus) logID() string {
+	if octopus.memoryLogID != "" {
+		return octopus.memoryLogID
+	}
+	return octopus.Config.clusterID()
+}
+
+// inLog returns true when the checkpoint belongs to the current event log.
+// Checkpoints stored before logs were identified belong to it, unless the log is kept in memory.
+func (octopus *EventOctopus) inLog(checkpoint *Checkpoint) bool {
+	if checkpoint.LogID == "" {
+		return octopus.memoryLogID == ""
+	}
+	return checkpoint.LogID == octopus.logID()
+}
+
+// saveCheckpoint stores the sequence as the last one processed by the service, a checkpoint never moves backwards
+// within an event log. A checkpoint of a previous event log is replaced.
+func (octopus *EventOctopus) saveCheckpoint(service, subject string, sequence uint64) error {
+	// subscriptions can be made without an event store
+	if octopus.Db == nil {
+		return nil
+	}
+
+	logID := octopus.logID()
+
+	db := octopus.Db.Debug().Model(&Checkpoint{}).
+		Where("service = ? AND subject = ? AND (sequence < ? OR log_id <> ?)", service, subject, sequence, logID).
+		UpdateColumns(map[string]interface{}{"sequence": sequence, "log_id": logID, "updated_at": now()})
+	if db.Error != nil || db.RowsAffected > 0 {
+		return db.Error
+	}
+
+	// no checkpoint yet, or it is at or after the sequence already
+	checkpoint := Checkpoint{
+		Service:   service,
+		Subject:   subject,
+		Sequence:  sequence,
+		LogID:     logID,
+		UpdatedAt: now(),
+	}
+
+	return octopus.Db.Debug().Where("service = ? AND subject = ?", service, subject).FirstOrCreate(&checkpoint).Error
+}
+
+// checkpointTracker advances the checkpoint of a subscription only over contiguous handled messages.
+// Nats delivers the next message while a failed one awaits redelivery, the checkpoint stays before the failed message.
+type checkpointTracker struct {
+	mutex sync.Mutex
+	// pending are the delivered messages that are not handled yet
+	pending map[uint64]bool
+	// handled are the handled messages after a pending message
+	handled []uint64
+}
+
+func newCheckpointTracker() *checkpointTracker {
+	return &checkpointTracker{pending: map[uint64]bool{}}
+}
+
+// deliver registers a message that is going to be handled
+func (t *checkpointTracker) deliver(sequence uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.pending[sequence] = true
+}
+
+// done marks the message as handled. It returns the sequence the checkpoint can advance to,
+// or 0 when all handled messages come after a pending message.
+func (t *checkpointTracker) done(sequence uint64) uint64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.pending, sequence)
+	t.handled = append(t.handled, sequence)
+
+	var lowestPending uint64
+	for p := range t.pending {
+		if lowestPending == 0 || p < lowestPending {
+			lowestPending = p
+		}
+	}
+
+	var checkpoint uint64
+	var remaining []uint64
+	for _, h := range t.handled {
+		if lowestPending != 0 && h > lowestPending {
+			remaining = append(remaining, h)
+		} else if h > checkpoint {
+			checkpoint = h
+		}
+	}
+	t.handled = remaining
+
+	return checkpoint
+}
+
+// checkpointStart returns the option to start after the checkpoint of the service or nil when there is no checkpoint.
+// The service did not process any event of a new event log, all available events are delivered instead of starting
+// after a sequence that may lie beyond the end of the new log.
+func (octopus *EventOctopus) checkpointStart(service, subject string) (natsClient.SubscriptionOption, error) {
+	if octopus.Db == nil {
+		return nil, nil
+	}
+
+	checkpoint, err := octopus.GetCheckpoint(service, subject)
+	if err != nil || checkpoint == nil {
+		return nil, err
+	}
+
+	if !octopus.inLog(checkpoint) {
+		logrus.Warnf("Checkpoint %d of %s.%s belongs to a previous event log, delivering all available events", checkpoint.Sequence, service, subject)
+		return natsClient.DeliverAllAvailable(), nil
+	}
+
+	return natsClient.StartAtSequence(checkpoint.Sequence + 1), nil
+}
+
+// processedBefore returns true for a redelivered message at or before the checkpoint of the service.
+// Only redeliveries of the event log of the checkpoint are checked, the sequence numbers of a new event log start at 1 again.
+// Members of a queue group don't know the pending messages of the other members, so it is not used for queue groups.
+func (octopus *EventOctopus) processedBefore(service string, msg *natsClient.Msg) (bool, error) {
+	if !msg.Redelivered || octopus.Db == nil {
+		return false, nil
+	}
+
+	checkpoint, err := octopus.GetCheckpoint(service, msg.Subject)
+	if err != nil || checkpoint == nil || !octopus.inLog(checkpoint) {
+		return false, err
+	}
+
+	return msg.Sequence <= checkpoint.Sequence, nil
+}
+
+type checkpointDiagnosticResult struct {
+	checkpoints []Checkpoint
+	err         error
+}
+
+// Name returns the name of the checkpointDiagnosticResult
+func (cdr checkpointDiagnosticResult) Name() string {
+	return "Subscriber checkpoints"
+}
+
+// String returns the last processed sequence per service and subject
+func (cdr checkpointDiagnosticResult) String() string {
+	if cdr.err != nil {
+		return fmt.Sprintf("error: %v", cdr.err)
+	}
+
+	if len(cdr.checkpoints) == 0 {
+		return "NONE"
+	}
+
+	var checkpoints []string
+	for _, c := range cdr.checkpoints {
+		checkpoints = append(checkpoints, fmt.Sprintf("%s.%s: %d", c.Service, c.Subject, c.Sequence))
+	}
+
+	return strings.Join(checkpoints, ", ")
+}
@@ -0,0 +1,312 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	natsClient "github.com/nats-io/stan.go"
+	"github.com/nats-io/stan.go/pb"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventOctopus_Checkpoints(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:checkpoints?mode=memory&cache=shared"
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	publisher, _ := i.EventPublisher("event-octopus-test")
+
+	publish := func(subject string) Event {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = publisher.Publish(subject, e)
+		return e
+	}
+
+	receive := func(received chan string) map[string]EventHandlerCallback {
+		return map[string]EventHandlerCallback{
+			event().Name: func(event *Event) {
+				received <- event.UUID
+			},
+		}
+	}
+
+	expect := func(t *testing.T, received chan string, e Event) {
+		select {
+		case u := <-received:
+			assert.Equal(t, e.UUID, u)
+		case <-time.After(time.Second):
+			assert.Fail(t, "event not delivered")
+		}
+	}
+
+	// waitForCheckpoint waits until the checkpoint reaches the sequence, the checkpoint is stored after the handler returns
+	waitForCheckpoint := func(service, subject string, sequence uint64) *Checkpoint {
+		var checkpoint *Checkpoint
+		for j := 0; j < 100; j++ {
+			checkpoint, _ = i.GetCheckpoint(service, subject)
+			if checkpoint != nil && checkpoint.Sequence >= sequence {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return checkpoint
+	}
+
+	t.Run("checkpoint is stored after each event", func(t *testing.T) {
+		received := make(chan string, 10)
+		_ = i.Subscribe("checkpoint-service", "checkpoint", receive(received), ResumeFromCheckpoint())
+		defer i.Unsubscribe("checkpoint-service", "checkpoint")
+
+		expect(t, received, publish("checkpoint"))
+		expect(t, received, publish("checkpoint"))
+
+		checkpoint := waitForCheckpoint("checkpoint-service", "checkpoint", 2)
+		if assert.NotNil(t, checkpoint) {
+			assert.Equal(t, uint64(2), checkpoint.Sequence)
+		}
+	})
+
+	t.Run("subscription resumes after the checkpoint", func(t *testing.T) {
+		received := make(chan string, 10)
+		_ = i.Subscribe("resume-service", "resume", receive(received), ResumeFromCheckpoint())
+
+		expect(t, received, publish("resume"))
+		waitForCheckpoint("resume-service", "resume", 1)
+		_ = i.Unsubscribe("resume-service", "resume")
+
+		missed := publish("resume")
+
+		_ = i.Subscribe("resume-service", "resume", receive(received), ResumeFromCheckpoint())
+		defer i.Unsubscribe("resume-service", "resume")
+
+		expect(t, received, missed)
+	})
+
+	t.Run("start option takes precedence over the checkpoint", func(t *testing.T) {
+		received := make(chan string, 10)
+		_ = i.Subscribe("option-service", "option", receive(received), ResumeFromCheckpoint())
+
+		first := publish("option")
+		expect(t, received, first)
+		waitForCheckpoint("option-service", "option", 1)
+		_ = i.Unsubscribe("option-service", "option")
+
+		_ = i.Subscribe("option-service", "option", receive(received), ResumeFromCheckpoint(), StartAtSequence(1))
+		defer i.Unsubscribe("option-service", "option")
+
+		expect(t, received, first)
+	})
+
+	t.Run("reset checkpoint", func(t *testing.T) {
+		received := make(chan string, 10)
+		_ = i.Subscribe("reset-service", "reset", receive(received), ResumeFromCheckpoint())
+
+		expect(t, received, publish("reset"))
+		waitForCheckpoint("reset-service", "reset", 1)
+		_ = i.Unsubscribe("reset-service", "reset")
+
+		publish("reset")
+
+		reset, err := i.ResetCheckpoint("reset-service", "reset")
+		if assert.NoError(t, err) {
+			assert.True(t, reset)
+		}
+		reset, _ = i.ResetCheckpoint("reset-service", "reset")
+		assert.False(t, reset)
+
+		// without checkpoint only new events are received
+		_ = i.Subscribe("reset-service", "reset", receive(received), ResumeFromCheckpoint())
+		defer i.Unsubscribe("reset-service", "reset")
+
+		expect(t, received, publish("reset"))
+	})
+
+	t.Run("legacy subscription acks automatically and only receives new events", func(t *testing.T) {
+		received := make(chan string, 10)
+		_ = i.Subscribe("legacy-service", "legacy", receive(received))
+
+		expect(t, received, publish("legacy"))
+		assert.False(t, i.channelHandlers["legacy-service"]["legacy"].options.manualAck)
+		_ = i.Unsubscribe("legacy-service", "legacy")
+
+		publish("legacy")
+
+		_ = i.Subscribe("legacy-service", "legacy", receive(received))
+		defer i.Unsubscribe("legacy-service", "legacy")
+
+		expect(t, received, publish("legacy"))
+		checkpoint, _ := i.GetCheckpoint("legacy-service", "legacy")
+		assert.Nil(t, checkpoint)
+	})
+
+	t.Run("all checkpoints are returned", func(t *testing.T) {
+		checkpoints, err := i.Checkpoints()
+
+		if assert.NoError(t, err) {
+			assert.True(t, len(checkpoints) >= 3)
+		}
+	})
+
+	t.Run("redelivered message up to the checkpoint has been processed before", func(t *testing.T) {
+		_ = i.saveCheckpoint("redelivery-service", "redelivery", 5)
+
+		msg := func(sequence uint64, redelivered bool) *natsClient.Msg {
+			return &natsClient.Msg{MsgProto: pb.MsgProto{Subject: "redelivery", Sequence: sequence, Redelivered: redelivered}}
+		}
+
+		processed, _ := i.processedBefore("redelivery-service", msg(5, true))
+		assert.True(t, processed)
+		processed, _ = i.processedBefore("redelivery-service", msg(6, true))
+		assert.False(t, processed)
+		processed, _ = i.processedBefore("redelivery-service", msg(5, false))
+		assert.False(t, processed)
+		processed, _ = i.processedBefore("other-service", msg(5, true))
+		assert.False(t, processed)
+	})
+
+	t.Run("checkpoint never moves backwards", func(t *testing.T) {
+		_ = i.saveCheckpoint("monotonic-service", "monotonic", 5)
+		_ = i.saveCheckpoint("monotonic-service", "monotonic", 3)
+
+		checkpoint, _ := i.GetCheckpoint("monotonic-service", "monotonic")
+		if assert.NotNil(t, checkpoint) {
+			assert.Equal(t, uint64(5), checkpoint.Sequence)
+		}
+
+		_ = i.saveCheckpoint("monotonic-service", "monotonic", 7)
+
+		checkpoint, _ = i.GetCheckpoint("monotonic-service", "monotonic")
+		if assert.NotNil(t, checkpoint) {
+			assert.Equal(t, uint64(7), checkpoint.Sequence)
+		}
+	})
+}
+
+func TestEventOctopus_CheckpointsAfterRestart(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "checkpoints")
+	defer os.RemoveAll(dir)
+
+	start := func() *EventOctopus {
+		i := testEventOctopus()
+		i.Config.Connectionstring = fmt.Sprintf("file:%s/events.db", dir)
+		i.configure()
+		if err := i.Start(); err != nil {
+			t.Fatal(err)
+		}
+		return i
+	}
+
+	received := make(chan string, 10)
+	handlers := map[string]EventHandlerCallback{
+		event().Name: func(event *Event) {
+			received <- event.UUID
+		},
+	}
+
+	publish := func(i *EventOctopus) Event {
+		publisher, _ := i.EventPublisher("event-octopus-test")
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = publisher.Publish("restart", e)
+		return e
+	}
+
+	expect := func(e Event) {
+		select {
+		case u := <-received:
+			assert.Equal(t, e.UUID, u)
+		case <-time.After(time.Second):
+			assert.Fail(t, "event not delivered")
+		}
+	}
+
+	waitForCheckpoint := func(i *EventOctopus, sequence uint64) *Checkpoint {
+		var checkpoint *Checkpoint
+		for j := 0; j < 100; j++ {
+			checkpoint, _ = i.GetCheckpoint("restart-service", "restart")
+			if checkpoint != nil && checkpoint.Sequence == sequence && checkpoint.LogID == i.logID() {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return checkpoint
+	}
+
+	// the in-memory store loses the event log at shutdown
+	first := start()
+	_ = first.Subscribe("restart-service", "restart", handlers, ResumeFromCheckpoint())
+	expect(publish(first))
+	expect(publish(first))
+	expect(publish(first))
+	waitForCheckpoint(first, 3)
+	_ = first.Shutdown()
+
+	second := start()
+	defer second.Shutdown()
+
+	missed := publish(second)
+	_ = second.Subscribe("restart-service", "restart", handlers, ResumeFromCheckpoint())
+
+	expect(missed)
+
+	checkpoint := waitForCheckpoint(second, 1)
+	if assert.NotNil(t, checkpoint) {
+		assert.Equal(t, uint64(1), checkpoint.Sequence)
+		assert.Equal(t, second.logID(), checkpoint.LogID)
+		assert.NotEqual(t, first.logID(), checkpoint.LogID)
+	}
+}
+
+func TestCheckpointTracker(t *testing.T) {
+	t.Run("advances over handled messages", func(t *testing.T) {
+		tracker := newCheckpointTracker()
+		tracker.deliver(1)
+		assert.Equal(t, uint64(1), tracker.done(1))
+		tracker.deliver(2)
+		assert.Equal(t, uint64(2), tracker.done(2))
+	})
+
+	t.Run("stays before a message awaiting redelivery", func(t *testing.T) {
+		tracker := newCheckpointTracker()
+		tracker.deliver(1)
+		assert.Equal(t, uint64(1), tracker.done(1))
+
+		// 2 failed
+		tracker.deliver(2)
+		tracker.deliver(3)
+		assert.Equal(t, uint64(0), tracker.done(3))
+		tracker.deliver(4)
+		assert.Equal(t, uint64(0), tracker.done(4))
+
+		// redelivery of 2 succeeds
+		tracker.deliver(2)
+		assert.Equal(t, uint64(4), tracker.done(2))
+	})
+}
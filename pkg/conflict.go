@@ -0,0 +1,46 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrConflict is returned when an event is saved while it has been changed by another writer
+var ErrConflict = errors.New("conflicting update")
+
+// maxConflictRetries is the number of attempts to store an event without an expected version when it keeps changing
+const maxConflictRetries = 5
+
+// ConflictError is returned when an event is saved with a version that is not the current version of the stored event
+type ConflictError struct {
+	UUID string
+	// Version is the version the event was expected to have, 0 for an event that did not exist yet
+	Version int
+}
+
+func (e ConflictError) Error() string {
+	return fmt.Sprintf("%v: event %s has been changed since version %d", ErrConflict, e.UUID, e.Version)
+}
+
+// Unwrap allows errors.Is(err, ErrConflict)
+func (e ConflictError) Unwrap() error {
+	return ErrConflict
+}
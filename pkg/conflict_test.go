@@ -0,0 +1,209 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventOctopus_SaveOrUpdateEvent_versions(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:versions?mode=memory&cache=shared"
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	t.Run("version is incremented on every save", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = i.SaveOrUpdateEvent(e)
+
+		stored, _ := i.GetEvent(e.UUID)
+		assert.Equal(t, 1, stored.Version)
+
+		stored.Name = EventConsentRequestInFlight
+		assert.NoError(t, i.SaveOrUpdateEvent(*stored))
+
+		stored, _ = i.GetEvent(e.UUID)
+		assert.Equal(t, 2, stored.Version)
+		assert.Equal(t, EventConsentRequestInFlight, stored.Name)
+	})
+
+	t.Run("stale version gives a conflict", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = i.SaveOrUpdateEvent(e)
+
+		first, _ := i.GetEvent(e.UUID)
+		second, _ := i.GetEvent(e.UUID)
+
+		first.Name = EventConsentRequestInFlight
+		assert.NoError(t, i.SaveOrUpdateEvent(*first))
+
+		second.Name = EventConsentRequestFlowErrored
+		err := i.SaveOrUpdateEvent(*second)
+
+		assert.True(t, errors.Is(err, ErrConflict))
+		var conflict ConflictError
+		if assert.True(t, errors.As(err, &conflict)) {
+			assert.Equal(t, e.UUID, conflict.UUID)
+			assert.Equal(t, 1, conflict.Version)
+		}
+
+		stored, _ := i.GetEvent(e.UUID)
+		assert.Equal(t, EventConsentRequestInFlight, stored.Name)
+		history, _ := i.GetEventHistory(e.UUID)
+		assert.Len(t, history, 2)
+	})
+
+	t.Run("version of an unknown event gives a conflict", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Version = 1
+
+		err := i.SaveOrUpdateEvent(e)
+
+		assert.True(t, errors.Is(err, ErrConflict))
+		stored, _ := i.GetEvent(e.UUID)
+		assert.Nil(t, stored)
+	})
+
+	t.Run("event without version overwrites the stored event", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = i.SaveOrUpdateEvent(e)
+		_ = i.SaveOrUpdateEvent(e)
+
+		e.Name = EventConsentRequestInFlight
+		assert.NoError(t, i.SaveOrUpdateEvent(e))
+
+		stored, _ := i.GetEvent(e.UUID)
+		assert.Equal(t, 3, stored.Version)
+	})
+}
+
+func TestEventOctopus_SaveOrUpdateEvent_concurrency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "concurrency")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the writers of an in-memory SQLite DB are serialised, a SQLite file and PostgreSQL take them in parallel
+	connectionStrings := []string{"file:" + filepath.Join(dir, "events.db")}
+	if cs := os.Getenv(postgresTestConnectionString); cs != "" {
+		connectionStrings = append(connectionStrings, cs)
+	}
+
+	for _, cs := range connectionStrings {
+		i := testEventOctopus()
+		i.Config.Connectionstring = cs
+		if err := i.configure(); err != nil {
+			t.Fatal(err)
+		}
+		if err := i.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Run(i.dialect, func(t *testing.T) {
+			t.Run("concurrent writers with the same version", func(t *testing.T) {
+				e := event()
+				e.UUID = uuid.NewV4().String()
+				_ = i.SaveOrUpdateEvent(e)
+				stored, _ := i.GetEvent(e.UUID)
+
+				// the writers start while another transaction is in progress
+				tx := i.Db.Begin()
+
+				writers := 10
+				errs := make(chan error, writers)
+				wg := sync.WaitGroup{}
+				for j := 0; j < writers; j++ {
+					wg.Add(1)
+					go func(update Event) {
+						defer wg.Done()
+						update.RetryCount++
+						errs <- i.SaveOrUpdateEvent(update)
+					}(*stored)
+				}
+				time.Sleep(50 * time.Millisecond)
+				assert.NoError(t, tx.Commit().Error)
+				wg.Wait()
+				close(errs)
+
+				// the writers used connections of their own
+				assert.True(t, i.sqlDb.Stats().OpenConnections > 1)
+
+				saved := 0
+				for err := range errs {
+					if err == nil {
+						saved++
+					} else {
+						assert.True(t, errors.Is(err, ErrConflict), err.Error())
+					}
+				}
+				assert.Equal(t, 1, saved)
+
+				stored, _ = i.GetEvent(e.UUID)
+				assert.Equal(t, 2, stored.Version)
+			})
+
+			t.Run("concurrent writers without version are all stored", func(t *testing.T) {
+				if i.dialect == DialectPostgres {
+					t.Skip("writers without version can conflict more than the retries on PostgreSQL, only SQLite takes the write lock before reading")
+				}
+				e := event()
+				e.UUID = uuid.NewV4().String()
+
+				writers := 10
+				wg := sync.WaitGroup{}
+				for j := 0; j < writers; j++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						assert.NoError(t, i.SaveOrUpdateEvent(e))
+					}()
+				}
+				wg.Wait()
+
+				stored, _ := i.GetEvent(e.UUID)
+				if assert.NotNil(t, stored) {
+					assert.Equal(t, writers, stored.Version)
+				}
+				history, _ := i.GetEventHistory(e.UUID)
+				assert.Len(t, history, writers)
+			})
+
+		})
+
+		emptyTable(i)
+		i.Shutdown()
+	}
+}
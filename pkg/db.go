@@ -0,0 +1,127 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+)
+
+// DialectSqlite is the dialect for the (default) SQLite event store
+const DialectSqlite = "sqlite3"
+
+// DialectPostgres is the dialect for a PostgreSQL event store
+const DialectPostgres = "postgres"
+
+// SortCreatedAt sorts events on the moment they were first stored
+const SortCreatedAt = "createdAt"
+
+// SortUpdatedAt sorts events on the moment they were last stored
+const SortUpdatedAt = "updatedAt"
+
+// SortTransitionedAt sorts events on the moment their name last changed
+const SortTransitionedAt = "transitionedAt"
+
+// ErrUnknownSortField is returned when events are listed with an unsupported sort field
+var ErrUnknownSortField = errors.New("unknown sort field")
+
+// sortColumns maps the sort fields to their db columns
+var sortColumns = map[string]string{
+	SortCreatedAt:      "created_at",
+	SortUpdatedAt:      "updated_at",
+	SortTransitionedAt: "transitioned_at",
+}
+
+// dialect returns the configured dialect or derives it from the connection string
+func (c EventOctopusConfig) dialect() (string, error) {
+	switch c.DbDialect {
+	case "":
+		if strings.HasPrefix(c.Connectionstring, "postgres://") || strings.HasPrefix(c.Connectionstring, "postgresql://") {
+			return DialectPostgres, nil
+		}
+		return DialectSqlite, nil
+	case DialectSqlite, DialectPostgres:
+		return c.DbDialect, nil
+	default:
+		return "", fmt.Errorf("unsupported %s: %s", ConfigDbDialect, c.DbDialect)
+	}
+}
+
+// sqliteInMemory returns true for the connection string of an in-memory SQLite DB
+func sqliteInMemory(connectionString string) bool {
+	return strings.Contains(connectionString, ":memory:") || strings.Contains(connectionString, "mode=memory")
+}
+
+// sqliteConnectionString adds the options for concurrent writers to the connection string of a SQLite file. Readers don't block
+// the writer in WAL mode and a transaction takes the write lock when it begins, waiting for other writers up to the busy timeout
+// instead of failing when it writes after reading. Options in the connection string are kept.
+func sqliteConnectionString(connectionString string) string {
+	query := ""
+	if i := strings.IndexRune(connectionString, '?'); i >= 0 {
+		query = connectionString[i+1:]
+	}
+	params, _ := url.ParseQuery(query)
+
+	var options []string
+	add := func(value string, names ...string) {
+		for _, n := range names {
+			if params.Get(n) != "" {
+				return
+			}
+		}
+		options = append(options, names[0]+"="+value)
+	}
+	add("WAL", "_journal_mode", "_journal")
+	add("immediate", "_txlock")
+	add("5000", "_busy_timeout", "_timeout")
+
+	if len(options) == 0 {
+		return connectionString
+	}
+	separator := "?"
+	if strings.ContainsRune(connectionString, '?') {
+		separator = "&"
+	}
+	return connectionString + separator + strings.Join(options, "&")
+}
+
+// migrationDriver returns the golang-migrate driver for the given dialect
+func migrationDriver(dialect string, db *sql.DB) (database.Driver, error) {
+	switch dialect {
+	case DialectPostgres:
+		return postgres.WithInstance(db, &postgres.Config{})
+	case DialectSqlite:
+		return sqlite3.WithInstance(db, &sqlite3.Config{})
+	default:
+		return nil, fmt.Errorf("no migrations for dialect: %s", dialect)
+	}
+}
+
+// now returns the current time in UTC, used for all timestamps in the event store
+func now() time.Time {
+	return time.Now().UTC()
+}
@@ -0,0 +1,246 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"database/sql"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuts-foundation/nuts-event-octopus/migrations"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// postgresTestConnectionString points to a PostgreSQL database used for testing the postgres dialect
+const postgresTestConnectionString = "NUTS_EVENTS_TEST_POSTGRES"
+
+func TestEventOctopusConfig_dialect(t *testing.T) {
+	t.Run("defaults to sqlite", func(t *testing.T) {
+		d, err := EventOctopusConfig{Connectionstring: ConfigConnectionStringDefault}.dialect()
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, DialectSqlite, d)
+		}
+	})
+
+	t.Run("derives postgres from connection string", func(t *testing.T) {
+		for _, cs := range []string{"postgres://localhost/events", "postgresql://localhost/events"} {
+			d, err := EventOctopusConfig{Connectionstring: cs}.dialect()
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, DialectPostgres, d)
+			}
+		}
+	})
+
+	t.Run("configured dialect takes precedence", func(t *testing.T) {
+		d, err := EventOctopusConfig{Connectionstring: "host=localhost dbname=events", DbDialect: DialectPostgres}.dialect()
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, DialectPostgres, d)
+		}
+	})
+
+	t.Run("unknown dialect gives error", func(t *testing.T) {
+		_, err := EventOctopusConfig{DbDialect: "mysql"}.dialect()
+
+		assert.Error(t, err)
+	})
+}
+
+func TestMigrations(t *testing.T) {
+	t.Run("all dialects have the same migrations", func(t *testing.T) {
+		sqlite, err := migrations.AssetDir(DialectSqlite)
+		if !assert.NoError(t, err) {
+			return
+		}
+		postgres, err := migrations.AssetDir(DialectPostgres)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		sort.Strings(sqlite)
+		sort.Strings(postgres)
+		assert.Equal(t, sqlite, postgres)
+	})
+
+	t.Run("all dialects have the same column sizes", func(t *testing.T) {
+		names, _ := migrations.AssetDir(DialectSqlite)
+		sizes := regexp.MustCompile(`\w+ (VAR)?CHAR\(\d+\)`)
+
+		for _, name := range names {
+			// SQLite down migrations recreate tables, postgres drops columns
+			if !strings.HasSuffix(name, ".up.sql") {
+				continue
+			}
+			sqlite, _ := migrations.Asset(DialectSqlite + "/" + name)
+			postgres, err := migrations.Asset(DialectPostgres + "/" + name)
+			if assert.NoError(t, err, name) {
+				assert.Equal(t, sizes.FindAllString(string(sqlite), -1), sizes.FindAllString(string(postgres), -1), name)
+			}
+		}
+	})
+}
+
+func TestMigrations_timestampBackfill(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:backfill?mode=memory&cache=shared"
+	db, err := sql.Open(DialectSqlite, i.Config.Connectionstring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m, err := i.migrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// state before timestamps were added
+	if err := m.Migrate(2); err != nil {
+		t.Fatal(err)
+	}
+
+	statements := []string{
+		"INSERT INTO events (uuid, name, retry_count, initiator_legal_entity, external_id, payload) VALUES ('a', 'consentRequest in flight', 1, 'urn', 'a', 'p')",
+		"INSERT INTO events (uuid, name, retry_count, initiator_legal_entity, external_id, payload) VALUES ('b', 'completed', 0, 'urn', 'b', 'p')",
+		"INSERT INTO event_history (event_uuid, name, retry_count, created_at) VALUES ('a', 'consentRequest constructed', 0, '2020-01-01 10:00:00')",
+		"INSERT INTO event_history (event_uuid, name, retry_count, created_at) VALUES ('a', 'consentRequest in flight', 0, '2020-01-01 11:00:00')",
+		"INSERT INTO event_history (event_uuid, name, retry_count, created_at) VALUES ('a', 'consentRequest in flight', 1, '2020-01-01 12:00:00')",
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	timestamps := func(uuid string) (created, updated, transitioned time.Time) {
+		row := db.QueryRow("SELECT created_at, updated_at, transitioned_at FROM events WHERE uuid = ?", uuid)
+		if err := row.Scan(&created, &updated, &transitioned); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	t.Run("timestamps are derived from the history", func(t *testing.T) {
+		created, updated, transitioned := timestamps("a")
+
+		assert.Equal(t, time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC), created)
+		assert.Equal(t, time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC), updated)
+		assert.Equal(t, time.Date(2020, 1, 1, 11, 0, 0, 0, time.UTC), transitioned)
+	})
+
+	t.Run("timestamps are set for events without history", func(t *testing.T) {
+		created, updated, transitioned := timestamps("b")
+
+		assert.False(t, created.IsZero())
+		assert.False(t, updated.IsZero())
+		assert.False(t, transitioned.IsZero())
+	})
+}
+
+func TestEventOctopus_Postgres(t *testing.T) {
+	cs := os.Getenv(postgresTestConnectionString)
+	if cs == "" {
+		t.Skipf("%s not set", postgresTestConnectionString)
+	}
+
+	i := testEventOctopus()
+	i.Config.Connectionstring = cs
+	i.Config.DbDialect = DialectPostgres
+	if err := i.configure(); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+	defer emptyTable(i)
+
+	e := event()
+	e.UUID = uuid.NewV4().String()
+	e.ExternalID = uuid.NewV4().String()
+
+	t.Run("event is saved and updated", func(t *testing.T) {
+		if !assert.NoError(t, i.SaveOrUpdateEvent(e)) {
+			return
+		}
+		e.Name = EventInFinalFlight
+		if !assert.NoError(t, i.SaveOrUpdateEvent(e)) {
+			return
+		}
+
+		stored, err := i.GetEvent(e.UUID)
+		if assert.NoError(t, err) && assert.NotNil(t, stored) {
+			assert.Equal(t, EventInFinalFlight, stored.Name)
+		}
+	})
+
+	t.Run("event is found by external ID", func(t *testing.T) {
+		stored, err := i.GetEventByExternalID(e.ExternalID)
+		if assert.NoError(t, err) && assert.NotNil(t, stored) {
+			assert.Equal(t, e.UUID, stored.UUID)
+		}
+	})
+
+	t.Run("all migrations are applied", func(t *testing.T) {
+		names, _ := migrations.AssetDir(DialectPostgres)
+		var version int
+		var dirty bool
+
+		err := i.Db.DB().QueryRow("SELECT version, dirty FROM schema_migrations").Scan(&version, &dirty)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, len(names)/2, version)
+			assert.False(t, dirty)
+		}
+	})
+
+	t.Run("event is listed", func(t *testing.T) {
+		events, err := i.List()
+		if assert.NoError(t, err) {
+			assert.Len(t, *events, 1)
+		}
+	})
+}
+
+func TestSqliteConnectionString(t *testing.T) {
+	t.Run("adds options for concurrent writers", func(t *testing.T) {
+		assert.Equal(t, "events.db?_journal_mode=WAL&_txlock=immediate&_busy_timeout=5000", sqliteConnectionString("events.db"))
+		assert.Equal(t, "file:events.db?cache=shared&_journal_mode=WAL&_txlock=immediate&_busy_timeout=5000", sqliteConnectionString("file:events.db?cache=shared"))
+	})
+
+	t.Run("keeps configured options", func(t *testing.T) {
+		assert.Equal(t, "events.db?_journal=DELETE&_timeout=100&_txlock=immediate", sqliteConnectionString("events.db?_journal=DELETE&_timeout=100"))
+	})
+
+	t.Run("in-memory DBs are recognized", func(t *testing.T) {
+		assert.True(t, sqliteInMemory(ConfigConnectionStringDefault))
+		assert.True(t, sqliteInMemory("file:events?mode=memory&cache=shared"))
+		assert.False(t, sqliteInMemory("file:events.db"))
+	})
+}
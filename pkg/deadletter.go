@@ -0,0 +1,227 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	natsClient "github.com/nats-io/stan.go"
+	uuid "github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ChannelDeadLetter receives the messages that could not be processed, they are stored for inspection
+const ChannelDeadLetter = "consentRequestDeadLetter"
+
+// DeadLetter is a message that could not be processed, together with where it came from and why it failed
+type DeadLetter struct {
+	ID string `gorm:"PRIMARY_KEY" json:"id"`
+	// Subject is the channel the message was received on
+	Subject string `gorm:"not null" json:"subject"`
+	// Sequence is the Nats sequence number of the message on its subject
+	Sequence  uint64    `gorm:"not null" json:"sequence"`
+	Reason    string    `gorm:"not null" json:"reason"`
+	Data      []byte    `json:"data"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Encrypted is set when Data is already encrypted for a row with the ID of the dead letter
+	Encrypted bool `gorm:"-" json:"encrypted,omitempty"`
+}
+
+// TableName returns the name of the dead letter table
+func (DeadLetter) TableName() string {
+	return "dead_letters"
+}
+
+// deadLetter publishes the raw message to the dead letter channel
+func (octopus *EventOctopus) deadLetter(msg *natsClient.Msg, reason string) error {
+	deadLetter := DeadLetter{
+		ID:        uuid.NewV4().String(),
+		Subject:   msg.Subject,
+		Sequence:  msg.Sequence,
+		Reason:    reason,
+		Data:      msg.Data,
+		CreatedAt: now(),
+	}
+
+	logrus.Warnf("Dead-lettering message %d from %s: %s", msg.Sequence, msg.Subject, reason)
+
+	if err := octopus.publishDeadLetter(deadLetter); err != nil {
+		return fmt.Errorf("Error while publishing message to dead letter channel: %w", err)
+	}
+
+	eventsDeadLettered.WithLabelValues(msg.Subject).Inc()
+	return nil
+}
+
+func (octopus *EventOctopus) publishDeadLetter(deadLetter DeadLetter) error {
+	conn, err := octopus.client(ClientID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(deadLetter)
+	if err != nil {
+		return err
+	}
+
+	return conn.Publish(ChannelDeadLetter, data)
+}
+
+// startDeadLetterSubscriber stores all messages published to the dead letter channel
+func (octopus *EventOctopus) startDeadLetterSubscriber(sc natsClient.Conn) error {
+	return octopus.subscribe(sc, ChannelDeadLetter, func(msg *natsClient.Msg) error {
+		deadLetter := DeadLetter{}
+		if err := json.Unmarshal(msg.Data, &deadLetter); err != nil {
+			// a redelivery won't fix it
+			logrus.WithError(err).Errorf("Error unmarshalling dead letter [%d]", msg.Sequence)
+			return nil
+		}
+
+		// the message holds the payload of the event, which is only encrypted in the event store
+		if !deadLetter.Encrypted {
+			data, err := octopus.encryptPayload(string(deadLetter.Data), deadLetter.ID)
+			if err != nil {
+				return fmt.Errorf("Error while encrypting dead letter %s: %w", deadLetter.ID, err)
+			}
+			deadLetter.Data = []byte(data)
+		}
+
+		// redelivered dead letters overwrite themselves
+		if err := octopus.Db.Debug().Save(&deadLetter).Error; err != nil {
+			return fmt.Errorf("Error while storing dead letter %s: %w", deadLetter.ID, err)
+		}
+
+		return nil
+	}, natsClient.DurableName("consent-request-dead-letter-durable"),
+		natsClient.StartWithLastReceived(),
+	)
+}
+
+// DeadLetters returns all stored dead letters, oldest first
+func (octopus *EventOctopus) DeadLetters() ([]DeadLetter, error) {
+	var deadLetters []DeadLetter
+
+	if err := octopus.Db.Debug().Order("created_at").Find(&deadLetters).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range deadLetters {
+		// one unreadable dead letter does not hide the others
+		if err := octopus.decryptDeadLetter(&deadLetters[i]); err != nil {
+			logrus.WithError(err).Error("Returning dead letter as stored")
+		}
+	}
+
+	return deadLetters, nil
+}
+
+// GetDeadLetter returns a single dead letter or nil when not found
+func (octopus *EventOctopus) GetDeadLetter(id string) (*DeadLetter, error) {
+	deadLetter := &DeadLetter{}
+
+	err := octopus.Db.Debug().Where("id = ?", id).First(deadLetter).Error
+
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := octopus.decryptDeadLetter(deadLetter); err != nil {
+		return nil, err
+	}
+
+	return deadLetter, nil
+}
+
+// decryptDeadLetter decrypts the data of a dead letter read from the event store in place
+func (octopus *EventOctopus) decryptDeadLetter(deadLetter *DeadLetter) error {
+	data, err := octopus.decryptPayload(string(deadLetter.Data), deadLetter.ID)
+	if err != nil {
+		return fmt.Errorf("Error while decrypting dead letter %s: %w", deadLetter.ID, err)
+	}
+
+	deadLetter.Data = []byte(data)
+	return nil
+}
+
+// RequeueDeadLetter republishes the raw message to its original subject and removes the dead letter.
+// An event that reached the max retry count is retried from ChannelConsentRequest with a reset retry count instead,
+// on the retry channel it would reach the max retry count again. It returns false when the dead letter does not exist.
+func (octopus *EventOctopus) RequeueDeadLetter(id string) (bool, error) {
+	deadLetter, err := octopus.GetDeadLetter(id)
+	if err != nil || deadLetter == nil {
+		return false, err
+	}
+
+	if deadLetter.Reason == errMaxRetryCount {
+		if err := octopus.retryDeadLetter(*deadLetter); err != nil {
+			return false, fmt.Errorf("Error while retrying event of dead letter %s: %w", id, err)
+		}
+
+		logrus.Infof("Requeued dead letter %s to %s", id, ChannelConsentRequest)
+
+		return octopus.DiscardDeadLetter(id)
+	}
+
+	conn, err := octopus.client(ClientID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := conn.Publish(deadLetter.Subject, deadLetter.Data); err != nil {
+		return false, err
+	}
+
+	logrus.Infof("Requeued dead letter %s to %s", id, deadLetter.Subject)
+
+	return octopus.DiscardDeadLetter(id)
+}
+
+// retryDeadLetter retries the event of a dead letter like RetryEvent, an event missing from the event store is published
+// to ChannelConsentRequest as it was dead-lettered with a reset retry count
+func (octopus *EventOctopus) retryDeadLetter(deadLetter DeadLetter) error {
+	event := Event{}
+	if err := json.Unmarshal(deadLetter.Data, &event); err != nil {
+		return err
+	}
+
+	retried, err := octopus.RetryEvent(event.UUID)
+	if err != nil || retried != nil {
+		return err
+	}
+
+	event.RetryCount = 0
+	event.Error = nil
+	event.ErrorClass = nil
+
+	return octopus.publishEventToChannel(event, ChannelConsentRequest)
+}
+
+// DiscardDeadLetter removes a dead letter, it returns false when the dead letter did not exist
+func (octopus *EventOctopus) DiscardDeadLetter(id string) (bool, error) {
+	db := octopus.Db.Debug().Where("id = ?", id).Delete(DeadLetter{})
+
+	return db.RowsAffected > 0, db.Error
+}
@@ -0,0 +1,189 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	natsClient "github.com/nats-io/stan.go"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventOctopus_DeadLetters(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:deadletters?mode=memory&cache=shared"
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	stanClient := stanConnection()
+	defer stanClient.Close()
+
+	// waitForDeadLetter returns the dead letter holding the data
+	waitForDeadLetter := func(data []byte) *DeadLetter {
+		for j := 0; j < 50; j++ {
+			deadLetters, _ := i.DeadLetters()
+			for _, d := range deadLetters {
+				if string(d.Data) == string(data) {
+					return &d
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return nil
+	}
+
+	t.Run("unreadable message is dead-lettered with its origin", func(t *testing.T) {
+		data := []byte("{unreadable")
+		_ = stanClient.Publish(ChannelConsentRequest, data)
+
+		d := waitForDeadLetter(data)
+		if assert.NotNil(t, d) {
+			assert.Equal(t, ChannelConsentRequest, d.Subject)
+			assert.NotZero(t, d.Sequence)
+			assert.Contains(t, d.Reason, "invalid character")
+
+			stored, err := i.GetDeadLetter(d.ID)
+			if assert.NoError(t, err) && assert.NotNil(t, stored) {
+				assert.Equal(t, data, stored.Data)
+			}
+		}
+	})
+
+	t.Run("event exceeding the max retry count is dead-lettered", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.RetryCount = ConfigMaxRetryCountDefault
+		data, _ := json.Marshal(e)
+		_ = stanClient.Publish(ChannelConsentRetry, data)
+
+		d := waitForDeadLetter(data)
+		if assert.NotNil(t, d) {
+			assert.Equal(t, ChannelConsentRetry, d.Subject)
+			assert.Equal(t, "max retry count reached", d.Reason)
+		}
+	})
+
+	t.Run("requeued dead letter is published to its subject and removed", func(t *testing.T) {
+		data := []byte("{requeue")
+		_ = stanClient.Publish(ChannelConsentRequest, data)
+		d := waitForDeadLetter(data)
+		if !assert.NotNil(t, d) {
+			return
+		}
+
+		received := make(chan []byte, 10)
+		sub, err := stanClient.Subscribe(ChannelConsentRequest, func(msg *natsClient.Msg) {
+			received <- msg.Data
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer sub.Unsubscribe()
+
+		requeued, err := i.RequeueDeadLetter(d.ID)
+		assert.NoError(t, err)
+		assert.True(t, requeued)
+
+		select {
+		case r := <-received:
+			assert.Equal(t, data, r)
+		case <-time.After(time.Second):
+			t.Error("requeued message not received")
+		}
+
+		// the requeued message is unreadable again and ends up as a new dead letter
+		if n := waitForDeadLetter(data); assert.NotNil(t, n) {
+			assert.NotEqual(t, d.ID, n.ID)
+		}
+	})
+
+	t.Run("requeued event that reached the max retry count is retried", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.ExternalID = uuid.NewV4().String()
+		_ = i.SaveOrUpdateEvent(e)
+		e.RetryCount = ConfigMaxRetryCountDefault
+		data, _ := json.Marshal(e)
+		_ = stanClient.Publish(ChannelConsentRetry, data)
+		d := waitForDeadLetter(data)
+		if !assert.NotNil(t, d) {
+			return
+		}
+
+		received := make(chan Event, 10)
+		sub, err := stanClient.Subscribe(ChannelConsentRequest, func(msg *natsClient.Msg) {
+			r := Event{}
+			if json.Unmarshal(msg.Data, &r) == nil && r.UUID == e.UUID {
+				received <- r
+			}
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer sub.Unsubscribe()
+
+		requeued, err := i.RequeueDeadLetter(d.ID)
+		assert.NoError(t, err)
+		assert.True(t, requeued)
+
+		select {
+		case r := <-received:
+			assert.Equal(t, 0, r.RetryCount)
+			assert.Equal(t, e.Name, r.Name)
+			assert.Nil(t, r.Error)
+		case <-time.After(time.Second):
+			t.Error("retried event not received")
+		}
+		stored, _ := i.GetDeadLetter(d.ID)
+		assert.Nil(t, stored)
+	})
+
+	t.Run("discarded dead letter is removed", func(t *testing.T) {
+		data := []byte("{discard")
+		_ = stanClient.Publish(ChannelConsentErrored, data)
+		d := waitForDeadLetter(data)
+		if !assert.NotNil(t, d) {
+			return
+		}
+
+		discarded, err := i.DiscardDeadLetter(d.ID)
+		assert.NoError(t, err)
+		assert.True(t, discarded)
+
+		stored, err := i.GetDeadLetter(d.ID)
+		assert.NoError(t, err)
+		assert.Nil(t, stored)
+	})
+
+	t.Run("unknown dead letter can not be requeued or discarded", func(t *testing.T) {
+		requeued, err := i.RequeueDeadLetter(uuid.NewV4().String())
+		assert.NoError(t, err)
+		assert.False(t, requeued)
+
+		discarded, err := i.DiscardDeadLetter(uuid.NewV4().String())
+		assert.NoError(t, err)
+		assert.False(t, discarded)
+	})
+}
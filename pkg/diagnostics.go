@@ -0,0 +1,334 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	natsServer "github.com/nats-io/nats-streaming-server/server"
+)
+
+// monitorHost is the interface the monitoring endpoint of the embedded Nats streaming server binds to
+const monitorHost = "127.0.0.1"
+
+// monitorTimeout is the max time for reading the channel statistics from the monitoring endpoint
+var monitorTimeout = 2 * time.Second
+
+// DiagnosticsReport holds all diagnostics of the event octopus, Diagnostics returns the same information as text
+type DiagnosticsReport struct {
+	Nats        NatsStatus            `json:"nats"`
+	DB          DBStatus              `json:"db"`
+	Checkpoints []Checkpoint          `json:"checkpoints"`
+	Channels    ChannelDiagnostics    `json:"channels"`
+	EventStates EventStateDiagnostics `json:"eventStates"`
+	RetryQueues RetryQueueDiagnostics `json:"retryQueues"`
+	// CircuitBreaker is the state of message processing of the event store
+	CircuitBreaker CircuitBreakerStatus `json:"circuitBreaker"`
+	// CheckpointError explains why the checkpoints could not be read
+	CheckpointError string `json:"checkpointError,omitempty"`
+}
+
+// NatsStatus is the state of the connection to Nats
+type NatsStatus struct {
+	Up        bool   `json:"up"`
+	Mode      string `json:"mode"`
+	Address   string `json:"address"`
+	ClusterID string `json:"clusterId"`
+	LastError string `json:"lastError"`
+}
+
+// DBStatus is the state of the connection to the event store
+type DBStatus struct {
+	Up    bool   `json:"up"`
+	Error string `json:"error"`
+}
+
+// ChannelStats are the statistics of a Nats channel
+type ChannelStats struct {
+	Name          string `json:"name"`
+	Messages      int    `json:"messages"`
+	Bytes         uint64 `json:"bytes"`
+	FirstSequence uint64 `json:"firstSequence"`
+	LastSequence  uint64 `json:"lastSequence"`
+	Subscriptions int    `json:"subscriptions"`
+	Durables      int    `json:"durables"`
+}
+
+// ChannelDiagnostics holds the statistics of all channels of the embedded Nats streaming server
+type ChannelDiagnostics struct {
+	Channels []ChannelStats `json:"channels"`
+	// Error explains why the statistics are not available
+	Error string `json:"error,omitempty"`
+}
+
+// Name returns the name of the ChannelDiagnostics
+func (cd ChannelDiagnostics) Name() string {
+	return "Nats channels"
+}
+
+// String returns the message count, sequences and subscriptions per channel
+func (cd ChannelDiagnostics) String() string {
+	if cd.Error != "" {
+		return cd.Error
+	}
+
+	if len(cd.Channels) == 0 {
+		return "NONE"
+	}
+
+	var channels []string
+	for _, c := range cd.Channels {
+		channels = append(channels, fmt.Sprintf("%s: %d msgs (%d-%d), %d subs, %d durable", c.Name, c.Messages, c.FirstSequence, c.LastSequence, c.Subscriptions, c.Durables))
+	}
+
+	return strings.Join(channels, ", ")
+}
+
+// StateCount is the number of events in a state
+type StateCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// EventStateDiagnostics holds the number of events per state in the event store
+type EventStateDiagnostics struct {
+	States []StateCount `json:"states"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// Name returns the name of the EventStateDiagnostics
+func (ed EventStateDiagnostics) Name() string {
+	return "Events per state"
+}
+
+// String returns the number of events per state
+func (ed EventStateDiagnostics) String() string {
+	if ed.Error != "" {
+		return fmt.Sprintf("error: %s", ed.Error)
+	}
+
+	if len(ed.States) == 0 {
+		return "NONE"
+	}
+
+	var states []string
+	for _, s := range ed.States {
+		states = append(states, fmt.Sprintf("%s: %d", s.Name, s.Count))
+	}
+
+	return strings.Join(states, ", ")
+}
+
+// RetryQueue is a channel of a DelayedConsumer and the delay before its messages are published again
+type RetryQueue struct {
+	Subject string        `json:"subject"`
+	Delay   time.Duration `json:"delay"`
+}
+
+// RetryQueueDiagnostics holds the configured retry ladders of events and webhook notifications
+type RetryQueueDiagnostics struct {
+	Events   []RetryQueue `json:"events"`
+	Webhooks []RetryQueue `json:"webhooks"`
+}
+
+// Name returns the name of the RetryQueueDiagnostics
+func (rd RetryQueueDiagnostics) Name() string {
+	return "Retry queues"
+}
+
+// String returns the delay of each retry queue
+func (rd RetryQueueDiagnostics) String() string {
+	return fmt.Sprintf("events: %s, webhooks: %s", formatRetryQueues(rd.Events), formatRetryQueues(rd.Webhooks))
+}
+
+func formatRetryQueues(queues []RetryQueue) string {
+	if len(queues) == 0 {
+		return "NONE"
+	}
+
+	var delays []string
+	for _, q := range queues {
+		delays = append(delays, fmt.Sprintf("%s after %s", q.Subject, q.Delay))
+	}
+
+	return strings.Join(delays, ", ")
+}
+
+// DiagnosticsReport returns all diagnostics as structured data
+func (octopus *EventOctopus) DiagnosticsReport() DiagnosticsReport {
+	nats := octopus.natsDiagnostics()
+	report := DiagnosticsReport{
+		Nats: NatsStatus{
+			Up:        nats.up,
+			Mode:      nats.natsMode,
+			Address:   nats.address(),
+			ClusterID: nats.stanID,
+		},
+		Channels:       octopus.channelDiagnostics(),
+		EventStates:    octopus.eventStateDiagnostics(),
+		RetryQueues:    octopus.retryQueueDiagnostics(),
+		CircuitBreaker: octopus.CircuitBreakerStatus(),
+	}
+	if nats.lastError != nil {
+		report.Nats.LastError = nats.lastError.Error()
+	}
+
+	if err := octopus.sqlDb.Ping(); err != nil {
+		report.DB.Error = err.Error()
+	} else {
+		report.DB.Up = true
+	}
+
+	checkpoints, err := octopus.Checkpoints()
+	if err != nil {
+		report.CheckpointError = err.Error()
+	}
+	report.Checkpoints = checkpoints
+
+	return report
+}
+
+// channelDiagnostics reads the channel statistics from the monitoring endpoint of the embedded Nats streaming server
+func (octopus *EventOctopus) channelDiagnostics() ChannelDiagnostics {
+	if !octopus.Config.embedded() {
+		return ChannelDiagnostics{Error: "not available for an external Nats cluster"}
+	}
+	if octopus.stanServer == nil {
+		return ChannelDiagnostics{Error: "not available, Nats is down"}
+	}
+
+	channelsz, err := octopus.channelsz()
+	if err != nil {
+		return ChannelDiagnostics{Error: fmt.Sprintf("error: %v", err)}
+	}
+
+	diagnostics := ChannelDiagnostics{Channels: []ChannelStats{}}
+	for _, c := range channelsz.Channels {
+		stats := ChannelStats{
+			Name:          c.Name,
+			Messages:      c.Msgs,
+			Bytes:         c.Bytes,
+			FirstSequence: c.FirstSeq,
+			LastSequence:  c.LastSeq,
+			Subscriptions: len(c.Subscriptions),
+		}
+		for _, s := range c.Subscriptions {
+			if s.IsDurable {
+				stats.Durables++
+			}
+		}
+		diagnostics.Channels = append(diagnostics.Channels, stats)
+	}
+
+	sort.Slice(diagnostics.Channels, func(i, j int) bool {
+		return diagnostics.Channels[i].Name < diagnostics.Channels[j].Name
+	})
+
+	return diagnostics
+}
+
+func (octopus *EventOctopus) channelsz() (*natsServer.Channelsz, error) {
+	channelsz := &natsServer.Channelsz{}
+	if err := octopus.monitor("subs=1", channelsz); err != nil {
+		return nil, err
+	}
+
+	return channelsz, nil
+}
+
+// channelz returns the statistics of a single channel, nil when the channel does not exist
+func (octopus *EventOctopus) channelz(channel string) (*natsServer.Channelz, error) {
+	channelz := &natsServer.Channelz{}
+	if err := octopus.monitor("channel="+url.QueryEscape(channel), channelz); err != nil {
+		if errors.Is(err, errChannelNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return channelz, nil
+}
+
+// errChannelNotFound is returned by the monitoring endpoint for a channel nothing was published or subscribed to
+var errChannelNotFound = errors.New("channel not found")
+
+// monitor reads the channel statistics selected by the query from the monitoring endpoint
+func (octopus *EventOctopus) monitor(query string, v interface{}) error {
+	client := http.Client{Timeout: monitorTimeout}
+
+	resp, err := client.Get(fmt.Sprintf("http://%s:%d%s?%s", monitorHost, octopus.monitorPort, natsServer.ChannelsPath, query))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errChannelNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("monitoring endpoint returned status %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// eventStateDiagnostics counts the events per state in the event store
+func (octopus *EventOctopus) eventStateDiagnostics() EventStateDiagnostics {
+	if octopus.Db == nil {
+		return EventStateDiagnostics{Error: "event store not started"}
+	}
+
+	var states []StateCount
+	err := octopus.Db.Debug().Model(&Event{}).Select("name, count(*) AS count").Group("name").Order("name").Scan(&states).Error
+	if err != nil {
+		return EventStateDiagnostics{Error: err.Error()}
+	}
+
+	if states == nil {
+		states = []StateCount{}
+	}
+
+	return EventStateDiagnostics{States: states}
+}
+
+// retryQueueDiagnostics returns the retry ladders as created from the config, without jitter
+func (octopus *EventOctopus) retryQueueDiagnostics() RetryQueueDiagnostics {
+	return RetryQueueDiagnostics{
+		Events:   retryQueues(octopus.retryConsumers(nil)),
+		Webhooks: retryQueues(NewDelayedConsumerSet(ChannelWebhookRetry, ChannelWebhookDelivery, octopus.Config.WebhookMaxRetryCount, time.Second, octopus.Config.WebhookIncrementalBackoff, nil, octopus)),
+	}
+}
+
+func retryQueues(consumers []*DelayedConsumer) []RetryQueue {
+	queues := make([]RetryQueue, len(consumers))
+	for i, dc := range consumers {
+		queues[i] = RetryQueue{
+			Subject: dc.consumeSubject,
+			Delay:   dc.delay,
+		}
+	}
+	return queues
+}
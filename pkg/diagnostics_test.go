@@ -0,0 +1,169 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventOctopus_channelDiagnostics(t *testing.T) {
+	t.Run("monitoring endpoint binds to a free port without monitoring port", func(t *testing.T) {
+		i := testEventOctopus()
+		_ = i.startStanServer()
+		defer i.Shutdown()
+
+		d := i.channelDiagnostics()
+
+		assert.Empty(t, d.Error)
+		assert.NotEqual(t, 0, i.monitorPort)
+	})
+
+	t.Run("not available for an external cluster", func(t *testing.T) {
+		i := testEventOctopus()
+		i.Config.NatsMonitorPort = 8223
+		i.Config.NatsURL = "nats://nats:4222"
+
+		d := i.channelDiagnostics()
+
+		assert.Equal(t, "not available for an external Nats cluster", d.String())
+	})
+
+	t.Run("not available when Nats is down", func(t *testing.T) {
+		i := testEventOctopus()
+		i.Config.NatsMonitorPort = 8223
+
+		d := i.channelDiagnostics()
+
+		assert.Equal(t, "not available, Nats is down", d.String())
+	})
+
+	t.Run("returns the statistics per channel", func(t *testing.T) {
+		i := testEventOctopus()
+		i.Config.NatsMonitorPort = 8223
+		i.Config.Connectionstring = "file:diagnostics_channels?mode=memory&cache=shared"
+		i.configure()
+		if err := i.Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer i.Shutdown()
+
+		publisher, _ := i.EventPublisher("diagnostics")
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = publisher.Publish(ChannelConsentRequest, e)
+
+		d := i.channelDiagnostics()
+
+		if assert.Empty(t, d.Error) {
+			var stats *ChannelStats
+			for j, c := range d.Channels {
+				if c.Name == ChannelConsentRequest {
+					stats = &d.Channels[j]
+				}
+			}
+			if assert.NotNil(t, stats) {
+				assert.Equal(t, 1, stats.Messages)
+				assert.Equal(t, uint64(1), stats.LastSequence)
+				assert.Equal(t, 1, stats.Durables)
+				assert.Contains(t, d.String(), ChannelConsentRequest+": ")
+			}
+		}
+	})
+}
+
+func TestEventOctopus_eventStateDiagnostics(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:diagnostics_states?mode=memory&cache=shared"
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("counts events per state", func(t *testing.T) {
+		for _, name := range []string{EventCompleted, EventCompleted, EventErrored} {
+			e := event()
+			e.UUID = uuid.NewV4().String()
+			e.Name = name
+			_ = i.SaveOrUpdateEvent(e)
+		}
+
+		d := i.eventStateDiagnostics()
+
+		assert.Empty(t, d.Error)
+		assert.Equal(t, []StateCount{{Name: EventCompleted, Count: 2}, {Name: EventErrored, Count: 1}}, d.States)
+		assert.Equal(t, "completed: 2, error: 1", d.String())
+	})
+
+	i.Shutdown()
+
+	t.Run("returns error when DB is down", func(t *testing.T) {
+		d := i.eventStateDiagnostics()
+
+		assert.Equal(t, "error: sql: database is closed", d.String())
+	})
+}
+
+func TestEventOctopus_retryQueueDiagnostics(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.MaxRetryCount = 3
+	i.Config.IncrementalBackoff = 2
+	i.Config.WebhookMaxRetryCount = 1
+
+	d := i.retryQueueDiagnostics()
+
+	assert.Equal(t, []RetryQueue{
+		{Subject: "consentRequestRetry-0", Delay: time.Second},
+		{Subject: "consentRequestRetry-1", Delay: 2 * time.Second},
+		{Subject: "consentRequestRetry-2", Delay: 4 * time.Second},
+	}, d.Events)
+	assert.Equal(t, []RetryQueue{{Subject: "webhookRetry-0", Delay: time.Second}}, d.Webhooks)
+	assert.Equal(t, "events: consentRequestRetry-0 after 1s, consentRequestRetry-1 after 2s, consentRequestRetry-2 after 4s, webhooks: webhookRetry-0 after 1s", d.String())
+}
+
+func TestEventOctopus_DiagnosticsReport(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:diagnostics_report?mode=memory&cache=shared"
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	_ = i.saveCheckpoint("service", "subject", 12)
+
+	report := i.DiagnosticsReport()
+
+	assert.Equal(t, NatsStatus{Up: true, Mode: "STANDALONE", Address: "0.0.0.0:4222", ClusterID: "nuts"}, report.Nats)
+	assert.Equal(t, DBStatus{Up: true}, report.DB)
+	assert.Len(t, report.Checkpoints, 1)
+	assert.Empty(t, report.Channels.Error)
+	assert.NotEmpty(t, report.Channels.Channels)
+	assert.Len(t, report.RetryQueues.Events, ConfigMaxRetryCountDefault)
+
+	t.Run("marshals to JSON", func(t *testing.T) {
+		_, err := json.Marshal(report)
+
+		assert.NoError(t, err)
+	})
+}
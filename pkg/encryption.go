@@ -0,0 +1,441 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// encryptedPrefix marks an encrypted payload, payloads without it are stored in plain text.
+// An encrypted payload is stored as enc:v2:{key ID}:{encrypted data key}:{encrypted payload}, all base64 encoded except the key ID.
+// The ID of the row the payload is stored in is authenticated with the payload, so it can't be moved to another row.
+const encryptedPrefix = "enc:v2:"
+
+// encryptedPrefixV1 marks a payload encrypted without the ID of its row, it is encrypted again by EncryptPayloads
+const encryptedPrefixV1 = "enc:v1:"
+
+// dataKeySize is the size of the AES-256 keys used for data keys and key encryption keys
+const dataKeySize = 32
+
+// ErrNoKeyProvider is returned when an encrypted payload is read or payloads are encrypted without an encryption key
+var ErrNoKeyProvider = errors.New("no encryption key configured")
+
+// ErrUnknownKey is returned when a payload is encrypted with a key that is not known to the key provider
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// KeyProvider encrypts and decrypts the data keys of encrypted payloads with a key encryption key.
+// Every payload is encrypted with its own data key, rotating the key encryption key only requires the data keys to be encrypted again.
+type KeyProvider interface {
+	// CurrentKeyID returns the ID of the key used to encrypt new data keys
+	CurrentKeyID() string
+	// WrapKey encrypts the data key with the current key and returns the ID of that key
+	WrapKey(dataKey []byte) (string, []byte, error)
+	// UnwrapKey decrypts a data key that was encrypted with the key with the given ID
+	UnwrapKey(keyID string, wrapped []byte) ([]byte, error)
+}
+
+// LocalKeyProvider is a KeyProvider with the keys read from a local key file
+type LocalKeyProvider struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewLocalKeyProvider reads the keys from the key file. Every line holds a key ID and a base64 encoded 256 bit key separated by whitespace,
+// empty lines and lines starting with # are ignored. The first key is the current key, the other keys are only used for decrypting.
+func NewLocalKeyProvider(path string) (*LocalKeyProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error while reading key file: %w", err)
+	}
+	defer f.Close()
+
+	provider := &LocalKeyProvider{keys: map[string][]byte{}}
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 || strings.Contains(fields[0], ":") {
+			return nil, fmt.Errorf("invalid key on line %d of key file %s", line, path)
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(key) != dataKeySize {
+			return nil, fmt.Errorf("invalid key on line %d of key file %s: expected %d base64 encoded bytes", line, path, dataKeySize)
+		}
+		if _, ok := provider.keys[fields[0]]; ok {
+			return nil, fmt.Errorf("duplicate key %s in key file %s", fields[0], path)
+		}
+
+		if provider.current == "" {
+			provider.current = fields[0]
+		}
+		provider.keys[fields[0]] = key
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Error while reading key file: %w", err)
+	}
+
+	if provider.current == "" {
+		return nil, fmt.Errorf("no keys in key file %s", path)
+	}
+
+	return provider, nil
+}
+
+// CurrentKeyID returns the ID of the first key in the key file
+func (p *LocalKeyProvider) CurrentKeyID() string {
+	return p.current
+}
+
+// WrapKey encrypts the data key with the first key in the key file
+func (p *LocalKeyProvider) WrapKey(dataKey []byte) (string, []byte, error) {
+	wrapped, err := seal(p.keys[p.current], dataKey, nil)
+	return p.current, wrapped, err
+}
+
+// UnwrapKey decrypts the data key with the key with the given ID
+func (p *LocalKeyProvider) UnwrapKey(keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+
+	return open(key, wrapped, nil)
+}
+
+// loadKeyProvider reads the configured key file, the KeyProvider is left untouched when no key file is configured
+func (octopus *EventOctopus) loadKeyProvider() error {
+	if octopus.Config.EncryptionKeyFile == "" {
+		return nil
+	}
+
+	provider, err := NewLocalKeyProvider(octopus.Config.EncryptionKeyFile)
+	if err != nil {
+		return err
+	}
+
+	octopus.KeyProvider = provider
+	return nil
+}
+
+// encryptPayload returns the payload encrypted with a new data key for the row with the given ID, or the payload itself when
+// no KeyProvider is set
+func (octopus *EventOctopus) encryptPayload(payload string, id string) (string, error) {
+	if octopus.KeyProvider == nil {
+		return payload, nil
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+
+	keyID, wrapped, err := octopus.KeyProvider.WrapKey(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := seal(dataKey, []byte(payload), []byte(id))
+	if err != nil {
+		return "", err
+	}
+
+	return formatEncrypted(keyID, wrapped, ciphertext), nil
+}
+
+// decryptPayload returns the plain text of a payload encrypted for the row with the given ID, payloads stored in plain text
+// are returned as is. A plain text payload that happens to start with the prefix is recognized by not having the encrypted format.
+func (octopus *EventOctopus) decryptPayload(payload string, id string) (string, error) {
+	keyID, wrapped, ciphertext, bound, err := parseEncrypted(payload)
+	if err != nil {
+		return payload, nil
+	}
+
+	if octopus.KeyProvider == nil {
+		return "", ErrNoKeyProvider
+	}
+
+	dataKey, err := octopus.KeyProvider.UnwrapKey(keyID, wrapped)
+	if err != nil {
+		return "", err
+	}
+
+	var aad []byte
+	if bound {
+		aad = []byte(id)
+	}
+
+	plaintext, err := open(dataKey, ciphertext, aad)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// decryptEvents decrypts the payloads of the events read from the event store in place.
+// An event that can't be decrypted keeps its stored payload, so it does not hide the other events.
+func (octopus *EventOctopus) decryptEvents(events []Event) error {
+	for i := range events {
+		if err := octopus.decryptEvent(&events[i]); err != nil {
+			logrus.WithError(err).Error("Returning event with its payload as stored")
+		}
+	}
+
+	return nil
+}
+
+// decryptEvent decrypts the payload of an event read from the event store in place
+func (octopus *EventOctopus) decryptEvent(event *Event) error {
+	payload, err := octopus.decryptPayload(event.Payload, event.UUID)
+	if err != nil {
+		return fmt.Errorf("Error while decrypting payload of event %s: %w", event.UUID, err)
+	}
+
+	event.Payload = payload
+	return nil
+}
+
+// EncryptPayloads encrypts all payloads stored in plain text and the data keys of payloads encrypted with another key than the current key.
+// Existing rows are migrated after encryption is enabled and after the key has been rotated. Messages waiting in the retry queues,
+// dead letters and webhook secrets are migrated as well. It returns the number of updated events, scheduled messages, dead letters
+// and webhooks.
+func (octopus *EventOctopus) EncryptPayloads() (int, error) {
+	if err := octopus.connectDb(); err != nil {
+		return 0, err
+	}
+
+	if octopus.KeyProvider == nil {
+		return 0, ErrNoKeyProvider
+	}
+
+	var events []Event
+	if err := octopus.Db.Debug().Select("uuid, payload, version").Find(&events).Error; err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, e := range events {
+		payload, err := octopus.reencryptPayload(e.Payload, e.UUID)
+		if err != nil {
+			return updated, fmt.Errorf("Error while encrypting payload of event %s: %w", e.UUID, err)
+		}
+		if payload == e.Payload {
+			continue
+		}
+
+		// the version is kept, the content of the event does not change. An event changed in the meantime already has a new payload.
+		result := octopus.Db.Debug().Model(&Event{}).Where("uuid = ? AND version = ?", e.UUID, e.Version).UpdateColumn("payload", payload)
+		if result.Error != nil {
+			return updated, result.Error
+		}
+		updated += int(result.RowsAffected)
+	}
+
+	var messages []ScheduledMessage
+	if err := octopus.Db.Debug().Select("id, data").Find(&messages).Error; err != nil {
+		return updated, err
+	}
+
+	for _, m := range messages {
+		data, err := octopus.reencryptPayload(m.Data, m.ID)
+		if err != nil {
+			return updated, fmt.Errorf("Error while encrypting scheduled message %s: %w", m.ID, err)
+		}
+		if data == m.Data {
+			continue
+		}
+
+		// a message published in the meantime is gone
+		result := octopus.Db.Debug().Model(&ScheduledMessage{}).Where("id = ?", m.ID).UpdateColumn("data", data)
+		if result.Error != nil {
+			return updated, result.Error
+		}
+		updated += int(result.RowsAffected)
+	}
+
+	var deadLetters []DeadLetter
+	if err := octopus.Db.Debug().Select("id, data").Find(&deadLetters).Error; err != nil {
+		return updated, err
+	}
+
+	for _, d := range deadLetters {
+		data, err := octopus.reencryptPayload(string(d.Data), d.ID)
+		if err != nil {
+			return updated, fmt.Errorf("Error while encrypting dead letter %s: %w", d.ID, err)
+		}
+		if data == string(d.Data) {
+			continue
+		}
+
+		result := octopus.Db.Debug().Model(&DeadLetter{}).Where("id = ?", d.ID).UpdateColumn("data", []byte(data))
+		if result.Error != nil {
+			return updated, result.Error
+		}
+		updated += int(result.RowsAffected)
+	}
+
+	var webhooks []Webhook
+	if err := octopus.Db.Debug().Select("id, secret").Where("secret IS NOT NULL AND secret <> ''").Find(&webhooks).Error; err != nil {
+		return updated, err
+	}
+
+	for _, w := range webhooks {
+		secret, err := octopus.reencryptPayload(w.Secret, w.ID)
+		if err != nil {
+			return updated, fmt.Errorf("Error while encrypting secret of webhook %s: %w", w.ID, err)
+		}
+		if secret == w.Secret {
+			continue
+		}
+
+		result := octopus.Db.Debug().Model(&Webhook{}).Where("id = ?", w.ID).UpdateColumn("secret", secret)
+		if result.Error != nil {
+			return updated, result.Error
+		}
+		updated += int(result.RowsAffected)
+	}
+
+	logrus.Infof("Encrypted payloads of %d events, scheduled messages, dead letters and webhooks with key %s", updated, octopus.KeyProvider.CurrentKeyID())
+
+	return updated, nil
+}
+
+// reencryptPayload encrypts a payload in plain text or encrypted without the ID of its row, or encrypts the data key again
+// when it's encrypted with an old key
+func (octopus *EventOctopus) reencryptPayload(payload string, id string) (string, error) {
+	keyID, wrapped, ciphertext, bound, err := parseEncrypted(payload)
+	if err != nil {
+		return octopus.encryptPayload(payload, id)
+	}
+	if !bound {
+		plaintext, err := octopus.decryptPayload(payload, id)
+		if err != nil {
+			return "", err
+		}
+		return octopus.encryptPayload(plaintext, id)
+	}
+	if keyID == octopus.KeyProvider.CurrentKeyID() {
+		return payload, nil
+	}
+
+	dataKey, err := octopus.KeyProvider.UnwrapKey(keyID, wrapped)
+	if err != nil {
+		return "", err
+	}
+
+	keyID, wrapped, err = octopus.KeyProvider.WrapKey(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	return formatEncrypted(keyID, wrapped, ciphertext), nil
+}
+
+func formatEncrypted(keyID string, wrapped []byte, ciphertext []byte) string {
+	return encryptedPrefix + strings.Join([]string{
+		keyID,
+		base64.StdEncoding.EncodeToString(wrapped),
+		base64.StdEncoding.EncodeToString(ciphertext),
+	}, ":")
+}
+
+// parseEncrypted returns the key ID, the encrypted data key and the encrypted payload. The returned bool is false for a
+// payload encrypted without the ID of its row.
+func parseEncrypted(payload string) (string, []byte, []byte, bool, error) {
+	var prefix string
+	switch {
+	case strings.HasPrefix(payload, encryptedPrefix):
+		prefix = encryptedPrefix
+	case strings.HasPrefix(payload, encryptedPrefixV1):
+		prefix = encryptedPrefixV1
+	default:
+		return "", nil, nil, false, errors.New("payload not encrypted")
+	}
+
+	parts := strings.Split(strings.TrimPrefix(payload, prefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, false, errors.New("invalid encrypted payload")
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, false, fmt.Errorf("invalid encrypted payload: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, false, fmt.Errorf("invalid encrypted payload: %w", err)
+	}
+
+	return parts[0], wrapped, ciphertext, prefix == encryptedPrefix, nil
+}
+
+// seal encrypts the plaintext with AES-GCM and authenticates the additional data with it, the random nonce is prepended to the ciphertext
+func seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts a ciphertext created by seal with the same additional data
+func open(key []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
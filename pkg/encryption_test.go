@@ -0,0 +1,366 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func randomKey() string {
+	key := make([]byte, dataKeySize)
+	_, _ = rand.Read(key)
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func writeKeyFile(t *testing.T, dir string, lines ...string) string {
+	path := filepath.Join(dir, uuid.NewV4().String())
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewLocalKeyProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("first key is the current key", func(t *testing.T) {
+		path := writeKeyFile(t, dir, "# keys", "", "key-2 "+randomKey(), "key-1 "+randomKey())
+
+		provider, err := NewLocalKeyProvider(path)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, "key-2", provider.CurrentKeyID())
+			assert.Len(t, provider.keys, 2)
+		}
+	})
+
+	t.Run("wrapped key can be unwrapped", func(t *testing.T) {
+		provider, _ := NewLocalKeyProvider(writeKeyFile(t, dir, "key-1 "+randomKey()))
+		dataKey := []byte(strings.Repeat("k", dataKeySize))
+
+		keyID, wrapped, err := provider.WrapKey(dataKey)
+		if !assert.NoError(t, err) {
+			return
+		}
+		unwrapped, err := provider.UnwrapKey(keyID, wrapped)
+
+		assert.NoError(t, err)
+		assert.Equal(t, dataKey, unwrapped)
+
+		_, err = provider.UnwrapKey("key-2", wrapped)
+		assert.True(t, errors.Is(err, ErrUnknownKey))
+	})
+
+	t.Run("returns error for invalid key files", func(t *testing.T) {
+		for _, lines := range [][]string{
+			{"key-1"},
+			{"key-1 not-base64"},
+			{"key-1 " + base64.StdEncoding.EncodeToString([]byte("short"))},
+			{"key:1 " + randomKey()},
+			{"key-1 " + randomKey(), "key-1 " + randomKey()},
+			{"# no keys"},
+		} {
+			_, err := NewLocalKeyProvider(writeKeyFile(t, dir, lines...))
+			assert.Error(t, err, lines)
+		}
+	})
+
+	t.Run("returns error for missing key file", func(t *testing.T) {
+		_, err := NewLocalKeyProvider(filepath.Join(dir, "missing"))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestEventOctopus_encryption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldKey := "key-1 " + randomKey()
+	newKey := "key-2 " + randomKey()
+
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:encryption?mode=memory&cache=shared"
+	i.Config.EncryptionKeyFile = writeKeyFile(t, dir, oldKey)
+	if err := i.configure(); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	storedPayload := func(u string) string {
+		var payloads []string
+		i.Db.Table("events").Where("uuid = ?", u).Pluck("payload", &payloads)
+		if len(payloads) == 0 {
+			return ""
+		}
+		return payloads[0]
+	}
+
+	t.Run("payload is encrypted in the event store", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Payload = "personal data"
+		_ = i.SaveOrUpdateEvent(e)
+
+		stored := storedPayload(e.UUID)
+		assert.True(t, strings.HasPrefix(stored, encryptedPrefix+"key-1:"))
+		assert.NotContains(t, stored, "personal data")
+
+		read, _ := i.GetEvent(e.UUID)
+		assert.Equal(t, "personal data", read.Payload)
+		read, _ = i.GetEventByExternalID(e.ExternalID)
+		assert.Equal(t, e.ExternalID, read.ExternalID)
+		assert.False(t, strings.HasPrefix(read.Payload, encryptedPrefix))
+
+		page, _ := i.Query(EventQuery{})
+		for _, p := range page.Events {
+			assert.False(t, strings.HasPrefix(p.Payload, encryptedPrefix))
+		}
+	})
+
+	t.Run("payloads stored in plain text are encrypted", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Payload = "plain"
+		provider := i.KeyProvider
+		i.KeyProvider = nil
+		_ = i.SaveOrUpdateEvent(e)
+		i.KeyProvider = provider
+		assert.Equal(t, "plain", storedPayload(e.UUID))
+
+		read, _ := i.GetEvent(e.UUID)
+		assert.Equal(t, "plain", read.Payload)
+
+		updated, err := i.EncryptPayloads()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, updated)
+		assert.True(t, strings.HasPrefix(storedPayload(e.UUID), encryptedPrefix))
+		read, _ = i.GetEvent(e.UUID)
+		assert.Equal(t, "plain", read.Payload)
+		assert.Equal(t, 1, read.Version)
+	})
+
+	t.Run("data keys are encrypted again after rotation", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Payload = "rotated"
+		_ = i.SaveOrUpdateEvent(e)
+		before := storedPayload(e.UUID)
+
+		rotated, err := NewLocalKeyProvider(writeKeyFile(t, dir, newKey, oldKey))
+		if !assert.NoError(t, err) {
+			return
+		}
+		i.KeyProvider = rotated
+
+		updated, err := i.EncryptPayloads()
+		assert.NoError(t, err)
+		assert.True(t, updated >= 1)
+
+		after := storedPayload(e.UUID)
+		assert.True(t, strings.HasPrefix(after, encryptedPrefix+"key-2:"))
+		// only the data key is encrypted again
+		assert.Equal(t, before[strings.LastIndex(before, ":"):], after[strings.LastIndex(after, ":"):])
+
+		// the old key is no longer needed
+		i.KeyProvider, _ = NewLocalKeyProvider(writeKeyFile(t, dir, newKey))
+		read, err := i.GetEvent(e.UUID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "rotated", read.Payload)
+		}
+
+		updated, _ = i.EncryptPayloads()
+		assert.Equal(t, 0, updated)
+	})
+
+	t.Run("encrypted payload can not be read without key", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = i.SaveOrUpdateEvent(e)
+		provider, keyFile := i.KeyProvider, i.Config.EncryptionKeyFile
+		i.KeyProvider, i.Config.EncryptionKeyFile = nil, ""
+		defer func() {
+			i.KeyProvider, i.Config.EncryptionKeyFile = provider, keyFile
+		}()
+
+		_, err := i.GetEvent(e.UUID)
+
+		assert.True(t, errors.Is(err, ErrNoKeyProvider))
+		_, err = i.EncryptPayloads()
+		assert.True(t, errors.Is(err, ErrNoKeyProvider))
+	})
+
+	t.Run("received event is stored encrypted", func(t *testing.T) {
+		publisher, _ := i.EventPublisher("event-octopus-test")
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Payload = "published"
+		_ = publisher.Publish(ChannelConsentRequest, e)
+
+		for j := 0; j < 100 && storedPayload(e.UUID) == ""; j++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		assert.True(t, strings.HasPrefix(storedPayload(e.UUID), encryptedPrefix))
+	})
+	t.Run("plain text payload with the prefix is readable", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Payload = encryptedPrefix + "not encrypted"
+		provider := i.KeyProvider
+		i.KeyProvider = nil
+		_ = i.SaveOrUpdateEvent(e)
+		i.KeyProvider = provider
+
+		read, err := i.GetEvent(e.UUID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, e.Payload, read.Payload)
+		}
+		events, err := i.List()
+		if assert.NoError(t, err) {
+			assert.Equal(t, e.Payload, byUUID(*events)[e.UUID].Payload)
+		}
+	})
+
+	t.Run("dead letters are stored encrypted", func(t *testing.T) {
+		sc, _ := i.client(ClientID)
+		_ = sc.Publish(ChannelConsentRequest, []byte("personal data that is not an event"))
+
+		var deadLetter *DeadLetter
+		for j := 0; j < 100 && deadLetter == nil; j++ {
+			deadLetters, _ := i.DeadLetters()
+			for k, d := range deadLetters {
+				if string(d.Data) == "personal data that is not an event" {
+					deadLetter = &deadLetters[k]
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if !assert.NotNil(t, deadLetter) {
+			return
+		}
+
+		var stored [][]byte
+		i.Db.Table("dead_letters").Where("id = ?", deadLetter.ID).Pluck("data", &stored)
+		if assert.Len(t, stored, 1) {
+			assert.True(t, strings.HasPrefix(string(stored[0]), encryptedPrefix))
+		}
+
+		read, err := i.GetDeadLetter(deadLetter.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "personal data that is not an event", string(read.Data))
+		}
+	})
+
+	t.Run("payload moved to another row can't be decrypted", func(t *testing.T) {
+		e, other := event(), event()
+		e.UUID, other.UUID = uuid.NewV4().String(), uuid.NewV4().String()
+		other.ExternalID = uuid.NewV4().String()
+		_ = i.SaveOrUpdateEvent(e)
+		_ = i.SaveOrUpdateEvent(other)
+
+		i.Db.Table("events").Where("uuid = ?", other.UUID).Update("payload", storedPayload(e.UUID))
+
+		_, err := i.GetEvent(other.UUID)
+		assert.Error(t, err)
+	})
+
+	t.Run("payloads encrypted without their row are encrypted again", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = i.SaveOrUpdateEvent(e)
+		dataKey := make([]byte, dataKeySize)
+		_, _ = rand.Read(dataKey)
+		keyID, wrapped, _ := i.KeyProvider.WrapKey(dataKey)
+		ciphertext, _ := seal(dataKey, []byte("unbound"), nil)
+		v1 := encryptedPrefixV1 + strings.TrimPrefix(formatEncrypted(keyID, wrapped, ciphertext), encryptedPrefix)
+		i.Db.Table("events").Where("uuid = ?", e.UUID).Update("payload", v1)
+
+		read, err := i.GetEvent(e.UUID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "unbound", read.Payload)
+		}
+
+		_, err = i.EncryptPayloads()
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(storedPayload(e.UUID), encryptedPrefix))
+		read, _ = i.GetEvent(e.UUID)
+		assert.Equal(t, "unbound", read.Payload)
+	})
+
+	t.Run("dead-lettered scheduled message is not encrypted again", func(t *testing.T) {
+		msg := ScheduledMessage{Queue: "encryption", Sequence: 1, Subject: "encryptionOut", Data: "scheduled data", DueAt: now().Add(time.Hour)}
+		_ = i.Schedule(msg)
+		var messages []ScheduledMessage
+		i.Db.Where("queue = ?", "encryption").Find(&messages)
+		if !assert.Len(t, messages, 1) {
+			return
+		}
+
+		assert.NoError(t, i.deadLetterScheduled(messages[0], "test"))
+
+		var deadLetter *DeadLetter
+		assert.Eventually(t, func() bool {
+			deadLetter, _ = i.GetDeadLetter(messages[0].ID)
+			return deadLetter != nil
+		}, time.Second, 10*time.Millisecond)
+		if assert.NotNil(t, deadLetter) {
+			assert.Equal(t, "scheduled data", string(deadLetter.Data))
+		}
+	})
+
+	t.Run("dead letters stored in plain text are encrypted", func(t *testing.T) {
+		d := DeadLetter{ID: uuid.NewV4().String(), Subject: ChannelConsentRequest, Reason: "test", Data: []byte("plain dead letter"), CreatedAt: time.Now()}
+		_ = i.Db.Create(&d).Error
+
+		_, err := i.EncryptPayloads()
+
+		assert.NoError(t, err)
+		var stored [][]byte
+		i.Db.Table("dead_letters").Where("id = ?", d.ID).Pluck("data", &stored)
+		if assert.Len(t, stored, 1) {
+			assert.True(t, strings.HasPrefix(string(stored[0]), encryptedPrefix))
+		}
+		read, _ := i.GetDeadLetter(d.ID)
+		assert.Equal(t, "plain dead letter", string(read.Data))
+	})
+}
@@ -19,22 +19,25 @@
 package pkg
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/sqlite3"
 	bindata "github.com/golang-migrate/migrate/v4/source/go_bindata"
 	"github.com/jinzhu/gorm"
+	_ "github.com/lib/pq" // postgres driver for the event store and the Nats sql store
 	natsServer "github.com/nats-io/nats-streaming-server/server"
 	"github.com/nats-io/nats-streaming-server/stores"
 	natsClient "github.com/nats-io/stan.go"
 	"github.com/nuts-foundation/nuts-event-octopus/migrations"
 	core "github.com/nuts-foundation/nuts-go-core"
+	"github.com/prometheus/client_golang/prometheus"
 	uuid "github.com/satori/go.uuid"
 	"github.com/sirupsen/logrus"
 )
@@ -48,6 +51,9 @@ const ConfigNatsPort = "natsPort"
 // ConfigConnectionstring defines the string for the flagset
 const ConfigConnectionstring = "connectionstring"
 
+// ConfigDbDialect defines the string for the flagset, when empty the dialect is derived from the connectionstring
+const ConfigDbDialect = "dbDialect"
+
 // ConfigRetryIntervalDefault defines the default for the nats retryInterval
 const ConfigRetryIntervalDefault = 60
 
@@ -60,9 +66,25 @@ const ConfigConnectionStringDefault = "file::memory:?cache=shared"
 // ConfigAutoRecover is the config name for republishing unfinished events at startup
 const ConfigAutoRecover = "autoRecover"
 
-// ConfigPurgeCompleted is the config name for enabling purging completed events
+// ConfigPurgeCompleted is the config name for enabling purging completed events.
+// Deprecated: use a retention rule for EventCompleted
 const ConfigPurgeCompleted = "purgeCompleted"
 
+// ConfigRetentionRules is the config name for the rules removing events from the event store, see parseRetentionRules for the format
+const ConfigRetentionRules = "retentionRules"
+
+// ConfigRetentionInterval is the config name for the interval in seconds between enforcements of the retention rules
+const ConfigRetentionInterval = "retentionInterval"
+
+// ConfigRetentionIntervalDefault enforces the retention rules every hour
+const ConfigRetentionIntervalDefault = 3600
+
+// ConfigRetentionDryRun is the config name for only reporting the events the retention rules would remove
+const ConfigRetentionDryRun = "retentionDryRun"
+
+// ConfigReplayOnStart is the config name for rebuilding the event store from the entire event log at startup
+const ConfigReplayOnStart = "replayOnStart"
+
 // ConfigMaxRetryCount is the config name for the number of retries for an event
 const ConfigMaxRetryCount = "maxRetryCount"
 
@@ -75,6 +97,112 @@ const ConfigIncrementalBackoff = "incrementalBackoff"
 // ConfigIncrementalBackoffDefault is the default setting for the incremental backoff of retrying events
 const ConfigIncrementalBackoffDefault = 8
 
+// ConfigRetrySchedule is the config name for the comma separated delays of the retry queues, overrides maxRetryCount and incrementalBackoff
+const ConfigRetrySchedule = "retrySchedule"
+
+// ConfigRetryJitter is the config name for the max percentage of the delay randomly added to the delay of a retry
+const ConfigRetryJitter = "retryJitter"
+
+// ConfigRetryPolicies is the config name for the semicolon separated retry policies per event name and error class
+const ConfigRetryPolicies = "retryPolicies"
+
+// ConfigWebhookMaxRetryCount is the config name for the number of retries for a webhook delivery
+const ConfigWebhookMaxRetryCount = "webhookMaxRetryCount"
+
+// ConfigWebhookMaxRetryCountDefault is the default setting for the number of retries of webhook deliveries
+const ConfigWebhookMaxRetryCountDefault = 5
+
+// ConfigWebhookIncrementalBackoff is the config name for the incremental backoff of retrying webhook deliveries
+const ConfigWebhookIncrementalBackoff = "webhookIncrementalBackoff"
+
+// ConfigWebhookIncrementalBackoffDefault is the default setting for the incremental backoff of retrying webhook deliveries
+const ConfigWebhookIncrementalBackoffDefault = 8
+
+// ConfigNatsMonitorPort is the config name for the port of the monitoring endpoint of the embedded Nats streaming server,
+// 0 binds it to a free port on the loopback interface
+const ConfigNatsMonitorPort = "natsMonitorPort"
+
+// ConfigNatsMonitorPortDefault binds the monitoring endpoint to a free port, so it does not claim a port that may be in use
+const ConfigNatsMonitorPortDefault = 0
+
+// ConfigDrainTimeout is the config name for the number of seconds Shutdown waits for messages that are being handled
+const ConfigDrainTimeout = "drainTimeout"
+
+// ConfigDrainTimeoutDefault is the default drain timeout in seconds
+const ConfigDrainTimeoutDefault = 10
+
+// ConfigMaxStreams is the config name for the max number of concurrent event streams, 0 means unlimited
+const ConfigMaxStreams = "maxStreams"
+
+// ConfigMaxStreamsDefault is the default max number of concurrent event streams
+const ConfigMaxStreamsDefault = 100
+
+// ConfigCircuitBreakerThreshold is the config name for the number of consecutive failures that pause message processing, 0 never pauses
+const ConfigCircuitBreakerThreshold = "circuitBreakerThreshold"
+
+// ConfigCircuitBreakerThresholdDefault is the default number of consecutive failures that open the circuit breaker
+const ConfigCircuitBreakerThresholdDefault = 5
+
+// ConfigCircuitBreakerTimeout is the config name for the number of seconds message processing is paused by the circuit breaker
+const ConfigCircuitBreakerTimeout = "circuitBreakerTimeout"
+
+// ConfigCircuitBreakerTimeoutDefault is the default pause of the circuit breaker in seconds
+const ConfigCircuitBreakerTimeoutDefault = 30
+
+// ConfigNatsStoreType is the config name for the type of store used by the embedded Nats streaming server
+const ConfigNatsStoreType = "natsStoreType"
+
+// ConfigNatsStoreTypeDefault keeps all channels in memory
+const ConfigNatsStoreTypeDefault = "memory"
+
+// ConfigNatsFileStoreDir is the config name for the directory used by the Nats file store
+const ConfigNatsFileStoreDir = "natsFileStoreDir"
+
+// ConfigNatsFileStoreDirDefault is the default directory for the Nats file store
+const ConfigNatsFileStoreDirDefault = "./nats"
+
+// ConfigNatsSQLStoreDriver is the config name for the database driver used by the Nats SQL store
+const ConfigNatsSQLStoreDriver = "natsSQLStoreDriver"
+
+// ConfigNatsSQLStoreSource is the config name for the datasource used by the Nats SQL store
+const ConfigNatsSQLStoreSource = "natsSQLStoreSource"
+
+// ConfigNatsMaxMsgs is the config name for the max number of messages per channel
+const ConfigNatsMaxMsgs = "natsMaxMsgs"
+
+// ConfigNatsMaxBytes is the config name for the max number of bytes per channel
+const ConfigNatsMaxBytes = "natsMaxBytes"
+
+// ConfigNatsMaxAge is the config name for the max age in seconds of messages in a channel
+const ConfigNatsMaxAge = "natsMaxAge"
+
+// ConfigNatsURL is the config name for the comma separated list of external Nats servers
+const ConfigNatsURL = "natsURL"
+
+// ConfigNatsClusterID is the config name for the Nats streaming cluster ID
+const ConfigNatsClusterID = "natsClusterID"
+
+// ConfigNatsClusterIDDefault is the cluster ID of the embedded Nats streaming server
+const ConfigNatsClusterIDDefault = "nuts"
+
+// ConfigNatsNodeID is the config name for the ID used to make client IDs unique when sharing an external Nats cluster
+const ConfigNatsNodeID = "natsNodeID"
+
+// ConfigNatsCredentials is the config name for the credentials file used to connect to an external Nats cluster
+const ConfigNatsCredentials = "natsCredentials"
+
+// ConfigNatsTLSCert is the config name for the client certificate used to connect to an external Nats cluster
+const ConfigNatsTLSCert = "natsTLSCert"
+
+// ConfigNatsTLSKey is the config name for the client key used to connect to an external Nats cluster
+const ConfigNatsTLSKey = "natsTLSKey"
+
+// ConfigNatsTLSCA is the config name for the CA used to verify the servers of an external Nats cluster
+const ConfigNatsTLSCA = "natsTLSCA"
+
+// ConfigEncryptionKeyFile is the config name for the key file used to encrypt payloads in the event store, payloads are stored in plain text when empty
+const ConfigEncryptionKeyFile = "encryptionKeyFile"
+
 // Name is the name of this module
 const Name = "Events octopus"
 
@@ -83,13 +211,43 @@ const ClientID = "event-store"
 
 // EventOctopusConfig holds the config for the EventOctopusInstance
 type EventOctopusConfig struct {
-	RetryInterval      int
-	NatsPort           int
-	Connectionstring   string
-	AutoRecover        bool
-	PurgeCompleted     bool
-	MaxRetryCount      int
-	IncrementalBackoff int
+	RetryInterval             int
+	NatsPort                  int
+	NatsMonitorPort           int
+	Connectionstring          string
+	DbDialect                 string
+	AutoRecover               bool
+	PurgeCompleted            bool
+	ReplayOnStart             bool
+	MaxRetryCount             int
+	IncrementalBackoff        int
+	RetrySchedule             string
+	RetryJitter               int
+	RetryPolicies             string
+	WebhookMaxRetryCount      int
+	WebhookIncrementalBackoff int
+	NatsStoreType             string
+	NatsFileStoreDir          string
+	NatsSQLStoreDriver        string
+	NatsSQLStoreSource        string
+	NatsMaxMsgs               int
+	NatsMaxBytes              int64
+	NatsMaxAge                int
+	NatsURL                   string
+	NatsClusterID             string
+	NatsNodeID                string
+	NatsCredentials           string
+	NatsTLSCert               string
+	NatsTLSKey                string
+	NatsTLSCA                 string
+	EncryptionKeyFile         string
+	RetentionRules            string
+	RetentionInterval         int
+	DrainTimeout              int
+	MaxStreams                int
+	RetentionDryRun           bool
+	CircuitBreakerThreshold   int
+	CircuitBreakerTimeout     int
 }
 
 // GetMode derives the mode (from the global mode) the engine should run in
@@ -106,14 +264,19 @@ type IEventPublisher interface {
 // EventOctopusClient is the client interface for publishing events
 type EventOctopusClient interface {
 	EventPublisher(clientID string) (IEventPublisher, error)
-	Subscribe(service, subject string, callbacks map[string]EventHandlerCallback) error
+	Subscribe(service, subject string, callbacks map[string]EventHandlerCallback, opts ...SubscribeOption) error
+	SubscribeHandlers(service, subject string, handlers map[string]EventHandler, opts ...SubscribeOption) error
 	Diagnostics() []core.DiagnosticResult
 }
 
 // ChannelHandlers store all the handlers for a specific channel subscription
 type ChannelHandlers struct {
 	subscription natsClient.Subscription
-	handlers     map[string]EventHandlerCallback
+	handlers     map[string]EventHandler
+	options      subscribeOptions
+	checkpoints  *checkpointTracker
+	// cancel ends the context of the running handlers
+	cancel context.CancelFunc
 }
 
 // EventOctopus is the default implementation for EventOctopusInstance
@@ -122,18 +285,51 @@ type EventOctopus struct {
 	Config     EventOctopusConfig
 	configOnce sync.Once
 	stanServer *natsServer.StanServer
-	Db         *gorm.DB
-	sqlDb      *sql.DB
+	// memoryLogID identifies the event log of the embedded in-memory store, it is empty for the other stores
+	memoryLogID string
+	// monitorPort is the port of the monitoring endpoint of the embedded server
+	monitorPort int
+	Db          *gorm.DB
+	// KeyProvider encrypts the payloads in the event store, nil stores payloads in plain text
+	KeyProvider KeyProvider
+	sqlDb       *sql.DB
+	dialect     string
 	// Clients per service
 	stanClients     map[string]natsClient.Conn
 	channelHandlers map[string]map[string]ChannelHandlers
+	// Subscriptions of the event store
+	subscriptions []natsClient.Subscription
+	inFlight      inFlight
+	breaker       circuitBreaker
 	// Retry
+	retrySchedule    []time.Duration
+	retryPolicies    []RetryPolicy
 	delayedConsumers []*DelayedConsumer
+	schedulerWake    chan struct{}
+	schedulerStop    chan struct{}
+	schedulerDone    chan struct{}
+	// Webhook delivery retry
+	webhookConsumers []*DelayedConsumer
+	// Event streams by ID
+	streamMutex sync.Mutex
+	streams     map[int]context.CancelFunc
+	lastStream  int
+	// Replay of the event log
+	replayMutex  sync.Mutex
+	replayStatus ReplayStatus
+	// Retention
+	retentionMutex  sync.Mutex
+	retentionRules  []RetentionRule
+	retentionStatus RetentionStatus
+	purgeMutex      sync.Mutex
+	janitorStop     chan struct{}
+	// Metrics registry owned by this instance
+	metricsOnce sync.Once
+	metrics     *prometheus.Registry
 }
 
 var instance *EventOctopus
 var oneInstance = &sync.Once{}
-var mutex = sync.Mutex{}
 
 // EventOctopusInstance returns the EventOctopus singleton
 func EventOctopusInstance() *EventOctopus {
@@ -141,11 +337,22 @@ func EventOctopusInstance() *EventOctopus {
 		instance = &EventOctopus{
 			Name: Name,
 			Config: EventOctopusConfig{
-				RetryInterval:      ConfigRetryIntervalDefault,
-				NatsPort:           ConfigNatsPortDefault,
-				Connectionstring:   ConfigConnectionStringDefault,
-				MaxRetryCount:      ConfigMaxRetryCountDefault,
-				IncrementalBackoff: ConfigIncrementalBackoffDefault,
+				RetryInterval:             ConfigRetryIntervalDefault,
+				NatsPort:                  ConfigNatsPortDefault,
+				NatsMonitorPort:           ConfigNatsMonitorPortDefault,
+				Connectionstring:          ConfigConnectionStringDefault,
+				MaxRetryCount:             ConfigMaxRetryCountDefault,
+				IncrementalBackoff:        ConfigIncrementalBackoffDefault,
+				WebhookMaxRetryCount:      ConfigWebhookMaxRetryCountDefault,
+				WebhookIncrementalBackoff: ConfigWebhookIncrementalBackoffDefault,
+				NatsStoreType:             ConfigNatsStoreTypeDefault,
+				NatsFileStoreDir:          ConfigNatsFileStoreDirDefault,
+				NatsClusterID:             ConfigNatsClusterIDDefault,
+				RetentionInterval:         ConfigRetentionIntervalDefault,
+				DrainTimeout:              ConfigDrainTimeoutDefault,
+				MaxStreams:                ConfigMaxStreamsDefault,
+				CircuitBreakerThreshold:   ConfigCircuitBreakerThresholdDefault,
+				CircuitBreakerTimeout:     ConfigCircuitBreakerTimeoutDefault,
 			},
 			channelHandlers: make(map[string]map[string]ChannelHandlers),
 			stanClients:     make(map[string]natsClient.Conn),
@@ -154,34 +361,60 @@ func EventOctopusInstance() *EventOctopus {
 	return instance
 }
 
-// Subscribe lets you subscribe to events for a service and subject. For each Event.name you can provide a callback function
-func (octopus *EventOctopus) Subscribe(service, subject string, handlers map[string]EventHandlerCallback) error {
+// Subscribe lets you subscribe to events for a service and subject. For each Event.name you can provide a callback function.
+// Events are acked automatically and only events published after subscribing are delivered, like in earlier versions.
+// The SubscribeOptions change that, ManualAck and ResumeFromCheckpoint opt in to the delivery of SubscribeHandlers.
+func (octopus *EventOctopus) Subscribe(service, subject string, callbacks map[string]EventHandlerCallback, opts ...SubscribeOption) error {
+	handlers := make(map[string]EventHandler, len(callbacks))
+	for name, callback := range callbacks {
+		handlers[name] = callback.handler()
+	}
+
+	return octopus.subscribeHandlers(service, subject, handlers, newSubscribeOptions(opts))
+}
+
+// SubscribeHandlers lets you subscribe to events for a service and subject. For each Event.name you can provide a handler.
+// Events are acked after the handler returns, failed events are published to the retry or error channel.
+// A service that subscribes again resumes after the last event it processed. Otherwise only events published after subscribing
+// are delivered, the SubscribeOptions change that.
+func (octopus *EventOctopus) SubscribeHandlers(service, subject string, handlers map[string]EventHandler, opts ...SubscribeOption) error {
+	options := newSubscribeOptions(append([]SubscribeOption{ManualAck(), ResumeFromCheckpoint()}, opts...))
+
+	return octopus.subscribeHandlers(service, subject, handlers, options)
+}
+
+func (octopus *EventOctopus) subscribeHandlers(service, subject string, handlers map[string]EventHandler, options subscribeOptions) error {
 	// create a new ChannelHandler if it does not exists for the combination of service and subject
 	if channelHandlers, ok := octopus.channelHandlers[service][subject]; !ok {
 
+		ctx, cancel := context.WithCancel(context.Background())
 		channelHandlers := ChannelHandlers{
-			handlers: handlers,
+			handlers:    handlers,
+			options:     options,
+			checkpoints: newCheckpointTracker(),
+			cancel:      cancel,
 		}
 		stanClient, err := octopus.client(service)
 		if err != nil {
+			cancel()
 			return err
 		}
 
-		channelHandlers.subscription, _ = stanClient.Subscribe(subject, func(msg *natsClient.Msg) {
-			event := &Event{}
-			// Unmarshal JSON that represents the Order data
-			err := json.Unmarshal(msg.Data, &event)
-			if err != nil {
-				logrus.Errorf("Error unmarshalling event: %v", err)
-				return
-			}
-			handler := channelHandlers.handlers[event.Name]
-			if handler == nil {
-				logrus.Infof("Event without handler %v", event.Name)
-				return
+		if options.start == nil && options.resume {
+			if options.start, err = octopus.checkpointStart(service, subject); err != nil {
+				cancel()
+				return err
 			}
-			handler(event)
-		})
+		}
+
+		handler := channelHandlers
+		channelHandlers.subscription, err = options.subscribe(stanClient, subject, octopus.tracked(func(msg *natsClient.Msg) {
+			octopus.handleMsg(ctx, service, handler, msg)
+		}))
+		if err != nil {
+			cancel()
+			return err
+		}
 		// does the inner map exists?
 		if _, ok := octopus.channelHandlers[service]; !ok {
 			octopus.channelHandlers[service] = make(map[string]ChannelHandlers)
@@ -196,7 +429,8 @@ func (octopus *EventOctopus) Subscribe(service, subject string, handlers map[str
 	return nil
 }
 
-// Unsubscribe from a service and subject. If no subjects for a service are left, it closes the stanClient
+// Unsubscribe from a service and subject. If no subjects for a service are left, it closes the stanClient.
+// The position of a durable subscription is removed as well.
 func (octopus *EventOctopus) Unsubscribe(service, subject string) error {
 	handlers, ok := octopus.channelHandlers[service][subject]
 	if !ok {
@@ -206,6 +440,7 @@ func (octopus *EventOctopus) Unsubscribe(service, subject string) error {
 	if err := handlers.subscription.Unsubscribe(); err != nil {
 		return err
 	}
+	handlers.cancel()
 	// delete subject from channelHandlers
 	delete(octopus.channelHandlers[service], subject)
 
@@ -227,6 +462,7 @@ type natsDiagnosticsResult struct {
 	up        bool
 	natsMode  string
 	natsPort  int
+	natsURL   string
 	stanID    string
 	lastError error
 }
@@ -236,6 +472,17 @@ func (ndr natsDiagnosticsResult) Name() string {
 	return "Nats streaming server"
 }
 
+// address returns the address of the connected server
+func (ndr natsDiagnosticsResult) address() string {
+	if ndr.natsURL != "" {
+		return ndr.natsURL
+	}
+	if ndr.natsPort == 0 {
+		return ""
+	}
+	return fmt.Sprintf("0.0.0.0:%d", ndr.natsPort)
+}
+
 // String returns the outcome of the natsDiagnosticsResult
 func (ndr natsDiagnosticsResult) String() string {
 	if !ndr.up {
@@ -247,6 +494,10 @@ func (ndr natsDiagnosticsResult) String() string {
 		lastError = ndr.lastError.Error()
 	}
 
+	if ndr.natsURL != "" {
+		return fmt.Sprintf("mode: %s @ %s, ID: %s, last error: %s", ndr.natsMode, ndr.natsURL, ndr.stanID, lastError)
+	}
+
 	return fmt.Sprintf("mode: %s @ 0.0.0.0:%d, ID: %s, last error: %s", ndr.natsMode, ndr.natsPort, ndr.stanID, lastError)
 }
 
@@ -264,34 +515,43 @@ func (ddr dbDiagnosticResult) String() string {
 	return fmt.Sprintf("ping: false, error: %v", ddr.pingError)
 }
 
-// Diagnostics returns diagnostic reports from the nats streaming service and the DB
+// Diagnostics returns diagnostic reports from the nats streaming service, its channels and the DB
 func (octopus *EventOctopus) Diagnostics() []core.DiagnosticResult {
-	var (
-		stanState core.DiagnosticResult
-		dbState   core.DiagnosticResult
-	)
+	checkpoints, err := octopus.Checkpoints()
+
+	return []core.DiagnosticResult{
+		octopus.natsDiagnostics(),
+		dbDiagnosticResult{
+			pingError: octopus.sqlDb.Ping(),
+		},
+		checkpointDiagnosticResult{
+			checkpoints: checkpoints,
+			err:         err,
+		},
+		octopus.channelDiagnostics(),
+		octopus.eventStateDiagnostics(),
+		octopus.retryQueueDiagnostics(),
+		octopus.CircuitBreakerStatus(),
+	}
+}
+
+func (octopus *EventOctopus) natsDiagnostics() natsDiagnosticsResult {
+	if !octopus.Config.embedded() {
+		return octopus.externalNatsDiagnostics()
+	}
 
 	if octopus.stanServer == nil {
-		stanState = natsDiagnosticsResult{
+		return natsDiagnosticsResult{
 			up: false,
 		}
-	} else {
-		stanState = natsDiagnosticsResult{
-			up:        true,
-			natsMode:  octopus.stanServer.State().String(),
-			natsPort:  octopus.Config.NatsPort,
-			stanID:    octopus.stanServer.ClusterID(),
-			lastError: octopus.stanServer.LastError(),
-		}
 	}
 
-	dbState = dbDiagnosticResult{
-		pingError: octopus.sqlDb.Ping(),
-	}
-
-	return []core.DiagnosticResult{
-		stanState,
-		dbState,
+	return natsDiagnosticsResult{
+		up:        true,
+		natsMode:  octopus.stanServer.State().String(),
+		natsPort:  octopus.Config.NatsPort,
+		stanID:    octopus.stanServer.ClusterID(),
+		lastError: octopus.stanServer.LastError(),
 	}
 }
 
@@ -309,15 +569,51 @@ func (octopus *EventOctopus) Configure() error {
 }
 
 func (octopus *EventOctopus) configure() error {
+	if octopus.Config.GetMode() != core.ServerEngineMode {
+		return nil
+	}
+
+	if err := octopus.loadKeyProvider(); err != nil {
+		return err
+	}
+
+	if err := octopus.configureRetrySchedule(); err != nil {
+		return err
+	}
+
+	if err := octopus.configureRetryPolicies(); err != nil {
+		return err
+	}
+
+	if err := octopus.configureRetention(); err != nil {
+		return err
+	}
+
+	if err := octopus.configureCircuitBreaker(); err != nil {
+		return err
+	}
+
+	return octopus.openSQLDb()
+}
+
+// openSQLDb connects to the event store and brings its schema up to date
+func (octopus *EventOctopus) openSQLDb() error {
 	var (
 		err error
 	)
 
-	if octopus.Config.GetMode() != core.ServerEngineMode {
-		return nil
+	octopus.dialect, err = octopus.Config.dialect()
+	if err != nil {
+		return err
+	}
+
+	connectionString := octopus.Config.Connectionstring
+	if octopus.dialect == DialectSqlite && !sqliteInMemory(connectionString) {
+		connectionString = sqliteConnectionString(connectionString)
 	}
 
-	octopus.sqlDb, err = sql.Open("sqlite3", octopus.Config.Connectionstring)
+	// the dialects are named after their database/sql driver
+	octopus.sqlDb, err = sql.Open(octopus.dialect, connectionString)
 
 	if err != nil {
 		return err
@@ -338,25 +634,53 @@ func (octopus *EventOctopus) configure() error {
 	return nil
 }
 
-// RunMigrations runs all new migrations in order
-func (octopus *EventOctopus) RunMigrations(db *sql.DB) error {
-	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
+// openDb opens the gorm connection on top of the connection to the event store
+func (octopus *EventOctopus) openDb() error {
+	var err error
 
-	// wrap assets into Resource
-	s := bindata.Resource(migrations.AssetNames(),
-		func(name string) ([]byte, error) {
-			return migrations.Asset(name)
-		})
+	// gorm db connection
+	if octopus.Db, err = gorm.Open(octopus.dialect, octopus.sqlDb); err != nil {
+		return err
+	}
 
-	d, err := bindata.WithInstance(s)
+	// logging
+	octopus.Db.SetLogger(logrus.StandardLogger())
 
-	if err != nil {
-		return err
+	// timestamps are stored in UTC so they can be compared as text by SQLite
+	octopus.Db.SetNowFuncOverride(now)
+
+	// the connections to an in-memory SQLite DB share a cache that fails concurrent writes instead of waiting, its writers are serialised
+	if octopus.dialect == DialectSqlite && sqliteInMemory(octopus.Config.Connectionstring) {
+		octopus.sqlDb.SetMaxOpenConns(1)
+	}
+
+	return nil
+}
+
+// connectDb opens the event store for commands that run without starting the engine
+func (octopus *EventOctopus) connectDb() error {
+	if octopus.KeyProvider == nil {
+		if err := octopus.loadKeyProvider(); err != nil {
+			return err
+		}
+	}
+
+	if octopus.sqlDb == nil {
+		if err := octopus.openSQLDb(); err != nil {
+			return err
+		}
 	}
 
-	// run migrations
-	m, err := migrate.NewWithInstance("go-bindata", d, "test", driver)
+	if octopus.Db == nil {
+		return octopus.openDb()
+	}
 
+	return nil
+}
+
+// RunMigrations runs all new migrations for the configured dialect in order
+func (octopus *EventOctopus) RunMigrations(db *sql.DB) error {
+	m, err := octopus.migrator(db)
 	if err != nil {
 		return err
 	}
@@ -372,32 +696,123 @@ func (octopus *EventOctopus) RunMigrations(db *sql.DB) error {
 	return nil
 }
 
+// migrator returns the migrations for the configured dialect
+func (octopus *EventOctopus) migrator(db *sql.DB) (*migrate.Migrate, error) {
+	dialect, err := octopus.Config.dialect()
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := migrationDriver(dialect, db)
+	if err != nil {
+		return nil, err
+	}
+
+	// migrations are packaged per dialect
+	names, err := migrations.AssetDir(dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	// wrap assets into Resource
+	s := bindata.Resource(names,
+		func(name string) ([]byte, error) {
+			return migrations.Asset(dialect + "/" + name)
+		})
+
+	d, err := bindata.WithInstance(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithInstance("go-bindata", d, "test", driver)
+}
+
 func (octopus *EventOctopus) startStanServer() error {
 	opts := natsServer.GetDefaultOptions()
 	opts.Debug = false
 	opts.Trace = false
-	//opts.StoreType = stores.TypeFile
-	opts.StoreType = stores.TypeMemory
-	opts.FilestoreDir = "./temp"
-	opts.ID = "nuts"
+	opts.ID = octopus.Config.clusterID()
+
+	if err := octopus.Config.applyStoreOptions(opts); err != nil {
+		return err
+	}
 
 	sopts := natsServer.DefaultNatsServerOptions
 	sopts.Host = "0.0.0.0"
 	sopts.Port = octopus.Config.NatsPort
-
 	var err error
 
+	// the channel statistics and the end of the event log are read from the monitoring endpoint
+	monitorPort := octopus.Config.NatsMonitorPort
+	if monitorPort <= 0 {
+		if monitorPort, err = freePort(); err != nil {
+			return fmt.Errorf("Unable to find a port for the Nats monitoring endpoint: %w", err)
+		}
+	}
+	sopts.HTTPHost = monitorHost
+	sopts.HTTPPort = monitorPort
+
 	octopus.stanServer, err = natsServer.RunServerWithOpts(opts, &sopts)
 	if err != nil {
 		return fmt.Errorf("Unable to start Nats-streaming server: %w", err)
 	}
+	octopus.monitorPort = monitorPort
 	octopus.stanServer.ClusterID()
 
-	logrus.Infof("Stan server started at %s:%d with ID: %v", sopts.Host, sopts.Port, octopus.stanServer.ClusterID())
+	// the in-memory store starts a new event log, checkpoints of the previous log don't apply to it
+	octopus.memoryLogID = ""
+	if opts.StoreType == stores.TypeMemory {
+		octopus.memoryLogID = fmt.Sprintf("%s-%s", opts.ID, uuid.NewV4().String())
+	}
+
+	logrus.Infof("Stan server started at %s:%d with ID: %v and %s store", sopts.Host, sopts.Port, octopus.stanServer.ClusterID(), opts.StoreType)
 
 	return err
 }
 
+// applyStoreOptions sets the store type and channel limits for the embedded Nats streaming server
+func (c EventOctopusConfig) applyStoreOptions(opts *natsServer.Options) error {
+	storeType := c.NatsStoreType
+	if storeType == "" {
+		storeType = ConfigNatsStoreTypeDefault
+	}
+
+	switch strings.ToUpper(storeType) {
+	case stores.TypeMemory:
+		opts.StoreType = stores.TypeMemory
+	case stores.TypeFile:
+		if c.NatsFileStoreDir == "" {
+			return fmt.Errorf("%s is required for the file store", ConfigNatsFileStoreDir)
+		}
+		opts.StoreType = stores.TypeFile
+		opts.FilestoreDir = c.NatsFileStoreDir
+	case stores.TypeSQL:
+		if c.NatsSQLStoreDriver == "" || c.NatsSQLStoreSource == "" {
+			return fmt.Errorf("%s and %s are required for the sql store", ConfigNatsSQLStoreDriver, ConfigNatsSQLStoreSource)
+		}
+		opts.StoreType = stores.TypeSQL
+		opts.SQLStoreOpts.Driver = c.NatsSQLStoreDriver
+		opts.SQLStoreOpts.Source = c.NatsSQLStoreSource
+	default:
+		return fmt.Errorf("unknown Nats store type: %s", storeType)
+	}
+
+	// 0 keeps the Nats default
+	if c.NatsMaxMsgs > 0 {
+		opts.MaxMsgs = c.NatsMaxMsgs
+	}
+	if c.NatsMaxBytes > 0 {
+		opts.MaxBytes = c.NatsMaxBytes
+	}
+	if c.NatsMaxAge > 0 {
+		opts.MaxAge = time.Duration(c.NatsMaxAge) * time.Second
+	}
+
+	return nil
+}
+
 // Start starts the receiver socket in a go routine
 func (octopus *EventOctopus) Start() error {
 	var err error
@@ -406,17 +821,22 @@ func (octopus *EventOctopus) Start() error {
 		return nil
 	}
 
-	// gorm db connection
-	if octopus.Db, err = gorm.Open("sqlite3", octopus.sqlDb); err != nil {
+	if err = octopus.openDb(); err != nil {
 		return err
 	}
 
-	// logging
-	octopus.Db.SetLogger(logrus.StandardLogger())
+	// natsServer startup, unless an external Nats cluster is used
+	if octopus.Config.embedded() {
+		if err = octopus.startStanServer(); err != nil {
+			return err
+		}
+	}
 
-	// natsServer startup
-	if err = octopus.startStanServer(); err != nil {
-		return err
+	// rebuild the event store before new events are processed
+	if octopus.Config.ReplayOnStart {
+		if err = octopus.Replay(); err != nil {
+			return err
+		}
 	}
 
 	// event store client
@@ -430,11 +850,7 @@ func (octopus *EventOctopus) Start() error {
 		}
 	}
 
-	if octopus.Config.PurgeCompleted {
-		if err := octopus.purgeCompleted(); err != nil {
-			return err
-		}
-	}
+	octopus.startJanitor()
 
 	return nil
 }
@@ -445,11 +861,7 @@ func (octopus *EventOctopus) client(clientID string) (natsClient.Conn, error) {
 		return client, nil
 	}
 
-	client, err := natsClient.Connect(
-		"nuts",
-		clientID,
-		natsClient.NatsURL(fmt.Sprintf("nats://localhost:%d", octopus.Config.NatsPort)),
-	)
+	client, err := octopus.Config.connect(clientID)
 	if err == nil {
 		octopus.stanClients[clientID] = client
 	}
@@ -467,7 +879,12 @@ func (p EventPublisher) Publish(subject string, event Event) error {
 	if err != nil {
 		return err
 	}
-	return p.conn.Publish(subject, data)
+	if err := p.conn.Publish(subject, data); err != nil {
+		return err
+	}
+
+	eventsPublished.WithLabelValues(subject, eventLabel(event.Name)).Inc()
+	return nil
 }
 
 // EventPublisher gets a connection and creates a new EventPublisher
@@ -480,18 +897,19 @@ func (octopus *EventOctopus) EventPublisher(clientID string) (IEventPublisher, e
 }
 
 func (octopus *EventOctopus) startSubscribers() error {
-	logrus.Tracef("Connecting to Stan-Streaming server @ nats://localhost:%d", octopus.Config.NatsPort)
+	logrus.Tracef("Connecting to Stan-Streaming server @ %s", octopus.Config.natsURL())
 
 	sc, err := octopus.client(ClientID)
 	if err != nil {
 		return err
 	}
 	// Subscribe to main subject
-	_, err = sc.Subscribe(ChannelConsentRequest, func(msg *natsClient.Msg) {
-		event := octopus.saveMsgAsEvent(msg.Data)
+	err = octopus.subscribe(sc, ChannelConsentRequest, func(msg *natsClient.Msg) error {
+		event, err := octopus.saveMsgAsEvent(msg)
 
 		// Handle the message
 		logrus.Debugf("received event [%d]: %+v\n", msg.Sequence, event)
+		return err
 	}, natsClient.DurableName("consent-request-durable"),
 		natsClient.StartWithLastReceived(),
 	)
@@ -500,11 +918,12 @@ func (octopus *EventOctopus) startSubscribers() error {
 	}
 
 	// Subscribe to error subject
-	_, err = sc.Subscribe(ChannelConsentErrored, func(msg *natsClient.Msg) {
-		event := octopus.saveMsgAsEvent(msg.Data)
+	err = octopus.subscribe(sc, ChannelConsentErrored, func(msg *natsClient.Msg) error {
+		event, err := octopus.saveMsgAsEvent(msg)
 
 		// Handle the message
 		logrus.Debugf("received error event [%d]: %+v\n", msg.Sequence, event)
+		return err
 	}, natsClient.DurableName("consent-request-error-durable"),
 		natsClient.StartWithLastReceived(),
 	)
@@ -513,31 +932,31 @@ func (octopus *EventOctopus) startSubscribers() error {
 	}
 
 	// Subscribe to retry subject
-	_, err = sc.Subscribe(ChannelConsentRetry, func(msg *natsClient.Msg) {
+	err = octopus.subscribe(sc, ChannelConsentRetry, func(msg *natsClient.Msg) error {
 		event := Event{}
 
 		err := json.Unmarshal(msg.Data, &event)
 		if err != nil {
 			logrus.WithError(err).Errorf("Error unmarshalling event")
-			octopus.saveMsgAsErrored(msg.Data, err.Error())
-
-			return
+			return octopus.deadLetter(msg, err.Error())
 		}
+		eventsConsumed.WithLabelValues(msg.Subject, eventLabel(event.Name)).Inc()
 
-		if event.RetryCount >= octopus.Config.MaxRetryCount {
-			event.Name = EventErrored
-			errStr := "max retry count reached"
-			event.Error = &errStr
-			octopus.SaveOrUpdateEvent(event)
+		if terminal, ok := octopus.exhausted(event); ok {
+			// a redelivery stores the terminal state again, which is rejected
+			if err := octopus.saveEvent(terminal, ChannelConsentRetry); err != nil && !errors.Is(err, ErrInvalidTransition) {
+				return fmt.Errorf("Error while storing event %s: %w", event.UUID, err)
+			}
 
-			return
+			return octopus.deadLetter(msg, errMaxRetryCount)
 		}
 
 		if err := octopus.publishEventToRetryChannel(event); err != nil {
-			logrus.WithError(err).Fatal("failed to publish message to retry channel")
+			return fmt.Errorf("Error while publishing event %s to retry channel: %w", event.UUID, err)
 		}
 
 		logrus.Debugf("received retry event [%d]: %+v\n", msg.Sequence, event)
+		return nil
 	}, natsClient.DurableName("consent-request-retry-durable"),
 		natsClient.StartWithLastReceived(),
 	)
@@ -545,15 +964,24 @@ func (octopus *EventOctopus) startSubscribers() error {
 		return err
 	}
 
-	// subscribe retry channels
-	octopus.delayedConsumers = NewDelayedConsumerSet(ChannelConsentRetry, ChannelConsentRequest, octopus.Config.MaxRetryCount, time.Second, octopus.Config.IncrementalBackoff, sc)
+	// subscribe retry channels, their messages are stored until they are due
+	octopus.startScheduler(sc)
+	octopus.delayedConsumers = octopus.retryConsumers(sc)
 	for _, dc := range octopus.delayedConsumers {
 		if err := dc.Start(); err != nil {
 			return err
 		}
 	}
 
-	logrus.Infof("Connected to Stan-Streaming server @ nats://localhost:%d", octopus.Config.NatsPort)
+	if err := octopus.startDeadLetterSubscriber(sc); err != nil {
+		return err
+	}
+
+	if err := octopus.startWebhookSubscribers(sc); err != nil {
+		return err
+	}
+
+	logrus.Infof("Connected to Stan-Streaming server @ %s", octopus.Config.natsURL())
 
 	return err
 }
@@ -564,7 +992,7 @@ func (octopus *EventOctopus) publishEventToRetryChannel(event Event) error {
 		return err
 	}
 
-	channel := fmt.Sprintf("%s-%d", ChannelConsentRetry, event.RetryCount)
+	channel := fmt.Sprintf("%s-%d", octopus.retryPolicy(event).subject(), event.RetryCount)
 	event.RetryCount++
 
 	eventBytes, err := json.Marshal(event)
@@ -572,14 +1000,13 @@ func (octopus *EventOctopus) publishEventToRetryChannel(event Event) error {
 		return err
 	}
 
-	// publish async otherwise we'll be waiting for the retry procedure to ack
-	_, err = conn.PublishAsync(channel, eventBytes, func(s string, e error) {
-		if e != nil {
-			logrus.WithError(err).Error("did not recieve ack for message published to retry queue")
-		}
-	})
+	// the retry message is only acked after Nats has stored the event on the retry channel, a failure leaves it for redelivery
+	if err := conn.Publish(channel, eventBytes); err != nil {
+		return err
+	}
 
-	return err
+	eventsPublished.WithLabelValues(channel, eventLabel(event.Name)).Inc()
+	return nil
 }
 
 func (octopus *EventOctopus) publishEventToChannel(event Event, channel string) error {
@@ -593,49 +1020,55 @@ func (octopus *EventOctopus) publishEventToChannel(event Event, channel string)
 		return err
 	}
 
-	// publish async otherwise we'll be waiting for the retry procedure to ack
-	return conn.Publish(channel, eventBytes)
+	if err := conn.Publish(channel, eventBytes); err != nil {
+		return err
+	}
+
+	eventsPublished.WithLabelValues(channel, eventLabel(event.Name)).Inc()
+	return nil
 }
 
-func (octopus *EventOctopus) saveMsgAsEvent(data []byte) Event {
+// saveMsgAsEvent stores the event in the message, messages that are not an event are dead-lettered.
+// Rejected events are not an error, other errors leave the message for redelivery.
+func (octopus *EventOctopus) saveMsgAsEvent(msg *natsClient.Msg) (Event, error) {
 	event := Event{}
 
-	err := json.Unmarshal(data, &event)
+	err := json.Unmarshal(msg.Data, &event)
 	if err != nil {
 		logrus.WithError(err).Errorf("Error unmarshalling event")
-		return octopus.saveMsgAsErrored(data, err.Error())
-	}
-
-	if err := octopus.SaveOrUpdateEvent(event); err != nil {
-		logrus.WithError(err).Fatal("could not store event")
-	}
-
-	return event
-}
-
-func (octopus *EventOctopus) saveMsgAsErrored(bytes []byte, msg string) Event {
-	event := Event{
-		InitiatorLegalEntity: "unknown",
-		Error:                &msg,
-		ExternalID:           "unknown",
-		Payload:              string(bytes),
-		RetryCount:           0,
-		Name:                 EventErrored,
-		UUID:                 uuid.NewV4().String(),
+		return event, octopus.deadLetter(msg, err.Error())
 	}
+	eventsConsumed.WithLabelValues(msg.Subject, eventLabel(event.Name)).Inc()
 
-	// go through transaction
-	if err := octopus.SaveOrUpdateEvent(event); err != nil {
-		logrus.WithError(err).Fatal("could not store errored event")
+	err = octopus.saveEvent(event, msg.Subject)
+	if errors.Is(err, ErrInvalidTransition) {
+		logrus.WithError(err).Warnf("Rejected event %s", event.UUID)
+	} else if err != nil {
+		return event, fmt.Errorf("Error while storing event %s: %w", event.UUID, err)
 	}
 
-	return event
+	return event, nil
 }
 
-// Shutdown closes the connection to the DB and the natsServer server
+// Shutdown stops receiving messages and waits up to drainTimeout seconds for the messages being handled and the
+// publications awaiting an ack. Then it closes the connections to Nats, the natsServer server and the DB.
+// An error is returned when messages were still in flight after the drain timeout.
 func (octopus *EventOctopus) Shutdown() error {
 	var err error
 
+	octopus.stopJanitor()
+	octopus.closeStreams()
+	octopus.closeSubscriptions()
+	octopus.stopScheduler()
+
+	timeout := time.Duration(octopus.Config.DrainTimeout) * time.Second
+	if abandoned := octopus.inFlight.wait(timeout); abandoned > 0 {
+		logrus.Warnf("Abandoning %d messages still in flight after the drain timeout of %s", abandoned, timeout)
+		err = fmt.Errorf("%d messages still in flight after the drain timeout of %s", abandoned, timeout)
+	}
+	octopus.cancelHandlers()
+	octopus.closeClients()
+
 	if octopus.stanServer != nil {
 		octopus.stanServer.Shutdown()
 	}
@@ -650,13 +1083,41 @@ func (octopus *EventOctopus) Shutdown() error {
 	return err
 }
 
-// List returns all current events from Db
-func (octopus *EventOctopus) List() (*[]Event, error) {
+// List returns all current events from Db.
+// The events are sorted on the given fields (SortCreatedAt, SortUpdatedAt or SortTransitionedAt), a field prefixed with a '-' sorts descending.
+func (octopus *EventOctopus) List(sort ...string) (*[]Event, error) {
 	events := &[]Event{}
 
-	err := octopus.Db.Debug().Find(events).Error
+	query := octopus.Db.Debug()
+	for _, s := range sort {
+		column, direction, err := sortColumn(s)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Order(fmt.Sprintf("%s %s", column, direction))
+	}
+
+	if err := query.Find(events).Error; err != nil {
+		return nil, err
+	}
+
+	return events, octopus.decryptEvents(*events)
+}
+
+// sortColumn returns the db column and direction (ASC or DESC) for a sort field
+func sortColumn(sort string) (string, string, error) {
+	direction := "ASC"
+	if strings.HasPrefix(sort, "-") {
+		direction = "DESC"
+		sort = sort[1:]
+	}
+
+	column, ok := sortColumns[sort]
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s", ErrUnknownSortField, sort)
+	}
 
-	return events, err
+	return column, direction, nil
 }
 
 // GetEvent returns single event or not based on given uuid
@@ -673,6 +1134,10 @@ func (octopus *EventOctopus) GetEvent(uuid string) (*Event, error) {
 		return nil, err
 	}
 
+	if err := octopus.decryptEvent(event); err != nil {
+		return nil, err
+	}
+
 	return event, err
 }
 
@@ -690,16 +1155,71 @@ func (octopus *EventOctopus) GetEventByExternalID(externalID string) (*Event, er
 		return nil, err
 	}
 
+	if err := octopus.decryptEvent(event); err != nil {
+		return nil, err
+	}
+
 	return event, err
 }
 
+// GetEventHistory returns all state transitions of an event in order of occurrence
+func (octopus *EventOctopus) GetEventHistory(uuid string) ([]EventHistory, error) {
+	var history []EventHistory
+
+	err := octopus.Db.Debug().Where("event_uuid = ?", uuid).Order("id").Find(&history).Error
+
+	return history, err
+}
+
 // SaveOrUpdateEvent saves or update the event in the store.
+// An event with a version is only updated when the stored event still has that version, otherwise a ConflictError is returned.
+// An event without a version (0) overwrites the stored event.
 func (octopus *EventOctopus) SaveOrUpdateEvent(event Event) error {
+	return octopus.storeEvent(event, "", event.Version)
+}
+
+// saveEvent saves or updates the event and appends the transition to the event history.
+// The channel records where the event came from, it's empty when not received through Nats.
+// The version of the event is ignored, the last message for an event wins.
+func (octopus *EventOctopus) saveEvent(event Event, channel string) error {
+	return octopus.storeEvent(event, channel, 0)
+}
+
+// storeEvent stores the event when the stored event has the expected version, 0 stores the event regardless of its version.
+// Without an expected version, the event is stored again when another writer changed it in the meantime.
+func (octopus *EventOctopus) storeEvent(event Event, channel string, version int) error {
+	var (
+		transitioned bool
+		err          error
+	)
+
+	start := time.Now()
+	for i := 0; i < maxConflictRetries; i++ {
+		transitioned, err = octopus.updateEvent(&event, channel, version)
+		if version != 0 || !errors.Is(err, ErrConflict) {
+			break
+		}
+		logrus.WithError(err).Debugf("Storing event %s again", event.UUID)
+	}
+	observeSave(start, err)
+
+	if err != nil {
+		return err
+	}
+
+	// a failing notification does not undo the state change, replayed events have been notified before
+	if transitioned && channel != SourceReplay {
+		if err := octopus.notifyWebhooks(event); err != nil {
+			logrus.WithError(err).Errorf("Failed to notify webhooks of event %s", event.UUID)
+		}
+	}
 
-	// sqlite is giving problems
-	mutex.Lock()
-	defer mutex.Unlock()
+	return nil
+}
 
+// updateEvent creates the event or updates it when the stored version has not changed since it was read.
+// It returns true when the event changed state.
+func (octopus *EventOctopus) updateEvent(event *Event, channel string, version int) (bool, error) {
 	// start transaction
 	tx := octopus.Db.Begin()
 	defer func() {
@@ -710,26 +1230,151 @@ func (octopus *EventOctopus) SaveOrUpdateEvent(event Event) error {
 
 	if err := tx.Error; err != nil {
 		tx.Rollback()
-		return err
+		return false, err
+	}
+
+	current := Event{}
+	err := tx.Debug().Where("uuid = ?", event.UUID).First(&current).Error
+	exists := err == nil
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		tx.Rollback()
+		return false, err
+	}
+
+	// the version of an event that does not exist is 0
+	if version != 0 && version != current.Version {
+		tx.Rollback()
+		return false, ConflictError{UUID: event.UUID, Version: version}
+	}
+
+	// an invalid transition does not change the event, it is only recorded in the history.
+	// A manual retry moves an event back to its state before the terminal state of a retry policy.
+	if exists && channel != SourceManualRetry && !ValidTransition(current.Name, event.Name) {
+		return false, octopus.rejectTransition(tx, current, *event, channel)
+	}
+
+	// timestamps sent along by publishers are ignored
+	now := now()
+	event.UpdatedAt = now
+	event.Version = current.Version + 1
+	if exists {
+		event.CreatedAt = current.CreatedAt
+		event.TransitionedAt = current.TransitionedAt
+	} else {
+		event.CreatedAt = now
+	}
+	transitioned := !exists || event.Name != current.Name || event.TransitionedAt.IsZero()
+	if transitioned {
+		event.TransitionedAt = now
+	}
+
+	// the payload is only encrypted in the event store, the event itself keeps the plain text
+	stored := *event
+	if stored.Payload, err = octopus.encryptPayload(event.Payload, event.UUID); err != nil {
+		tx.Rollback()
+		return false, err
 	}
 
-	// actual query
-	target := &Event{}
-	// When using real DB:
-	// err := eo.Db.Set("gorm:query_option", "FOR UPDATE").Where("uuid = ?", event.UUID).First(&target).Error
-	err := octopus.Db.Debug().Where("uuid = ?", event.UUID).First(&target).Error
+	if exists {
+		err = octopus.compareAndSwap(tx, stored, current.Version)
+	} else if err = tx.Debug().Create(&stored).Error; err != nil {
+		tx.Rollback()
+		// another writer created the event first
+		if stored, _ := octopus.GetEvent(event.UUID); stored != nil {
+			return false, ConflictError{UUID: event.UUID}
+		}
+		return false, err
+	}
+
+	if err == nil {
+		err = tx.Debug().Create(&EventHistory{
+			UUID:       event.UUID,
+			Name:       event.Name,
+			RetryCount: event.RetryCount,
+			Error:      event.Error,
+			Channel:    channel,
+		}).Error
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return false, err
+	}
+
+	return transitioned, nil
+}
+
+// compareAndSwap updates the stored event if it still has the given version
+func (octopus *EventOctopus) compareAndSwap(tx *gorm.DB, event Event, version int) error {
+	result := tx.Debug().Model(&Event{}).Where("uuid = ? AND version = ?", event.UUID, version).UpdateColumns(map[string]interface{}{
+		"name":                   event.Name,
+		"retry_count":            event.RetryCount,
+		"transaction_id":         event.TransactionID,
+		"initiator_legal_entity": event.InitiatorLegalEntity,
+		"external_id":            event.ExternalID,
+		"consent_id":             event.ConsentID,
+		"payload":                event.Payload,
+		"error":                  event.Error,
+		"error_class":            event.ErrorClass,
+		"updated_at":             event.UpdatedAt,
+		"transitioned_at":        event.TransitionedAt,
+		"version":                event.Version,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ConflictError{UUID: event.UUID, Version: version}
+	}
+
+	return nil
+}
+
+// rejectTransition appends the rejected state to the event history and commits the transaction.
+// A rejection is recorded once, redeliveries of the rejected message and replayed messages behind the state of the event are not recorded.
+func (octopus *EventOctopus) rejectTransition(tx *gorm.DB, current Event, event Event, channel string) error {
+	reason := fmt.Errorf("%w from %s to %s", ErrInvalidTransition, current.Name, event.Name)
+	errStr := reason.Error()
+
+	if channel == SourceReplay {
+		tx.Rollback()
+		return reason
+	}
 
-	// TODO, check if event has to be overwritten!!!!
-	if err == nil || gorm.IsRecordNotFoundError(err) {
-		err = octopus.Db.Debug().Save(&event).Error
+	// the reason holds both states
+	count := 0
+	err := tx.Debug().Model(&EventHistory{}).Where("event_uuid = ? AND rejected = ? AND error = ?", event.UUID, true, errStr).Count(&count).Error
+	if err != nil || count > 0 {
+		tx.Rollback()
+		if err != nil {
+			return err
+		}
+		return reason
 	}
 
+	err = tx.Debug().Create(&EventHistory{
+		UUID:       event.UUID,
+		Name:       event.Name,
+		RetryCount: event.RetryCount,
+		Error:      &errStr,
+		Channel:    channel,
+		Rejected:   true,
+	}).Error
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	return reason
 }
 
 // recover creates a map from event.UUID to event.Name
@@ -772,18 +1417,9 @@ func (octopus *EventOctopus) recover() error {
 }
 
 func (octopus *EventOctopus) allEvents() (events []Event, err error) {
-	err = octopus.Db.Debug().Find(&events).Error
-
-	return events, err
-}
-
-// purgeCompleted removes all events from the DB with name == Completed
-func (octopus *EventOctopus) purgeCompleted() error {
-	if err := octopus.Db.Debug().Delete(Event{}, "name = ?", EventCompleted).Error; err != nil {
-		return err
+	if err = octopus.Db.Debug().Find(&events).Error; err != nil {
+		return nil, err
 	}
 
-	logrus.Infof("Event DB purged from completed events")
-
-	return nil
+	return events, octopus.decryptEvents(events)
 }
@@ -20,13 +20,18 @@ package pkg
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"sync"
 	"testing"
 	"time"
 
 	core "github.com/nuts-foundation/nuts-go-core"
 
+	natsServer "github.com/nats-io/nats-streaming-server/server"
+	"github.com/nats-io/nats-streaming-server/stores"
 	natsClient "github.com/nats-io/stan.go"
 	uuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
@@ -71,6 +76,301 @@ func TestEventOctopus_Start(t *testing.T) {
 	})
 }
 
+func TestEventOctopusConfig_applyStoreOptions(t *testing.T) {
+	t.Run("defaults to memory store", func(t *testing.T) {
+		opts := natsServer.GetDefaultOptions()
+
+		if assert.NoError(t, EventOctopusConfig{}.applyStoreOptions(opts)) {
+			assert.Equal(t, stores.TypeMemory, opts.StoreType)
+		}
+	})
+
+	t.Run("file store uses directory", func(t *testing.T) {
+		opts := natsServer.GetDefaultOptions()
+		c := EventOctopusConfig{NatsStoreType: "file", NatsFileStoreDir: "dir"}
+
+		if assert.NoError(t, c.applyStoreOptions(opts)) {
+			assert.Equal(t, stores.TypeFile, opts.StoreType)
+			assert.Equal(t, "dir", opts.FilestoreDir)
+		}
+	})
+
+	t.Run("file store requires directory", func(t *testing.T) {
+		c := EventOctopusConfig{NatsStoreType: "file"}
+
+		assert.Error(t, c.applyStoreOptions(natsServer.GetDefaultOptions()))
+	})
+
+	t.Run("sql store uses driver and source", func(t *testing.T) {
+		opts := natsServer.GetDefaultOptions()
+		c := EventOctopusConfig{NatsStoreType: "sql", NatsSQLStoreDriver: "postgres", NatsSQLStoreSource: "dbname=nats"}
+
+		if assert.NoError(t, c.applyStoreOptions(opts)) {
+			assert.Equal(t, stores.TypeSQL, opts.StoreType)
+			assert.Equal(t, "postgres", opts.SQLStoreOpts.Driver)
+			assert.Equal(t, "dbname=nats", opts.SQLStoreOpts.Source)
+		}
+	})
+
+	t.Run("sql store requires driver and source", func(t *testing.T) {
+		c := EventOctopusConfig{NatsStoreType: "sql", NatsSQLStoreDriver: "postgres"}
+
+		assert.Error(t, c.applyStoreOptions(natsServer.GetDefaultOptions()))
+	})
+
+	t.Run("unknown store type gives error", func(t *testing.T) {
+		c := EventOctopusConfig{NatsStoreType: "unknown"}
+
+		assert.Error(t, c.applyStoreOptions(natsServer.GetDefaultOptions()))
+	})
+
+	t.Run("channel limits are set", func(t *testing.T) {
+		opts := natsServer.GetDefaultOptions()
+		c := EventOctopusConfig{NatsMaxMsgs: 10, NatsMaxBytes: 1024, NatsMaxAge: 60}
+
+		if assert.NoError(t, c.applyStoreOptions(opts)) {
+			assert.Equal(t, 10, opts.MaxMsgs)
+			assert.Equal(t, int64(1024), opts.MaxBytes)
+			assert.Equal(t, time.Minute, opts.MaxAge)
+		}
+	})
+
+	t.Run("zero channel limits keep Nats defaults", func(t *testing.T) {
+		opts := natsServer.GetDefaultOptions()
+
+		if assert.NoError(t, EventOctopusConfig{}.applyStoreOptions(opts)) {
+			assert.Equal(t, stores.DefaultStoreLimits.MaxMsgs, opts.MaxMsgs)
+		}
+	})
+}
+
+func TestEventOctopus_FileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileStoreOctopus := func() *EventOctopus {
+		i := testEventOctopus()
+		i.Config.NatsStoreType = "file"
+		i.Config.NatsFileStoreDir = dir
+		return i
+	}
+	stop := func(i *EventOctopus) {
+		for _, c := range i.stanClients {
+			_ = c.Close()
+		}
+		_ = i.Shutdown()
+	}
+
+	t.Run("events published while the event store is offline are stored after a restart", func(t *testing.T) {
+		i := fileStoreOctopus()
+		_ = i.configure()
+		if !assert.NoError(t, i.Start()) {
+			stop(i)
+			return
+		}
+
+		// closing the connection keeps the durable subscriptions
+		_ = i.stanClients[ClientID].Close()
+		delete(i.stanClients, ClientID)
+
+		// a new subscription would only receive the last one
+		e1 := event()
+		e1.UUID = uuid.NewV4().String()
+		e2 := event()
+		e2.UUID = uuid.NewV4().String()
+		sc := stanConnection()
+		for _, e := range []Event{e1, e2} {
+			je, _ := json.Marshal(e)
+			_ = sc.Publish(ChannelConsentRequest, je)
+		}
+		_ = sc.Close()
+
+		stop(i)
+
+		i = fileStoreOctopus()
+		defer stop(i)
+		_ = i.configure()
+		if !assert.NoError(t, i.Start()) {
+			return
+		}
+
+		var stored *Event
+		for j := 0; j < 100 && stored == nil; j++ {
+			time.Sleep(10 * time.Millisecond)
+			stored, _ = i.GetEvent(e2.UUID)
+		}
+		assert.NotNil(t, stored)
+
+		stored, _ = i.GetEvent(e1.UUID)
+		assert.NotNil(t, stored)
+	})
+
+	t.Run("unacked messages are redelivered after a restart", func(t *testing.T) {
+		i := fileStoreOctopus()
+		if !assert.NoError(t, i.startStanServer()) {
+			return
+		}
+
+		sc, _ := i.client("file-store-test")
+		received := make(chan *natsClient.Msg, 10)
+		_, err := sc.Subscribe("fileStore", func(msg *natsClient.Msg) {
+			// no ack
+			received <- msg
+		}, natsClient.DurableName("file-store-durable"), natsClient.SetManualAckMode())
+		if !assert.NoError(t, err) {
+			stop(i)
+			return
+		}
+
+		_ = sc.Publish("fileStore", []byte("test"))
+
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			assert.Fail(t, "expected message before restart")
+		}
+
+		stop(i)
+
+		i = fileStoreOctopus()
+		defer stop(i)
+		if !assert.NoError(t, i.startStanServer()) {
+			return
+		}
+
+		sc, _ = i.client("file-store-test")
+		_, err = sc.Subscribe("fileStore", func(msg *natsClient.Msg) {
+			_ = msg.Ack()
+			received <- msg
+		}, natsClient.DurableName("file-store-durable"), natsClient.SetManualAckMode())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		select {
+		case msg := <-received:
+			assert.Equal(t, "test", string(msg.Data))
+			assert.True(t, msg.Redelivered)
+		case <-time.After(time.Second):
+			assert.Fail(t, "expected message to be redelivered")
+		}
+	})
+}
+
+func TestEventOctopus_ExternalNats(t *testing.T) {
+	// an embedded server on another port plays the external cluster
+	external := testEventOctopus()
+	external.Config.NatsPort = 4223
+	external.Config.NatsClusterID = "external"
+	if err := external.startStanServer(); err != nil {
+		t.Fatal(err)
+	}
+	defer external.Shutdown()
+
+	i := testEventOctopus()
+	i.Config.NatsURL = "nats://localhost:4223"
+	i.Config.NatsClusterID = "external"
+	i.Config.NatsNodeID = "node"
+	_ = i.configure()
+	if !assert.NoError(t, i.Start()) {
+		return
+	}
+	defer i.Shutdown()
+
+	t.Run("does not start an embedded server", func(t *testing.T) {
+		assert.Nil(t, i.stanServer)
+	})
+
+	t.Run("client IDs are prefixed with the node ID", func(t *testing.T) {
+		// the client ID is already taken by the event store
+		sc, err := natsClient.Connect("external", "node-"+ClientID, natsClient.NatsURL("nats://localhost:4223"))
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "clientID already registered")
+		} else {
+			_ = sc.Close()
+		}
+	})
+
+	t.Run("published events are persisted", func(t *testing.T) {
+		publisher, err := i.EventPublisher("external-test")
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = publisher.Publish(ChannelConsentRequest, e)
+
+		var stored *Event
+		for j := 0; j < 100 && stored == nil; j++ {
+			time.Sleep(10 * time.Millisecond)
+			stored, _ = i.GetEvent(e.UUID)
+		}
+		assert.NotNil(t, stored)
+	})
+
+	t.Run("diagnostics report external mode", func(t *testing.T) {
+		for _, r := range i.Diagnostics() {
+			if r.Name() == "Nats streaming server" {
+				assert.Equal(t, "mode: EXTERNAL @ nats://localhost:4223, ID: external, last error: NONE", r.String())
+			}
+		}
+	})
+
+	t.Run("diagnostics report down when not connected", func(t *testing.T) {
+		o := testEventOctopus()
+		o.Config.NatsURL = "nats://localhost:4223"
+
+		assert.Equal(t, "DOWN", o.externalNatsDiagnostics().String())
+	})
+}
+
+func TestEventOctopusConfig_connect(t *testing.T) {
+	i := testEventOctopus()
+	_ = i.startStanServer()
+	defer i.Shutdown()
+
+	t.Run("returns error for unknown cluster", func(t *testing.T) {
+		c := i.Config
+		c.NatsClusterID = "unknown"
+		_, err := c.connect("test")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("returns error for missing TLS files", func(t *testing.T) {
+		c := i.Config
+		c.NatsTLSCert = "unknown.pem"
+		c.NatsTLSKey = "unknown.key"
+		_, err := c.connect("test")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("returns error for missing credentials file", func(t *testing.T) {
+		c := i.Config
+		c.NatsCredentials = "unknown.creds"
+		_, err := c.connect("test")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("closing the connection closes the Nats connection", func(t *testing.T) {
+		sc, err := i.Config.connect("test")
+		if !assert.NoError(t, err) {
+			return
+		}
+		nc := sc.NatsConn()
+
+		_ = sc.Close()
+
+		assert.True(t, nc.IsClosed())
+	})
+}
+
 func TestEventOctopus_Shutdown(t *testing.T) {
 	t.Run("Terminating does not give errors for default values", func(t *testing.T) {
 		eo := testEventOctopus()
@@ -116,7 +416,7 @@ func TestEventOctopus_EventPersisted(t *testing.T) {
 		u := uuid.NewV4().String()
 		e.UUID = u
 
-		je, _ := json.Marshal(event)
+		je, _ := json.Marshal(e)
 
 		_ = stanClient.Publish(ChannelConsentRequest, je)
 
@@ -128,15 +428,11 @@ func TestEventOctopus_EventPersisted(t *testing.T) {
 		}
 	})
 
-	t.Run("an incorrect event is persisted in db as errored", func(t *testing.T) {
+	t.Run("an incorrect event is dead-lettered", func(t *testing.T) {
 		stanClient := stanConnection()
 		defer stanClient.Close()
 		emptyTable(i)
 
-		e := event()
-		u := uuid.NewV4().String()
-		e.UUID = u
-
 		je := []byte("{")
 
 		_ = stanClient.Publish(ChannelConsentRequest, je)
@@ -146,8 +442,12 @@ func TestEventOctopus_EventPersisted(t *testing.T) {
 		evts, err := i.List()
 
 		if assert.Nil(t, err) {
-			assert.Equal(t, 1, len(*evts))
-			assert.Equal(t, EventErrored, (*evts)[0].Name)
+			assert.Equal(t, 0, len(*evts))
+		}
+
+		deadLetters, err := i.DeadLetters()
+		if assert.Nil(t, err) && assert.NotEmpty(t, deadLetters) {
+			assert.Equal(t, je, deadLetters[len(deadLetters)-1].Data)
 		}
 	})
 
@@ -477,11 +777,13 @@ func testEventOctopus() *EventOctopus {
 	return &EventOctopus{
 		Name: Name,
 		Config: EventOctopusConfig{
-			RetryInterval:      ConfigRetryIntervalDefault,
-			NatsPort:           ConfigNatsPortDefault,
-			Connectionstring:   ConfigConnectionStringDefault,
-			MaxRetryCount:      ConfigMaxRetryCountDefault,
-			IncrementalBackoff: ConfigIncrementalBackoffDefault,
+			RetryInterval:             ConfigRetryIntervalDefault,
+			NatsPort:                  ConfigNatsPortDefault,
+			Connectionstring:          ConfigConnectionStringDefault,
+			MaxRetryCount:             ConfigMaxRetryCountDefault,
+			IncrementalBackoff:        ConfigIncrementalBackoffDefault,
+			WebhookMaxRetryCount:      ConfigWebhookMaxRetryCountDefault,
+			WebhookIncrementalBackoff: ConfigWebhookIncrementalBackoffDefault,
 		},
 		channelHandlers: make(map[string]map[string]ChannelHandlers),
 		stanClients:     make(map[string]natsClient.Conn),
@@ -533,10 +835,10 @@ func TestEventOctopus_Diagnostics(t *testing.T) {
 	i.configure()
 	i.Start()
 
-	t.Run("Diagnostics returns 2 reports", func(t *testing.T) {
+	t.Run("Diagnostics returns 7 reports", func(t *testing.T) {
 		results := i.Diagnostics()
 
-		assert.Len(t, results, 2)
+		assert.Len(t, results, 7)
 	})
 
 	t.Run("Diagnostics returns Nats info", func(t *testing.T) {
@@ -565,6 +867,22 @@ func TestEventOctopus_Diagnostics(t *testing.T) {
 		assert.True(t, found)
 	})
 
+	t.Run("Diagnostics returns checkpoint info", func(t *testing.T) {
+		found := false
+		_ = i.saveCheckpoint("service", "subject", 12)
+		defer i.ResetCheckpoint("service", "subject")
+
+		results := i.Diagnostics()
+		for _, r := range results {
+			if r.Name() == "Subscriber checkpoints" {
+				found = true
+				assert.Contains(t, r.String(), "service.subject: 12")
+			}
+		}
+
+		assert.True(t, found)
+	})
+
 	i.Shutdown()
 	i.stanServer = nil
 
@@ -623,6 +941,169 @@ func TestEventOctopus_GetEvent(t *testing.T) {
 	})
 }
 
+func TestEventOctopus_Timestamps(t *testing.T) {
+	i := testEventOctopus()
+	i.configure()
+	i.Start()
+	defer i.Shutdown()
+
+	e := Event{
+		ExternalID: "4",
+		Name:       EventConsentRequestConstructed,
+		UUID:       uuid.NewV4().String(),
+	}
+	i.SaveOrUpdateEvent(e)
+	first, _ := i.GetEvent(e.UUID)
+
+	t.Run("new event has all timestamps set", func(t *testing.T) {
+		if assert.NotNil(t, first) {
+			assert.False(t, first.CreatedAt.IsZero())
+			assert.WithinDuration(t, first.CreatedAt, first.UpdatedAt, time.Second)
+			assert.Equal(t, first.CreatedAt, first.TransitionedAt)
+		}
+	})
+
+	t.Run("update without transition only changes updatedAt", func(t *testing.T) {
+		e.RetryCount = 1
+		i.SaveOrUpdateEvent(e)
+
+		ep, _ := i.GetEvent(e.UUID)
+		if assert.NotNil(t, ep) {
+			assert.Equal(t, first.CreatedAt, ep.CreatedAt)
+			assert.True(t, ep.UpdatedAt.After(first.UpdatedAt))
+			assert.Equal(t, first.TransitionedAt, ep.TransitionedAt)
+		}
+	})
+
+	t.Run("transition changes transitionedAt", func(t *testing.T) {
+		e.Name = EventConsentRequestInFlight
+		i.SaveOrUpdateEvent(e)
+
+		ep, _ := i.GetEvent(e.UUID)
+		if assert.NotNil(t, ep) {
+			assert.Equal(t, first.CreatedAt, ep.CreatedAt)
+			assert.True(t, ep.TransitionedAt.After(first.TransitionedAt))
+			assert.WithinDuration(t, ep.UpdatedAt, ep.TransitionedAt, time.Second)
+		}
+	})
+
+	t.Run("timestamps of publishers are ignored", func(t *testing.T) {
+		e.CreatedAt = time.Now().Add(time.Hour)
+		e.TransitionedAt = time.Now().Add(time.Hour)
+		i.SaveOrUpdateEvent(e)
+
+		ep, _ := i.GetEvent(e.UUID)
+		if assert.NotNil(t, ep) {
+			assert.Equal(t, first.CreatedAt, ep.CreatedAt)
+			assert.True(t, ep.TransitionedAt.Before(time.Now()))
+		}
+	})
+}
+
+func TestEventOctopus_List(t *testing.T) {
+	i := testEventOctopus()
+	i.configure()
+	i.Start()
+	defer i.Shutdown()
+	emptyTable(i)
+	defer emptyTable(i)
+
+	var uuids []string
+	for j := 0; j < 3; j++ {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		i.SaveOrUpdateEvent(e)
+		uuids = append(uuids, e.UUID)
+	}
+	// update the first event so it's the last updated
+	e, _ := i.GetEvent(uuids[0])
+	i.SaveOrUpdateEvent(*e)
+
+	listed := func(t *testing.T, sort ...string) []string {
+		events, err := i.List(sort...)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		var result []string
+		for _, e := range *events {
+			result = append(result, e.UUID)
+		}
+		return result
+	}
+
+	t.Run("sorted on createdAt", func(t *testing.T) {
+		assert.Equal(t, uuids, listed(t, SortCreatedAt))
+	})
+
+	t.Run("sorted on createdAt descending", func(t *testing.T) {
+		assert.Equal(t, []string{uuids[2], uuids[1], uuids[0]}, listed(t, "-"+SortCreatedAt))
+	})
+
+	t.Run("sorted on updatedAt", func(t *testing.T) {
+		assert.Equal(t, []string{uuids[1], uuids[2], uuids[0]}, listed(t, SortUpdatedAt))
+	})
+
+	t.Run("unknown sort field gives error", func(t *testing.T) {
+		_, err := i.List("name")
+		assert.True(t, errors.Is(err, ErrUnknownSortField))
+	})
+}
+
+func TestEventOctopus_GetEventHistory(t *testing.T) {
+	i := testEventOctopus()
+	i.configure()
+	i.Start()
+	defer i.Shutdown()
+
+	t.Run("every save is recorded in order", func(t *testing.T) {
+		e := Event{
+			ExternalID: "3",
+			Name:       EventConsentRequestConstructed,
+			UUID:       uuid.NewV4().String(),
+		}
+		i.SaveOrUpdateEvent(e)
+		e.Name = EventConsentDistributed
+		e.RetryCount = 1
+		i.SaveOrUpdateEvent(e)
+
+		history, err := i.GetEventHistory(e.UUID)
+		if assert.Nil(t, err) && assert.Len(t, history, 2) {
+			assert.Equal(t, EventConsentRequestConstructed, history[0].Name)
+			assert.Equal(t, 0, history[0].RetryCount)
+			assert.Equal(t, EventConsentDistributed, history[1].Name)
+			assert.Equal(t, 1, history[1].RetryCount)
+			assert.Equal(t, "", history[1].Channel)
+			assert.Equal(t, e.UUID, history[1].UUID)
+		}
+	})
+
+	t.Run("the channel of a received event is recorded", func(t *testing.T) {
+		stanClient := stanConnection()
+		defer stanClient.Close()
+
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		je, _ := json.Marshal(e)
+		_ = stanClient.Publish(ChannelConsentRequest, je)
+
+		var history []EventHistory
+		for j := 0; j < 50 && len(history) == 0; j++ {
+			time.Sleep(10 * time.Millisecond)
+			history, _ = i.GetEventHistory(e.UUID)
+		}
+
+		if assert.Len(t, history, 1) {
+			assert.Equal(t, ChannelConsentRequest, history[0].Channel)
+		}
+	})
+
+	t.Run("unknown event has no history", func(t *testing.T) {
+		history, err := i.GetEventHistory(uuid.NewV4().String())
+		assert.Nil(t, err)
+		assert.Empty(t, history)
+	})
+}
+
 func TestEventOctopus_recover(t *testing.T) {
 	t.Run("events not completed are published", func(t *testing.T) {
 		i := testEventOctopus()
@@ -677,6 +1158,7 @@ func TestEventOctopus_GetMode(t *testing.T) {
 func TestEventOctopus_purgeCompleted(t *testing.T) {
 	t.Run("purgeCompleted removes events with name completed", func(t *testing.T) {
 		i := testEventOctopus()
+		i.Config.PurgeCompleted = true
 		i.configure()
 		i.Start()
 		defer i.Shutdown()
@@ -694,7 +1176,7 @@ func TestEventOctopus_purgeCompleted(t *testing.T) {
 			UUID:       uuid.NewV4().String(),
 		})
 
-		i.purgeCompleted()
+		i.Purge(false)
 
 		e, _ := i.GetEventByExternalID("1")
 
@@ -0,0 +1,186 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	natsClient "github.com/nats-io/stan.go"
+	"github.com/sirupsen/logrus"
+)
+
+// subscriptionAckWait is the default time a handler has to process an event before Nats redelivers it
+const subscriptionAckWait = 30 * time.Second
+
+// NonRetryableError is returned by an EventHandler when retrying the event will not help.
+// The event is published to ChannelConsentErrored instead of ChannelConsentRetry.
+type NonRetryableError struct {
+	Err error
+}
+
+// NonRetryable marks the error as not retryable
+func NonRetryable(err error) error {
+	return NonRetryableError{Err: err}
+}
+
+func (e NonRetryableError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e NonRetryableError) Unwrap() error {
+	return e.Err
+}
+
+// IsNonRetryable returns true if the error or any error it wraps is a NonRetryableError
+func IsNonRetryable(err error) bool {
+	return errors.As(err, &NonRetryableError{})
+}
+
+// ClassifiedError is returned by an EventHandler to classify the error, the class selects the retry policy of the event.
+type ClassifiedError struct {
+	Class string
+	Err   error
+}
+
+// Classify classifies the error, for instance as "corda" or "vendor"
+func Classify(class string, err error) error {
+	return ClassifiedError{Class: class, Err: err}
+}
+
+func (e ClassifiedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e ClassifiedError) Unwrap() error {
+	return e.Err
+}
+
+// errorClass returns the class of the first ClassifiedError the error wraps, nil when it is not classified
+func errorClass(err error) *string {
+	classified := ClassifiedError{}
+	if !errors.As(err, &classified) {
+		return nil
+	}
+	return &classified.Class
+}
+
+// handleMsg calls the handler for the event in the message. With manual acks the message is only acked when the handler succeeded
+// or when the failed event has been published to the retry or error channel, otherwise Nats redelivers it.
+// When resuming from a checkpoint, the checkpoint of the service is updated before acking and redelivered messages up to the
+// checkpoint are skipped unless the subscription is part of a queue group.
+func (octopus *EventOctopus) handleMsg(ctx context.Context, service string, channel ChannelHandlers, msg *natsClient.Msg) {
+	channel.checkpoints.deliver(msg.Sequence)
+
+	if channel.options.resume && channel.options.queueGroup == "" {
+		processed, err := octopus.processedBefore(service, msg)
+		if err != nil {
+			logrus.WithError(err).Errorf("Error while fetching checkpoint of %s.%s", service, msg.Subject)
+		}
+		if processed {
+			logrus.Debugf("Skipping message %d on %s, already processed by %s", msg.Sequence, msg.Subject, service)
+			channel.checkpoints.done(msg.Sequence)
+			channel.ack(msg)
+			return
+		}
+	}
+
+	event := &Event{}
+	if err := json.Unmarshal(msg.Data, event); err != nil {
+		// redelivering won't help
+		logrus.Errorf("Error unmarshalling event: %v", err)
+		if err := octopus.deadLetter(msg, fmt.Sprintf("%s: %v", service, err)); err != nil {
+			logrus.WithError(err).Errorf("Failed to dead-letter message %d on %s, awaiting redelivery", msg.Sequence, msg.Subject)
+			return
+		}
+		octopus.checkpoint(service, channel, msg)
+		return
+	}
+	eventsConsumed.WithLabelValues(msg.Subject, eventLabel(event.Name)).Inc()
+
+	handler := channel.handlers[event.Name]
+	if handler == nil {
+		logrus.Infof("Event without handler %v", event.Name)
+		octopus.checkpoint(service, channel, msg)
+		return
+	}
+
+	// the handler may alter the event, the original is retried
+	original := *event
+
+	ctx, cancel := context.WithTimeout(ctx, channel.options.ackWait)
+	defer cancel()
+
+	start := time.Now()
+	err := handler(ctx, event)
+	handlerDuration.WithLabelValues(service, msg.Subject, eventLabel(original.Name)).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		logrus.WithError(err).Warnf("Handler for event %s failed", original.UUID)
+		if err := octopus.publishFailedEvent(original, err); err != nil {
+			logrus.WithError(err).Errorf("Failed to publish failed event %s, awaiting redelivery", original.UUID)
+			return
+		}
+	}
+
+	octopus.checkpoint(service, channel, msg)
+}
+
+// checkpoint advances the checkpoint of the service over the handled messages and acks the message
+func (octopus *EventOctopus) checkpoint(service string, channel ChannelHandlers, msg *natsClient.Msg) {
+	if sequence := channel.checkpoints.done(msg.Sequence); sequence > 0 && channel.options.resume {
+		if err := octopus.saveCheckpoint(service, msg.Subject, sequence); err != nil {
+			logrus.WithError(err).Errorf("Failed to store checkpoint of %s.%s", service, msg.Subject)
+		}
+	}
+
+	channel.ack(msg)
+}
+
+// publishFailedEvent publishes the event to ChannelConsentErrored for NonRetryableErrors and to ChannelConsentRetry otherwise
+func (octopus *EventOctopus) publishFailedEvent(event Event, handlerErr error) error {
+	errStr := handlerErr.Error()
+	event.Error = &errStr
+	event.ErrorClass = errorClass(handlerErr)
+
+	if IsNonRetryable(handlerErr) {
+		event.Name = EventErrored
+		return octopus.publishEventToChannel(event, ChannelConsentErrored)
+	}
+
+	return octopus.publishEventToChannel(event, ChannelConsentRetry)
+}
+
+// ack acks the message when the subscription acks manually, Nats acks the others automatically
+func (channel ChannelHandlers) ack(msg *natsClient.Msg) {
+	if channel.options.manualAck {
+		ack(msg)
+	}
+}
+
+func ack(msg *natsClient.Msg) {
+	if err := msg.Ack(); err != nil {
+		logrus.WithError(err).Errorf("Failed to ack message %d on %s", msg.Sequence, msg.Subject)
+	}
+}
@@ -0,0 +1,219 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNonRetryable(t *testing.T) {
+	cause := errors.New("invalid payload")
+
+	t.Run("wrapped error is non retryable", func(t *testing.T) {
+		err := fmt.Errorf("handling failed: %w", NonRetryable(cause))
+
+		assert.True(t, IsNonRetryable(err))
+		assert.True(t, errors.Is(err, cause))
+		assert.Equal(t, "handling failed: invalid payload", err.Error())
+	})
+
+	t.Run("plain error is retryable", func(t *testing.T) {
+		assert.False(t, IsNonRetryable(cause))
+	})
+}
+
+func TestClassify(t *testing.T) {
+	cause := errors.New("timeout")
+
+	t.Run("wrapped error has error class", func(t *testing.T) {
+		err := fmt.Errorf("handling failed: %w", Classify("corda", cause))
+
+		if assert.NotNil(t, errorClass(err)) {
+			assert.Equal(t, "corda", *errorClass(err))
+		}
+		assert.True(t, errors.Is(err, cause))
+		assert.Equal(t, "handling failed: timeout", err.Error())
+	})
+
+	t.Run("plain error has no error class", func(t *testing.T) {
+		assert.Nil(t, errorClass(cause))
+	})
+}
+
+func TestEventOctopus_SubscribeHandlers(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:handlers?mode=memory&cache=shared"
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	publisher, _ := i.EventPublisher("event-octopus-test")
+
+	newEvent := func() Event {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		return e
+	}
+
+	t.Run("handler receives a context with a deadline", func(t *testing.T) {
+		e := newEvent()
+		deadlines := make(chan bool, 1)
+
+		_ = i.SubscribeHandlers("handler-deadline", "handlerDeadline", map[string]EventHandler{
+			e.Name: func(ctx context.Context, event *Event) error {
+				_, ok := ctx.Deadline()
+				deadlines <- ok
+				return nil
+			},
+		})
+
+		_ = publisher.Publish("handlerDeadline", e)
+
+		select {
+		case ok := <-deadlines:
+			assert.True(t, ok)
+		case <-time.After(time.Second):
+			assert.Fail(t, "handler not called")
+		}
+	})
+
+	t.Run("failed event is retried", func(t *testing.T) {
+		e := newEvent()
+		retries := make(chan *Event, 1)
+		calls := 0
+
+		_ = i.SubscribeHandlers("handler-retry", ChannelConsentRequest, map[string]EventHandler{
+			e.Name: func(ctx context.Context, event *Event) error {
+				if event.UUID != e.UUID {
+					return nil
+				}
+				calls++
+				if calls == 1 {
+					return errors.New("service unavailable")
+				}
+				retries <- event
+				return nil
+			},
+		})
+		defer i.Unsubscribe("handler-retry", ChannelConsentRequest)
+
+		_ = publisher.Publish(ChannelConsentRequest, e)
+
+		select {
+		case retried := <-retries:
+			assert.Equal(t, 1, retried.RetryCount)
+			if assert.NotNil(t, retried.Error) {
+				assert.Equal(t, "service unavailable", *retried.Error)
+			}
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "event not retried")
+		}
+	})
+
+	t.Run("non retryable failure is published as error", func(t *testing.T) {
+		e := newEvent()
+
+		_ = i.SubscribeHandlers("handler-error", ChannelConsentRequest, map[string]EventHandler{
+			e.Name: func(ctx context.Context, event *Event) error {
+				if event.UUID != e.UUID {
+					return nil
+				}
+				return NonRetryable(errors.New("invalid payload"))
+			},
+		})
+		defer i.Unsubscribe("handler-error", ChannelConsentRequest)
+
+		_ = publisher.Publish(ChannelConsentRequest, e)
+
+		var stored *Event
+		for j := 0; j < 100; j++ {
+			time.Sleep(10 * time.Millisecond)
+			stored, _ = i.GetEvent(e.UUID)
+			if stored != nil && stored.Name == EventErrored {
+				break
+			}
+		}
+		if assert.NotNil(t, stored) {
+			assert.Equal(t, EventErrored, stored.Name)
+			if assert.NotNil(t, stored.Error) {
+				assert.Equal(t, "invalid payload", *stored.Error)
+			}
+		}
+	})
+
+	t.Run("unreadable message is dead-lettered", func(t *testing.T) {
+		_ = i.SubscribeHandlers("handler-unreadable", "handlerUnreadable", map[string]EventHandler{})
+		defer i.Unsubscribe("handler-unreadable", "handlerUnreadable")
+		sc, _ := i.client("handler-unreadable-publisher")
+
+		_ = sc.Publish("handlerUnreadable", []byte("{unreadable"))
+
+		var deadLetters []DeadLetter
+		for j := 0; j < 100 && len(deadLetters) == 0; j++ {
+			time.Sleep(10 * time.Millisecond)
+			deadLetters, _ = i.DeadLetters()
+		}
+		if assert.Len(t, deadLetters, 1) {
+			assert.Equal(t, "handlerUnreadable", deadLetters[0].Subject)
+			assert.Contains(t, deadLetters[0].Reason, "handler-unreadable")
+		}
+	})
+
+	t.Run("unsubscribing cancels the context", func(t *testing.T) {
+		e := newEvent()
+		started := make(chan bool)
+		done := make(chan error, 1)
+
+		_ = i.SubscribeHandlers("handler-cancel", "handlerCancel", map[string]EventHandler{
+			e.Name: func(ctx context.Context, event *Event) error {
+				close(started)
+				<-ctx.Done()
+				done <- ctx.Err()
+				return nil
+			},
+		})
+
+		_ = publisher.Publish("handlerCancel", e)
+
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			assert.Fail(t, "handler not called")
+			return
+		}
+
+		_ = i.Unsubscribe("handler-cancel", "handlerCancel")
+
+		select {
+		case err := <-done:
+			assert.Equal(t, context.Canceled, err)
+		case <-time.After(time.Second):
+			assert.Fail(t, "context not cancelled")
+		}
+	})
+}
@@ -0,0 +1,179 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SourceManualRetry is recorded as channel in the event history when an event is retried manually
+const SourceManualRetry = "manualRetry"
+
+// ErrNotErrored is returned when an event is retried that is not in the EventErrored state,
+// nor in the terminal state of a retry policy after its last retry
+var ErrNotErrored = errors.New("event is not errored")
+
+// ErrNoRetryState is returned when an errored event is retried that never had a state before it errored
+var ErrNoRetryState = errors.New("event has no state to retry from")
+
+// RetryEvent resets the retry count and error of an errored event and republishes it to ChannelConsentRequest
+// with the last state it had before it errored. Events that got the terminal state of a retry policy after their
+// last retry are retried the same way. It returns nil when the event does not exist.
+func (octopus *EventOctopus) RetryEvent(uuid string) (*Event, error) {
+	event, err := octopus.GetEvent(uuid)
+	if err != nil || event == nil {
+		return nil, err
+	}
+
+	if event.Name != EventErrored {
+		exhausted, err := octopus.retriesExhausted(*event)
+		if err != nil {
+			return nil, err
+		}
+		if !exhausted {
+			return nil, ErrNotErrored
+		}
+	}
+
+	name, err := octopus.lastStateBefore(uuid, event.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Name = name
+	event.RetryCount = 0
+	event.Error = nil
+	event.ErrorClass = nil
+
+	// the retry itself is recorded in the history before the event is processed again,
+	// it fails with a ConflictError when the event changed since it was read
+	if err := octopus.storeEvent(*event, SourceManualRetry, event.Version); err != nil {
+		return nil, err
+	}
+
+	if err := octopus.publishEventToChannel(*event, ChannelConsentRequest); err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Event %s manually retried from state: %s", uuid, name)
+
+	return octopus.GetEvent(uuid)
+}
+
+// RetryEvents retries all errored events matching the query filters, sort, cursor and limit are ignored.
+// Events in the terminal state of a retry policy after their last retry are included, the name filter is ignored.
+// Events without a state to retry from or that changed while retrying are skipped. It returns the number of retried events.
+func (octopus *EventOctopus) RetryEvents(query EventQuery) (int, error) {
+	query.Sort = ""
+	query.Limit = MaxPageSize
+
+	// collect first, retried events no longer match the query
+	var uuids []string
+	for _, name := range octopus.terminalStates() {
+		query.Name = name
+		query.Cursor = ""
+		for {
+			page, err := octopus.Query(query)
+			if err != nil {
+				return 0, err
+			}
+			for _, e := range page.Events {
+				uuids = append(uuids, e.UUID)
+			}
+			if page.Next == "" {
+				break
+			}
+			query.Cursor = page.Next
+		}
+	}
+
+	retried := 0
+	for _, u := range uuids {
+		_, err := octopus.RetryEvent(u)
+		if errors.Is(err, ErrNoRetryState) || errors.Is(err, ErrNotErrored) || errors.Is(err, ErrConflict) {
+			logrus.WithError(err).Warnf("Skipping retry of event %s", u)
+			continue
+		}
+		if err != nil {
+			return retried, err
+		}
+		retried++
+	}
+
+	return retried, nil
+}
+
+// terminalStates returns EventErrored and the terminal states of the retry policies
+func (octopus *EventOctopus) terminalStates() []string {
+	states := []string{EventErrored}
+	for _, p := range octopus.retryPolicies {
+		found := false
+		for _, s := range states {
+			found = found || s == p.Terminal
+		}
+		if !found {
+			states = append(states, p.Terminal)
+		}
+	}
+
+	return states
+}
+
+// retriesExhausted returns true when the event got the terminal state of a retry policy after its last retry.
+// The terminal state can be part of the flow, an event that reached it otherwise is not retried.
+func (octopus *EventOctopus) retriesExhausted(event Event) (bool, error) {
+	terminal := false
+	for _, name := range octopus.terminalStates() {
+		terminal = terminal || name == event.Name
+	}
+	if !terminal {
+		return false, nil
+	}
+
+	history, err := octopus.GetEventHistory(event.UUID)
+	if err != nil {
+		return false, err
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		if !history[i].Rejected {
+			return history[i].Name == event.Name && history[i].Channel == ChannelConsentRetry, nil
+		}
+	}
+
+	return false, nil
+}
+
+// lastStateBefore finds the name of the event before it entered the EventErrored or the given terminal state
+func (octopus *EventOctopus) lastStateBefore(uuid string, terminal string) (string, error) {
+	history, err := octopus.GetEventHistory(uuid)
+	if err != nil {
+		return "", err
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Name != EventErrored && history[i].Name != terminal && !history[i].Rejected {
+			return history[i].Name, nil
+		}
+	}
+
+	return "", ErrNoRetryState
+}
@@ -0,0 +1,156 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventOctopus_RetryEvent(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:manualretry?mode=memory&cache=shared"
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	// errored stores an event that errored after reaching the max retry count
+	errored := func(legalEntity string) Event {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.InitiatorLegalEntity = legalEntity
+		e.Name = EventConsentRequestInFlight
+		i.SaveOrUpdateEvent(e)
+
+		errStr := "max retry count reached"
+		e.Name = EventErrored
+		e.Error = &errStr
+		e.RetryCount = ConfigMaxRetryCountDefault
+		i.SaveOrUpdateEvent(e)
+
+		return e
+	}
+
+	t.Run("errored event is reset and republished", func(t *testing.T) {
+		e := errored("urn:nuts:entity:test")
+
+		retried, err := i.RetryEvent(e.UUID)
+		if !assert.NoError(t, err) || !assert.NotNil(t, retried) {
+			return
+		}
+		assert.Equal(t, EventConsentRequestInFlight, retried.Name)
+		assert.Equal(t, 0, retried.RetryCount)
+		assert.Nil(t, retried.Error)
+
+		// the republished event is received on the consentRequest channel
+		var history []EventHistory
+		for j := 0; j < 50 && len(history) < 4; j++ {
+			time.Sleep(10 * time.Millisecond)
+			history, _ = i.GetEventHistory(e.UUID)
+		}
+		if assert.Len(t, history, 4) {
+			assert.Equal(t, SourceManualRetry, history[2].Channel)
+			assert.Equal(t, EventConsentRequestInFlight, history[2].Name)
+			assert.Equal(t, ChannelConsentRequest, history[3].Channel)
+		}
+	})
+
+	t.Run("unknown event returns nil", func(t *testing.T) {
+		retried, err := i.RetryEvent(uuid.NewV4().String())
+
+		assert.NoError(t, err)
+		assert.Nil(t, retried)
+	})
+
+	t.Run("event that is not errored gives error", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		i.SaveOrUpdateEvent(e)
+
+		_, err := i.RetryEvent(e.UUID)
+
+		assert.True(t, errors.Is(err, ErrNotErrored))
+	})
+
+	t.Run("event without state before the error gives error", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Name = EventErrored
+		i.SaveOrUpdateEvent(e)
+
+		_, err := i.RetryEvent(e.UUID)
+
+		assert.True(t, errors.Is(err, ErrNoRetryState))
+	})
+
+	t.Run("errored events matching the filter are retried", func(t *testing.T) {
+		e1 := errored("urn:nuts:entity:bulk")
+		e2 := errored("urn:nuts:entity:bulk")
+		e3 := errored("urn:nuts:entity:other")
+
+		retried, err := i.RetryEvents(EventQuery{InitiatorLegalEntity: "urn:nuts:entity:bulk"})
+		if assert.NoError(t, err) {
+			assert.Equal(t, 2, retried)
+		}
+
+		for _, e := range []Event{e1, e2} {
+			stored, _ := i.GetEvent(e.UUID)
+			assert.NotEqual(t, EventErrored, stored.Name)
+		}
+		stored, _ := i.GetEvent(e3.UUID)
+		assert.Equal(t, EventErrored, stored.Name)
+	})
+	t.Run("event in the terminal state of a retry policy is retried", func(t *testing.T) {
+		i.retryPolicies = []RetryPolicy{{Name: EventConsentRequestConstructed, Terminal: EventConsentRequestFlowErrored}}
+		defer func() { i.retryPolicies = nil }()
+
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = i.saveEvent(e, ChannelConsentRequest)
+		e.Name = EventConsentRequestFlowErrored
+		_ = i.saveEvent(e, ChannelConsentRetry)
+
+		retried, err := i.RetryEvent(e.UUID)
+
+		if assert.NoError(t, err) && assert.NotNil(t, retried) {
+			assert.Equal(t, EventConsentRequestConstructed, retried.Name)
+		}
+	})
+
+	t.Run("event that got the terminal state of a retry policy otherwise gives error", func(t *testing.T) {
+		i.retryPolicies = []RetryPolicy{{Name: EventConsentRequestConstructed, Terminal: EventConsentRequestFlowErrored}}
+		defer func() { i.retryPolicies = nil }()
+
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = i.saveEvent(e, ChannelConsentRequest)
+		e.Name = EventConsentRequestFlowErrored
+		_ = i.saveEvent(e, ChannelConsentRequest)
+
+		_, err := i.RetryEvent(e.UUID)
+
+		assert.True(t, errors.Is(err, ErrNotErrored))
+	})
+}
@@ -0,0 +1,149 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsNamespace prefixes the names of all metrics of the event octopus
+const metricsNamespace = "nuts_event_octopus"
+
+// otherEventLabel is the event label of event names that are not part of the consent request flow
+const otherEventLabel = "other"
+
+// results of storing an event, used as label of the save latency
+const (
+	saveResultOK       = "ok"
+	saveResultConflict = "conflict"
+	saveResultRejected = "rejected"
+	saveResultError    = "error"
+)
+
+var (
+	eventsPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "events_published_total",
+		Help:      "Number of events published per subject and event name",
+	}, []string{"subject", "event"})
+
+	eventsConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "events_consumed_total",
+		Help:      "Number of events received per subject and event name, by the event store and by subscribed services",
+	}, []string{"subject", "event"})
+
+	eventsDeadLettered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "events_dead_lettered_total",
+		Help:      "Number of messages published to the dead letter channel per original subject",
+	}, []string{"subject"})
+
+	eventsPurged = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "events_purged_total",
+		Help:      "Number of events removed by the retention rules per event name",
+	}, []string{"event"})
+
+	retryQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "retry_queue_depth",
+		Help:      "Number of messages waiting for their delay in a retry queue",
+	}, []string{"queue"})
+
+	retryRedeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "retry_redeliveries_total",
+		Help:      "Number of messages redelivered by Nats to a retry queue",
+	}, []string{"queue"})
+
+	handlerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "handler_duration_seconds",
+		Help:      "Time spent in the event handlers of subscribed services",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "subject", "event"})
+
+	saveDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "save_duration_seconds",
+		Help:      "Time spent storing an event in the event store per result: ok, conflict, rejected or error",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"result"})
+
+	messageFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "message_failures_total",
+		Help:      "Number of messages the event store failed to process per subject, they are left for redelivery",
+	}, []string{"subject"})
+
+	circuitBreakerOpen = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "circuit_breaker_open",
+		Help:      "1 when the circuit breaker paused message processing of the event store, 0 otherwise",
+	})
+)
+
+// Metrics returns the registry holding the metrics of the event octopus. The metrics are not registered globally,
+// the node exposes the registry next to the metrics of other engines.
+func (octopus *EventOctopus) Metrics() *prometheus.Registry {
+	octopus.metricsOnce.Do(func() {
+		octopus.metrics = prometheus.NewRegistry()
+		octopus.metrics.MustRegister(
+			eventsPublished,
+			eventsConsumed,
+			eventsDeadLettered,
+			eventsPurged,
+			retryQueueDepth,
+			retryRedeliveries,
+			handlerDuration,
+			saveDuration,
+			messageFailures,
+			circuitBreakerOpen,
+		)
+	})
+
+	return octopus.metrics
+}
+
+// eventLabel returns the event name as label when it's a state of the flow, the names come from the messages so others are grouped
+func eventLabel(name string) string {
+	if isState(name) {
+		return name
+	}
+	return otherEventLabel
+}
+
+// observeSave records the latency of storing an event with the result derived from the error
+func observeSave(start time.Time, err error) {
+	result := saveResultOK
+	switch {
+	case errors.Is(err, ErrConflict):
+		result = saveResultConflict
+	case errors.Is(err, ErrInvalidTransition):
+		result = saveResultRejected
+	case err != nil:
+		result = saveResultError
+	}
+
+	saveDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,211 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	natsClient "github.com/nats-io/stan.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserveSave(t *testing.T) {
+	t.Run("result is derived from the error", func(t *testing.T) {
+		for result, err := range map[string]error{
+			saveResultOK:       nil,
+			saveResultConflict: ConflictError{UUID: "1"},
+			saveResultRejected: ErrInvalidTransition,
+			saveResultError:    errors.New("b00m!"),
+		} {
+			before := histogramCount(t, result)
+
+			observeSave(time.Now(), err)
+
+			assert.Equal(t, before+1, histogramCount(t, result), result)
+		}
+	})
+}
+
+func TestEventLabel(t *testing.T) {
+	assert.Equal(t, EventConsentRequestConstructed, eventLabel(EventConsentRequestConstructed))
+	assert.Equal(t, EventErrored, eventLabel(EventErrored))
+	assert.Equal(t, otherEventLabel, eventLabel(uuid.NewV4().String()))
+	assert.Equal(t, otherEventLabel, eventLabel(""))
+}
+
+func TestEventOctopus_Metrics(t *testing.T) {
+	t.Run("metrics are not registered globally", func(t *testing.T) {
+		assert.False(t, prometheus.DefaultRegisterer.Unregister(circuitBreakerOpen))
+	})
+
+	t.Run("every instance owns a registry", func(t *testing.T) {
+		i := testEventOctopus()
+
+		families, err := i.Metrics().Gather()
+
+		if assert.NoError(t, err) {
+			assert.NotEmpty(t, families)
+		}
+		assert.Same(t, i.Metrics(), i.Metrics())
+		assert.NotSame(t, i.Metrics(), testEventOctopus().Metrics())
+	})
+}
+
+func histogramCount(t *testing.T, result string) uint64 {
+	m := &dto.Metric{}
+	if err := saveDuration.WithLabelValues(result).(prometheus.Histogram).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestEventOctopus_metrics(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:metrics?mode=memory&cache=shared"
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	publisher, _ := i.EventPublisher("event-octopus-test")
+
+	t.Run("published and consumed events are counted", func(t *testing.T) {
+		published := testutil.ToFloat64(eventsPublished.WithLabelValues(ChannelConsentRequest, EventConsentRequestAcked))
+		consumed := testutil.ToFloat64(eventsConsumed.WithLabelValues(ChannelConsentRequest, EventConsentRequestAcked))
+
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Name = EventConsentRequestAcked
+		_ = publisher.Publish(ChannelConsentRequest, e)
+
+		for j := 0; j < 100; j++ {
+			if stored, _ := i.GetEvent(e.UUID); stored != nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		assert.Equal(t, published+1, testutil.ToFloat64(eventsPublished.WithLabelValues(ChannelConsentRequest, EventConsentRequestAcked)))
+		assert.Equal(t, consumed+1, testutil.ToFloat64(eventsConsumed.WithLabelValues(ChannelConsentRequest, EventConsentRequestAcked)))
+	})
+
+	t.Run("dead-lettered messages are counted", func(t *testing.T) {
+		before := testutil.ToFloat64(eventsDeadLettered.WithLabelValues(ChannelConsentRequest))
+
+		sc, _ := i.client("event-octopus-test")
+		_ = sc.Publish(ChannelConsentRequest, []byte("not an event"))
+
+		for j := 0; j < 100 && testutil.ToFloat64(eventsDeadLettered.WithLabelValues(ChannelConsentRequest)) == before; j++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		assert.Equal(t, before+1, testutil.ToFloat64(eventsDeadLettered.WithLabelValues(ChannelConsentRequest)))
+	})
+
+	t.Run("handler latency is observed", func(t *testing.T) {
+		handled := make(chan bool, 1)
+		_ = i.Subscribe("metrics-service", "metrics", map[string]EventHandlerCallback{
+			EventConsentRequestConstructed: func(event *Event) {
+				handled <- true
+			},
+		})
+		defer i.Unsubscribe("metrics-service", "metrics")
+
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = publisher.Publish("metrics", e)
+		<-handled
+
+		m := &dto.Metric{}
+		for j := 0; j < 100; j++ {
+			_ = handlerDuration.WithLabelValues("metrics-service", "metrics", EventConsentRequestConstructed).(prometheus.Histogram).Write(m)
+			if m.GetHistogram().GetSampleCount() > 0 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+	})
+
+	t.Run("purged events are counted", func(t *testing.T) {
+		before := testutil.ToFloat64(eventsPurged.WithLabelValues(EventClosed))
+		i.Config.RetentionRules = "closed"
+		_ = i.configureRetention()
+
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Name = EventClosed
+		_ = i.SaveOrUpdateEvent(e)
+
+		_, _ = i.Purge(true)
+		assert.Equal(t, before, testutil.ToFloat64(eventsPurged.WithLabelValues(EventClosed)))
+
+		_, _ = i.Purge(false)
+		assert.Equal(t, before+1, testutil.ToFloat64(eventsPurged.WithLabelValues(EventClosed)))
+	})
+}
+
+func TestDelayedConsumer_metrics(t *testing.T) {
+	t.Run("queue depth is back to 0 after publishing", func(t *testing.T) {
+		i := testEventOctopus()
+		i.Config.Connectionstring = "file:metrics_scheduler?mode=memory&cache=shared"
+		i.configure()
+		if err := i.Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer i.Shutdown()
+
+		sc, _ := i.client("event-octopus-test")
+		consumers := NewDelayedConsumerSet("metrics-retry", "metrics-target", 1, 100*time.Millisecond, 1, sc, i)
+		if err := consumers[0].Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer consumers[0].Stop()
+
+		received := make(chan bool, 1)
+		sub, _ := sc.Subscribe("metrics-target", func(msg *natsClient.Msg) {
+			received <- true
+		})
+		defer sub.Unsubscribe()
+
+		_ = sc.Publish("metrics-retry-0", []byte("{}"))
+
+		for j := 0; j < 50 && testutil.ToFloat64(retryQueueDepth.WithLabelValues("metrics-retry-0")) == 0; j++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+		assert.Equal(t, float64(1), testutil.ToFloat64(retryQueueDepth.WithLabelValues("metrics-retry-0")))
+
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			assert.Fail(t, "message not republished")
+		}
+		for j := 0; j < 50 && testutil.ToFloat64(retryQueueDepth.WithLabelValues("metrics-retry-0")) != 0; j++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+		assert.Equal(t, float64(0), testutil.ToFloat64(retryQueueDepth.WithLabelValues("metrics-retry-0")))
+	})
+}
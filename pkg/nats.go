@@ -0,0 +1,135 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/nats-io/nats.go"
+	natsClient "github.com/nats-io/stan.go"
+)
+
+// natsModeExternal is reported by the diagnostics when connected to an external Nats cluster
+const natsModeExternal = "EXTERNAL"
+
+// embedded returns true when the octopus runs its own Nats streaming server
+func (c EventOctopusConfig) embedded() bool {
+	return c.NatsURL == ""
+}
+
+// freePort returns a port on the interface of the monitoring endpoint that is not in use
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(monitorHost, "0"))
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// natsURL returns the url(s) clients connect to
+func (c EventOctopusConfig) natsURL() string {
+	if c.embedded() {
+		return fmt.Sprintf("nats://localhost:%d", c.NatsPort)
+	}
+	return c.NatsURL
+}
+
+// clusterID returns the Nats streaming cluster ID, defaults to the ID of the embedded server
+func (c EventOctopusConfig) clusterID() string {
+	if c.NatsClusterID == "" {
+		return ConfigNatsClusterIDDefault
+	}
+	return c.NatsClusterID
+}
+
+// natsOptions returns the options for the underlying Nats connection
+func (c EventOctopusConfig) natsOptions(clientID string) []nats.Option {
+	// same settings as used by stan.Connect: reconnect forever and do not buffer while reconnecting
+	options := []nats.Option{
+		nats.Name(clientID),
+		nats.MaxReconnects(-1),
+		nats.ReconnectBufSize(-1),
+	}
+
+	if c.NatsCredentials != "" {
+		options = append(options, nats.UserCredentials(c.NatsCredentials))
+	}
+	if c.NatsTLSCert != "" || c.NatsTLSKey != "" {
+		options = append(options, nats.ClientCert(c.NatsTLSCert, c.NatsTLSKey))
+	}
+	if c.NatsTLSCA != "" {
+		options = append(options, nats.RootCAs(c.NatsTLSCA))
+	}
+
+	return options
+}
+
+// connect creates a new Nats streaming connection for the given client ID
+func (c EventOctopusConfig) connect(clientID string) (natsClient.Conn, error) {
+	if c.NatsNodeID != "" {
+		// client IDs must be unique within a Nats streaming cluster
+		clientID = fmt.Sprintf("%s-%s", c.NatsNodeID, clientID)
+	}
+
+	nc, err := nats.Connect(c.natsURL(), c.natsOptions(clientID)...)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, err := natsClient.Connect(c.clusterID(), clientID, natsClient.NatsConn(nc))
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	return ownedNatsConn{Conn: sc, nc: nc}, nil
+}
+
+// ownedNatsConn closes the Nats connection it was created with when the streaming connection is closed
+type ownedNatsConn struct {
+	natsClient.Conn
+	nc *nats.Conn
+}
+
+// Close closes the streaming connection and the underlying Nats connection
+func (c ownedNatsConn) Close() error {
+	err := c.Conn.Close()
+	c.nc.Close()
+	return err
+}
+
+func (octopus *EventOctopus) externalNatsDiagnostics() natsDiagnosticsResult {
+	conn, ok := octopus.stanClients[ClientID]
+	if !ok || conn.NatsConn() == nil || !conn.NatsConn().IsConnected() {
+		return natsDiagnosticsResult{
+			up: false,
+		}
+	}
+
+	return natsDiagnosticsResult{
+		up:        true,
+		natsMode:  natsModeExternal,
+		natsURL:   conn.NatsConn().ConnectedUrl(),
+		stanID:    octopus.Config.clusterID(),
+		lastError: conn.NatsConn().LastError(),
+	}
+}
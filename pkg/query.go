@@ -0,0 +1,210 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// DefaultPageSize is the number of events returned by Query when no limit is given
+const DefaultPageSize = 100
+
+// MaxPageSize is the maximum number of events returned by Query
+const MaxPageSize = 1000
+
+// ErrInvalidCursor is returned when events are queried with a cursor that was not returned by a previous query with the same sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EventQuery filters and paginates events, empty fields are ignored
+type EventQuery struct {
+	Name                 string
+	InitiatorLegalEntity string
+	ConsentID            string
+	TransactionID        string
+	// HasError selects events with (true) or without (false) an error
+	HasError      *bool
+	MinRetryCount *int
+	MaxRetryCount *int
+	// Since and Until select events by the moment they were last stored, Until is exclusive
+	Since *time.Time
+	Until *time.Time
+	// Sort is one of the sort fields accepted by List
+	Sort string
+	// Cursor is the Next value of the previous page
+	Cursor string
+	// Limit is the page size, it defaults to DefaultPageSize and can not exceed MaxPageSize
+	Limit int
+}
+
+// EventPage is a single page of events
+type EventPage struct {
+	Events []Event
+	// Next is the cursor for the next page, empty when this is the last page
+	Next string
+}
+
+// cursor points to the last event of a page
+type cursor struct {
+	Sort string    `json:"s,omitempty"`
+	Time time.Time `json:"t"`
+	UUID string    `json:"u"`
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (cursor, error) {
+	c := cursor{}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.UUID == "" {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// Query returns a single page of events matching the query.
+// Events are sorted on the query sort field with the uuid as tiebreaker, so pages are stable while events are added.
+func (octopus *EventOctopus) Query(query EventQuery) (*EventPage, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	db := filter(octopus.Db.Debug(), query)
+
+	// uuid is used as tiebreaker, without sort field events are sorted on uuid only
+	column := ""
+	direction := "ASC"
+	if query.Sort != "" {
+		var err error
+		if column, direction, err = sortColumn(query.Sort); err != nil {
+			return nil, err
+		}
+		db = db.Order(fmt.Sprintf("%s %s", column, direction))
+	}
+	db = db.Order(fmt.Sprintf("uuid %s", direction))
+
+	if query.Cursor != "" {
+		c, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if c.Sort != query.Sort {
+			return nil, fmt.Errorf("%w: sorted on %s", ErrInvalidCursor, c.Sort)
+		}
+
+		operator := ">"
+		if direction == "DESC" {
+			operator = "<"
+		}
+		if column == "" {
+			db = db.Where(fmt.Sprintf("uuid %s ?", operator), c.UUID)
+		} else {
+			t := c.Time.UTC()
+			db = db.Where(fmt.Sprintf("%[1]s %[2]s ? OR (%[1]s = ? AND uuid %[2]s ?)", column, operator), t, t, c.UUID)
+		}
+	}
+
+	// one extra event to find out if there's a next page
+	var events []Event
+	if err := db.Limit(limit + 1).Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	if err := octopus.decryptEvents(events); err != nil {
+		return nil, err
+	}
+
+	page := &EventPage{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.Next = nextCursor(query.Sort, page.Events[limit-1])
+	}
+
+	return page, nil
+}
+
+// filter adds the where clauses for the query filters
+func filter(db *gorm.DB, query EventQuery) *gorm.DB {
+	if query.Name != "" {
+		db = db.Where("name = ?", query.Name)
+	}
+	if query.InitiatorLegalEntity != "" {
+		db = db.Where("initiator_legal_entity = ?", query.InitiatorLegalEntity)
+	}
+	if query.ConsentID != "" {
+		db = db.Where("consent_id = ?", query.ConsentID)
+	}
+	if query.TransactionID != "" {
+		db = db.Where("transaction_id = ?", query.TransactionID)
+	}
+	if query.HasError != nil {
+		if *query.HasError {
+			db = db.Where("error IS NOT NULL")
+		} else {
+			db = db.Where("error IS NULL")
+		}
+	}
+	if query.MinRetryCount != nil {
+		db = db.Where("retry_count >= ?", *query.MinRetryCount)
+	}
+	if query.MaxRetryCount != nil {
+		db = db.Where("retry_count <= ?", *query.MaxRetryCount)
+	}
+	if query.Since != nil {
+		db = db.Where("updated_at >= ?", query.Since.UTC())
+	}
+	if query.Until != nil {
+		db = db.Where("updated_at < ?", query.Until.UTC())
+	}
+
+	return db
+}
+
+func nextCursor(sort string, last Event) string {
+	c := cursor{Sort: sort, UUID: last.UUID}
+
+	switch strings.TrimPrefix(sort, "-") {
+	case SortCreatedAt:
+		c.Time = last.CreatedAt
+	case SortUpdatedAt:
+		c.Time = last.UpdatedAt
+	case SortTransitionedAt:
+		c.Time = last.TransitionedAt
+	}
+
+	return c.encode()
+}
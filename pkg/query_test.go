@@ -0,0 +1,139 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventOctopus_Query(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:query?mode=memory&cache=shared"
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	errorMsg := "error"
+	var events []Event
+	for j := 0; j < 5; j++ {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.RetryCount = j
+		e.TransactionID = e.UUID
+		if j == 4 {
+			e.Name = EventErrored
+			e.Error = &errorMsg
+		}
+		if err := i.SaveOrUpdateEvent(e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+
+	// all pages of the query
+	all := func(t *testing.T, query EventQuery) []string {
+		var uuids []string
+		for {
+			page, err := i.Query(query)
+			if !assert.NoError(t, err) {
+				return nil
+			}
+			for _, e := range page.Events {
+				uuids = append(uuids, e.UUID)
+			}
+			if page.Next == "" {
+				return uuids
+			}
+			query.Cursor = page.Next
+		}
+	}
+
+	t.Run("pages contain all events once", func(t *testing.T) {
+		page, err := i.Query(EventQuery{Limit: 2})
+		if assert.NoError(t, err) {
+			assert.Len(t, page.Events, 2)
+			assert.NotEmpty(t, page.Next)
+		}
+
+		uuids := all(t, EventQuery{Limit: 2})
+		assert.Len(t, uuids, 5)
+		for _, e := range events {
+			assert.Contains(t, uuids, e.UUID)
+		}
+	})
+
+	t.Run("pages follow the sort", func(t *testing.T) {
+		expected := []string{events[4].UUID, events[3].UUID, events[2].UUID, events[1].UUID, events[0].UUID}
+
+		assert.Equal(t, expected, all(t, EventQuery{Limit: 2, Sort: "-" + SortCreatedAt}))
+		assert.Equal(t, expected, all(t, EventQuery{Limit: 1, Sort: "-" + SortCreatedAt}))
+	})
+
+	t.Run("last page has no cursor", func(t *testing.T) {
+		page, err := i.Query(EventQuery{Limit: 5})
+		if assert.NoError(t, err) {
+			assert.Len(t, page.Events, 5)
+			assert.Empty(t, page.Next)
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		hasError := true
+		noError := false
+		min := 1
+		max := 2
+		future := time.Now().Add(time.Hour)
+
+		assert.Equal(t, []string{events[4].UUID}, all(t, EventQuery{Name: EventErrored}))
+		assert.Equal(t, []string{events[4].UUID}, all(t, EventQuery{HasError: &hasError}))
+		assert.Len(t, all(t, EventQuery{HasError: &noError}), 4)
+		assert.Equal(t, []string{events[1].UUID, events[2].UUID}, all(t, EventQuery{MinRetryCount: &min, MaxRetryCount: &max, Sort: SortCreatedAt}))
+		assert.Equal(t, []string{events[3].UUID}, all(t, EventQuery{TransactionID: events[3].UUID}))
+		assert.Equal(t, []string{events[2].UUID}, all(t, EventQuery{ConsentID: events[2].ConsentID}))
+		assert.Len(t, all(t, EventQuery{InitiatorLegalEntity: events[0].InitiatorLegalEntity}), 5)
+		assert.Len(t, all(t, EventQuery{Until: &future}), 5)
+		assert.Empty(t, all(t, EventQuery{Since: &future}))
+	})
+
+	t.Run("invalid cursor gives error", func(t *testing.T) {
+		_, err := i.Query(EventQuery{Cursor: "invalid"})
+
+		assert.True(t, errors.Is(err, ErrInvalidCursor))
+	})
+
+	t.Run("cursor for another sort gives error", func(t *testing.T) {
+		page, _ := i.Query(EventQuery{Limit: 1, Sort: SortCreatedAt})
+		_, err := i.Query(EventQuery{Limit: 1, Sort: SortUpdatedAt, Cursor: page.Next})
+
+		assert.True(t, errors.Is(err, ErrInvalidCursor))
+	})
+
+	t.Run("unknown sort field gives error", func(t *testing.T) {
+		_, err := i.Query(EventQuery{Sort: "name"})
+
+		assert.True(t, errors.Is(err, ErrUnknownSortField))
+	})
+}
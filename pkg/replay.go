@@ -0,0 +1,322 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	natsClient "github.com/nats-io/stan.go"
+	"github.com/sirupsen/logrus"
+)
+
+// SourceReplay is the channel recorded in the event history for events restored by a replay
+const SourceReplay = "replay"
+
+// replayTimeout is the time to wait for the next message of the event log
+var replayTimeout = 10 * time.Second
+
+// ErrReplayRunning is returned when a replay is started while another one is running
+var ErrReplayRunning = errors.New("replay already running")
+
+// ReplayStatus reports the progress of the last replay of the event log
+type ReplayStatus struct {
+	Running bool `json:"running"`
+	// LastSequence is the sequence number of the last message in the event log when the replay started
+	LastSequence uint64 `json:"lastSequence"`
+	// Sequence is the sequence number of the last replayed message
+	Sequence uint64 `json:"sequence"`
+	// Replayed is the number of events stored by the replay
+	Replayed   int        `json:"replayed"`
+	StartedAt  *time.Time `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt"`
+	Error      string     `json:"error"`
+}
+
+// ReplayStatus returns the progress of the running or last replay
+func (octopus *EventOctopus) ReplayStatus() ReplayStatus {
+	octopus.replayMutex.Lock()
+	defer octopus.replayMutex.Unlock()
+
+	return octopus.replayStatus
+}
+
+// StartReplay rebuilds the event store from the entire event log in the background, the progress is reported by ReplayStatus
+func (octopus *EventOctopus) StartReplay() error {
+	if err := octopus.startReplay(); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := octopus.runReplay(); err != nil {
+			logrus.WithError(err).Error("Replay of the event log failed")
+		}
+	}()
+
+	return nil
+}
+
+// Replay rebuilds the event store from the entire event log and returns when all events have been stored
+func (octopus *EventOctopus) Replay() error {
+	if err := octopus.startReplay(); err != nil {
+		return err
+	}
+
+	return octopus.runReplay()
+}
+
+func (octopus *EventOctopus) startReplay() error {
+	octopus.replayMutex.Lock()
+	defer octopus.replayMutex.Unlock()
+
+	if octopus.replayStatus.Running {
+		return ErrReplayRunning
+	}
+
+	startedAt := now()
+	octopus.replayStatus = ReplayStatus{
+		Running:   true,
+		StartedAt: &startedAt,
+	}
+
+	return nil
+}
+
+// replayChannels carry the states of events: the states published by the modules, the errored states and the retries
+// that ended in the terminal state of their retry policy
+var replayChannels = []string{ChannelConsentRequest, ChannelConsentErrored, ChannelConsentRetry}
+
+// replayChannel holds the messages of a channel that are not replayed yet
+type replayChannel struct {
+	name string
+	// last is the sequence number of the last message in the channel when the replay started
+	last     uint64
+	sequence uint64
+	msgs     chan *natsClient.Msg
+	head     *natsClient.Msg
+}
+
+// runReplay stores every event published to the replayChannels up to the last message at the start of the replay, in the
+// order the messages were published. Storing is idempotent: messages the event store already stored are skipped and the
+// state of each event ends up as its last message in the log.
+func (octopus *EventOctopus) runReplay() (err error) {
+	defer func() {
+		octopus.updateReplay(func(status *ReplayStatus) {
+			finishedAt := now()
+			status.Running = false
+			status.FinishedAt = &finishedAt
+			if err != nil {
+				status.Error = err.Error()
+			}
+		})
+	}()
+
+	conn, err := octopus.client(ClientID)
+	if err != nil {
+		return err
+	}
+
+	var channels []*replayChannel
+	for _, name := range replayChannels {
+		last, err := octopus.lastSequence(conn, name)
+		if err != nil {
+			return fmt.Errorf("Error while reading the last message of %s: %w", name, err)
+		}
+		if last > 0 {
+			channels = append(channels, &replayChannel{name: name, last: last, msgs: make(chan *natsClient.Msg, 64)})
+		}
+		if name == ChannelConsentRequest {
+			octopus.updateReplay(func(status *ReplayStatus) {
+				status.LastSequence = last
+			})
+		}
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+
+	logrus.Infof("Replaying event log up to message %d", octopus.ReplayStatus().LastSequence)
+
+	// the subscriptions block until the message has been taken, closing stop releases them
+	stop := make(chan struct{})
+	var subs []natsClient.Subscription
+	defer func() {
+		close(stop)
+		for _, sub := range subs {
+			_ = sub.Unsubscribe()
+		}
+	}()
+
+	for _, c := range channels {
+		c := c
+		sub, err := conn.Subscribe(c.name, func(msg *natsClient.Msg) {
+			if msg.Sequence > c.last {
+				return
+			}
+			select {
+			case c.msgs <- msg:
+			case <-stop:
+			}
+		}, natsClient.DeliverAllAvailable())
+		if err != nil {
+			return err
+		}
+		subs = append(subs, sub)
+	}
+
+	for {
+		next, err := nextReplayChannel(channels)
+		if err != nil || next == nil {
+			if err == nil {
+				logrus.Infof("Replayed %d events", octopus.ReplayStatus().Replayed)
+			}
+			return err
+		}
+
+		msg := next.head
+		next.head = nil
+		next.sequence = msg.Sequence
+
+		replayed, err := octopus.replayMsg(next.name, msg)
+		if err != nil {
+			return err
+		}
+
+		octopus.updateReplay(func(status *ReplayStatus) {
+			if next.name == ChannelConsentRequest {
+				status.Sequence = msg.Sequence
+			}
+			status.Replayed += replayed
+		})
+	}
+}
+
+// nextReplayChannel returns the channel with the first published message that is not replayed yet, nil when all
+// channels have been replayed
+func nextReplayChannel(channels []*replayChannel) (*replayChannel, error) {
+	var next *replayChannel
+
+	for _, c := range channels {
+		if c.head == nil && c.sequence < c.last {
+			select {
+			case c.head = <-c.msgs:
+			case <-time.After(replayTimeout):
+				return nil, fmt.Errorf("no message received from %s within %s after message %d of %d", c.name, replayTimeout, c.sequence, c.last)
+			}
+		}
+		if c.head != nil && (next == nil || c.head.Timestamp < next.head.Timestamp) {
+			next = c
+		}
+	}
+
+	return next, nil
+}
+
+// replayMsg stores the state of the event in the message, it returns 1 when the state was stored and 0 when it was skipped
+func (octopus *EventOctopus) replayMsg(channel string, msg *natsClient.Msg) (int, error) {
+	event := Event{}
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		// already dead-lettered when it was received the first time
+		logrus.WithError(err).Warnf("Skipping unreadable message %d of %s during replay", msg.Sequence, channel)
+		return 0, nil
+	}
+
+	if channel == ChannelConsentRetry {
+		// a retry that is not exhausted is published to ChannelConsentRequest again when it is due
+		terminal, ok := octopus.exhausted(event)
+		if !ok {
+			return 0, nil
+		}
+		event = terminal
+	}
+
+	stored, err := octopus.storedBefore(event)
+	if err != nil {
+		return 0, err
+	}
+	if stored {
+		logrus.Debugf("Skipping message %d of %s during replay, already stored", msg.Sequence, channel)
+		return 0, nil
+	}
+
+	if err := octopus.saveEvent(event, SourceReplay); errors.Is(err, ErrInvalidTransition) {
+		// the event store is further than the log, for instance when it has not been emptied
+		logrus.WithError(err).Debugf("Skipping message %d of %s during replay", msg.Sequence, channel)
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return 1, nil
+}
+
+func (octopus *EventOctopus) updateReplay(update func(status *ReplayStatus)) {
+	octopus.replayMutex.Lock()
+	defer octopus.replayMutex.Unlock()
+
+	update(&octopus.replayStatus)
+}
+
+// lastSequence returns the sequence number of the last message published to the channel or 0 when there are none.
+// The embedded server reports it on its monitoring endpoint. An external cluster is asked for the last message, which
+// it doesn't send when the channel is empty, so an empty channel of an external cluster can't be replayed.
+func (octopus *EventOctopus) lastSequence(conn natsClient.Conn, channel string) (uint64, error) {
+	if octopus.Config.embedded() {
+		channelz, err := octopus.channelz(channel)
+		if err != nil || channelz == nil {
+			return 0, err
+		}
+		return channelz.LastSeq, nil
+	}
+
+	sequences := make(chan uint64, 1)
+
+	sub, err := conn.Subscribe(channel, func(msg *natsClient.Msg) {
+		select {
+		case sequences <- msg.Sequence:
+		default:
+		}
+	}, natsClient.StartWithLastReceived())
+	if err != nil {
+		return 0, err
+	}
+	defer sub.Unsubscribe()
+
+	select {
+	case sequence := <-sequences:
+		return sequence, nil
+	case <-time.After(replayTimeout):
+		return 0, fmt.Errorf("no message received within %s, the channel is empty or Nats does not respond", replayTimeout)
+	}
+}
+
+// storedBefore returns true when the stored event already went through the state of the event with the same retry count.
+// History from before the stored event was created, for instance when only the events were lost, does not count.
+func (octopus *EventOctopus) storedBefore(event Event) (bool, error) {
+	count := 0
+
+	err := octopus.Db.Debug().Model(&EventHistory{}).
+		Joins("JOIN events ON events.uuid = event_history.event_uuid AND event_history.created_at >= events.created_at").
+		Where("event_history.event_uuid = ? AND event_history.name = ? AND event_history.retry_count = ? AND event_history.rejected = ?", event.UUID, event.Name, event.RetryCount, false).
+		Count(&count).Error
+
+	return count > 0, err
+}
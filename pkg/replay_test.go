@@ -0,0 +1,226 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventOctopus_Replay(t *testing.T) {
+	replayTimeout = time.Second
+
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:replay?mode=memory&cache=shared"
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	t.Run("empty event log is replayed", func(t *testing.T) {
+		if !assert.NoError(t, i.Replay()) {
+			return
+		}
+
+		status := i.ReplayStatus()
+		assert.False(t, status.Running)
+		assert.Equal(t, 0, status.Replayed)
+		assert.NotNil(t, status.FinishedAt)
+	})
+
+	t.Run("replay restores the state of the event store", func(t *testing.T) {
+		publisher, _ := i.EventPublisher("event-octopus-test")
+
+		var uuids []string
+		for j := 0; j < 3; j++ {
+			e := event()
+			e.UUID = uuid.NewV4().String()
+			uuids = append(uuids, e.UUID)
+			for _, name := range []string{EventConsentRequestConstructed, EventConsentRequestInFlight, EventConsentRequestFlowSuccess}[:j+1] {
+				e.Name = name
+				_ = publisher.Publish(ChannelConsentRequest, e)
+			}
+		}
+
+		// wait for the last state of the last event
+		for j := 0; j < 100; j++ {
+			stored, _ := i.GetEvent(uuids[2])
+			if stored != nil && stored.Name == EventConsentRequestFlowSuccess {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		before, _ := i.List()
+		emptyTable(i)
+		after, _ := i.List()
+		if !assert.Len(t, *after, 0) {
+			return
+		}
+
+		if !assert.NoError(t, i.Replay()) {
+			return
+		}
+
+		after, _ = i.List()
+		if assert.Len(t, *after, len(*before)) {
+			replayed := byUUID(*after)
+			for _, e := range *before {
+				r := replayed[e.UUID]
+				assert.Equal(t, e.Name, r.Name)
+				assert.Equal(t, e.RetryCount, r.RetryCount)
+				assert.Equal(t, e.ExternalID, r.ExternalID)
+				assert.Equal(t, e.Payload, r.Payload)
+				assert.Equal(t, e.InitiatorLegalEntity, r.InitiatorLegalEntity)
+			}
+		}
+
+		status := i.ReplayStatus()
+		assert.Equal(t, 6, status.Replayed)
+		assert.Equal(t, status.LastSequence, status.Sequence)
+		assert.Empty(t, status.Error)
+
+		history, _ := i.GetEventHistory(uuids[0])
+		if assert.NotEmpty(t, history) {
+			assert.Equal(t, SourceReplay, history[len(history)-1].Channel)
+		}
+	})
+
+	t.Run("replaying twice gives the same state", func(t *testing.T) {
+		before, _ := i.List()
+		historyBefore := map[string]int{}
+		for _, e := range *before {
+			history, _ := i.GetEventHistory(e.UUID)
+			historyBefore[e.UUID] = len(history)
+		}
+
+		if !assert.NoError(t, i.Replay()) {
+			return
+		}
+
+		after, _ := i.List()
+		if assert.Len(t, *after, len(*before)) {
+			replayed := byUUID(*after)
+			for _, e := range *before {
+				assert.Equal(t, e.Name, replayed[e.UUID].Name)
+				assert.Equal(t, e.Version, replayed[e.UUID].Version)
+
+				history, _ := i.GetEventHistory(e.UUID)
+				assert.Len(t, history, historyBefore[e.UUID])
+			}
+		}
+		assert.Equal(t, 0, i.ReplayStatus().Replayed)
+	})
+
+	t.Run("replay in the background", func(t *testing.T) {
+		if !assert.NoError(t, i.StartReplay()) {
+			return
+		}
+
+		assert.Equal(t, ErrReplayRunning, i.StartReplay())
+
+		for j := 0; j < 100 && i.ReplayStatus().Running; j++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		// all states have been stored before
+		status := i.ReplayStatus()
+		assert.False(t, status.Running)
+		assert.Equal(t, 0, status.Replayed)
+		assert.Equal(t, status.LastSequence, status.Sequence)
+	})
+
+	t.Run("errored and retry-exhausted events are replayed", func(t *testing.T) {
+		publisher, _ := i.EventPublisher("event-octopus-test")
+
+		waitFor := func(uuid, name string) {
+			for j := 0; j < 100; j++ {
+				stored, _ := i.GetEvent(uuid)
+				if stored != nil && stored.Name == name {
+					return
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			assert.Fail(t, "event not stored", "%s in state %s", uuid, name)
+		}
+
+		errored := event()
+		errored.UUID = uuid.NewV4().String()
+		_ = publisher.Publish(ChannelConsentRequest, errored)
+		waitFor(errored.UUID, errored.Name)
+		errStr := "vendor rejected the request"
+		errored.Name = EventErrored
+		errored.Error = &errStr
+		_ = publisher.Publish(ChannelConsentErrored, errored)
+		waitFor(errored.UUID, EventErrored)
+
+		exhausted := event()
+		exhausted.UUID = uuid.NewV4().String()
+		_ = publisher.Publish(ChannelConsentRequest, exhausted)
+		waitFor(exhausted.UUID, exhausted.Name)
+		exhausted.RetryCount = ConfigMaxRetryCountDefault
+		_ = publisher.Publish(ChannelConsentRetry, exhausted)
+		waitFor(exhausted.UUID, EventErrored)
+
+		before, _ := i.List()
+		emptyTable(i)
+
+		if !assert.NoError(t, i.Replay()) {
+			return
+		}
+
+		after, _ := i.List()
+		if assert.Len(t, *after, len(*before)) {
+			replayed := byUUID(*after)
+			for _, e := range *before {
+				r := replayed[e.UUID]
+				assert.Equal(t, e.Name, r.Name)
+				assert.Equal(t, e.RetryCount, r.RetryCount)
+				assert.Equal(t, e.Error, r.Error)
+				assert.Equal(t, e.Payload, r.Payload)
+			}
+			assert.Equal(t, EventErrored, replayed[errored.UUID].Name)
+			assert.Equal(t, EventErrored, replayed[exhausted.UUID].Name)
+			if assert.NotNil(t, replayed[exhausted.UUID].Error) {
+				assert.Equal(t, errMaxRetryCount, *replayed[exhausted.UUID].Error)
+			}
+		}
+	})
+
+	t.Run("unknown end of an external event log is an error", func(t *testing.T) {
+		external := testEventOctopus()
+		external.Config.NatsURL = "nats://localhost:4222"
+		conn, _ := i.client(ClientID)
+
+		_, err := external.lastSequence(conn, "replay-empty")
+
+		assert.Error(t, err)
+	})
+}
+
+func byUUID(events []Event) map[string]Event {
+	m := make(map[string]Event, len(events))
+	for _, e := range events {
+		m[e.UUID] = e
+	}
+	return m
+}
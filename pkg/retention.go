@@ -0,0 +1,392 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// retentionBatchSize is the max number of events removed in a single transaction
+const retentionBatchSize = 500
+
+// errPurgeStopped is returned when the janitor is stopped while it removes events, the remaining batches are left for the next run
+var errPurgeStopped = errors.New("purge stopped")
+
+// RetentionRule removes events in a state after they have been in that state for MaxAge or when there are more than MaxCount of them.
+// A rule without MaxAge and MaxCount removes all events in the state.
+type RetentionRule struct {
+	// Name is the name of the state the rule applies to
+	Name string
+	// MaxAge is the time an event is kept after it entered the state, 0 keeps events regardless of their age
+	MaxAge time.Duration
+	// MaxCount is the number of events kept, the events that entered the state first are removed first. 0 keeps events regardless of their number
+	MaxCount int
+}
+
+// RetentionReport is the outcome of a single enforcement of the retention rules
+type RetentionReport struct {
+	// DryRun is true when no events were removed
+	DryRun     bool
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// Purged is the number of events removed per state, or the number that would be removed in a dry run
+	Purged map[string]int
+	Error  string
+}
+
+// RetentionStatus reports the retention rules and what they removed
+type RetentionStatus struct {
+	Rules []RetentionRule
+	// LastRun is the last enforcement of the rules, nil when the rules have not been enforced yet
+	LastRun *RetentionReport
+	// TotalPurged is the number of events removed per state since the start, dry runs excluded
+	TotalPurged map[string]int
+}
+
+// parseRetentionRules parses comma separated rules formatted as {name}[={maxAge}][/{maxCount}], for instance:
+// "completed=168h,error=2160h/1000". The max age is a duration like 720h, a name without limits removes all events in that state.
+func parseRetentionRules(rules string) ([]RetentionRule, error) {
+	var parsed []RetentionRule
+
+	for _, r := range strings.Split(rules, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+
+		rule := RetentionRule{}
+		limits := ""
+		if i := strings.Index(r, "="); i >= 0 {
+			rule.Name, limits = strings.TrimSpace(r[:i]), strings.TrimSpace(r[i+1:])
+		} else {
+			rule.Name = r
+		}
+
+		if !isState(rule.Name) {
+			return nil, fmt.Errorf("invalid %s: unknown state %s", ConfigRetentionRules, rule.Name)
+		}
+		for _, p := range parsed {
+			if p.Name == rule.Name {
+				return nil, fmt.Errorf("invalid %s: more than one rule for %s", ConfigRetentionRules, rule.Name)
+			}
+		}
+
+		age, count := limits, ""
+		if i := strings.Index(limits, "/"); i >= 0 {
+			age, count = limits[:i], limits[i+1:]
+		}
+
+		var err error
+		if age != "" {
+			if rule.MaxAge, err = time.ParseDuration(age); err != nil || rule.MaxAge < 0 {
+				return nil, fmt.Errorf("invalid %s: invalid max age for %s: %s", ConfigRetentionRules, rule.Name, age)
+			}
+		}
+		if count != "" {
+			if rule.MaxCount, err = strconv.Atoi(count); err != nil || rule.MaxCount < 0 {
+				return nil, fmt.Errorf("invalid %s: invalid max count for %s: %s", ConfigRetentionRules, rule.Name, count)
+			}
+		}
+
+		parsed = append(parsed, rule)
+	}
+
+	return parsed, nil
+}
+
+// configureRetention parses the retention rules, the deprecated purgeCompleted adds a rule removing all completed events
+func (octopus *EventOctopus) configureRetention() error {
+	rules, err := parseRetentionRules(octopus.Config.RetentionRules)
+	if err != nil {
+		return err
+	}
+
+	if octopus.Config.PurgeCompleted {
+		found := false
+		for _, r := range rules {
+			found = found || r.Name == EventCompleted
+		}
+		if !found {
+			rules = append(rules, RetentionRule{Name: EventCompleted})
+		}
+	}
+
+	octopus.retentionMutex.Lock()
+	defer octopus.retentionMutex.Unlock()
+
+	octopus.retentionRules = rules
+	octopus.retentionStatus = RetentionStatus{TotalPurged: map[string]int{}}
+
+	return nil
+}
+
+// RetentionStatus returns the retention rules, the last enforcement and the number of removed events
+func (octopus *EventOctopus) RetentionStatus() RetentionStatus {
+	octopus.retentionMutex.Lock()
+	defer octopus.retentionMutex.Unlock()
+
+	status := RetentionStatus{
+		Rules:       append([]RetentionRule{}, octopus.retentionRules...),
+		LastRun:     octopus.retentionStatus.LastRun,
+		TotalPurged: map[string]int{},
+	}
+	for name, purged := range octopus.retentionStatus.TotalPurged {
+		status.TotalPurged[name] = purged
+	}
+
+	return status
+}
+
+// Purge removes the events matching the retention rules together with their history.
+// A dry run only reports the number of events that would be removed.
+func (octopus *EventOctopus) Purge(dryRun bool) (RetentionReport, error) {
+	return octopus.purge(dryRun, nil)
+}
+
+// purge enforces the retention rules until stop is closed, it is checked between batches
+func (octopus *EventOctopus) purge(dryRun bool, stop <-chan struct{}) (RetentionReport, error) {
+	octopus.purgeMutex.Lock()
+	defer octopus.purgeMutex.Unlock()
+
+	report := RetentionReport{
+		DryRun:    dryRun,
+		StartedAt: now(),
+		Purged:    map[string]int{},
+	}
+
+	err := octopus.enforceRetention(octopus.RetentionStatus().Rules, &report, stop)
+	report.FinishedAt = now()
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	octopus.retentionMutex.Lock()
+	octopus.retentionStatus.LastRun = &report
+	if octopus.retentionStatus.TotalPurged == nil {
+		octopus.retentionStatus.TotalPurged = map[string]int{}
+	}
+	if !dryRun {
+		for name, purged := range report.Purged {
+			octopus.retentionStatus.TotalPurged[name] += purged
+			eventsPurged.WithLabelValues(name).Add(float64(purged))
+		}
+	}
+	octopus.retentionMutex.Unlock()
+
+	if dryRun {
+		logrus.Infof("Retention dry run: %s would be purged", formatPurged(report.Purged))
+	} else {
+		logrus.Infof("Retention: %s purged", formatPurged(report.Purged))
+	}
+
+	return report, err
+}
+
+func (octopus *EventOctopus) enforceRetention(rules []RetentionRule, report *RetentionReport, stop <-chan struct{}) error {
+	for _, rule := range rules {
+		uuids, cutoff, err := octopus.retentionCandidates(rule)
+		if err != nil {
+			return err
+		}
+
+		purged := len(uuids)
+		if !report.DryRun {
+			purged, err = octopus.deleteEvents(rule.Name, cutoff, uuids, stop)
+			report.Purged[rule.Name] = purged
+			if err != nil {
+				return err
+			}
+		}
+
+		report.Purged[rule.Name] = purged
+	}
+
+	return nil
+}
+
+// retentionCandidates returns the uuids of the events removed by the rule and the moment the last of them entered the state
+func (octopus *EventOctopus) retentionCandidates(rule RetentionRule) ([]string, time.Time, error) {
+	var (
+		uuids  []string
+		cutoff time.Time
+	)
+
+	query := octopus.Db.Debug().Model(&Event{}).Select("uuid, transitioned_at").Where("name = ?", rule.Name)
+	candidates := map[string]time.Time{}
+
+	if rule.MaxAge == 0 && rule.MaxCount == 0 {
+		var all []Event
+		if err := query.Find(&all).Error; err != nil {
+			return nil, cutoff, err
+		}
+		for _, e := range all {
+			candidates[e.UUID] = e.TransitionedAt
+		}
+	}
+
+	if rule.MaxAge > 0 {
+		var expired []Event
+		if err := query.Where("transitioned_at < ?", now().Add(-rule.MaxAge)).Find(&expired).Error; err != nil {
+			return nil, cutoff, err
+		}
+		for _, e := range expired {
+			candidates[e.UUID] = e.TransitionedAt
+		}
+	}
+
+	if rule.MaxCount > 0 {
+		var all []Event
+		if err := query.Order("transitioned_at DESC").Order("uuid DESC").Find(&all).Error; err != nil {
+			return nil, cutoff, err
+		}
+		for i := rule.MaxCount; i < len(all); i++ {
+			candidates[all[i].UUID] = all[i].TransitionedAt
+		}
+	}
+
+	for u, transitionedAt := range candidates {
+		uuids = append(uuids, u)
+		if transitionedAt.After(cutoff) {
+			cutoff = transitionedAt
+		}
+	}
+	sort.Strings(uuids)
+
+	return uuids, cutoff, nil
+}
+
+// deleteEvents removes the events and their history in batches. Events that left the state or entered it again after the cutoff,
+// for instance by a manual retry since they were selected, are kept. It returns the number of removed events.
+// Closing stop ends the removal after the running batch.
+func (octopus *EventOctopus) deleteEvents(name string, cutoff time.Time, uuids []string, stop <-chan struct{}) (int, error) {
+	deleted := 0
+
+	for start := 0; start < len(uuids); start += retentionBatchSize {
+		select {
+		case <-stop:
+			return deleted, errPurgeStopped
+		default:
+		}
+
+		end := start + retentionBatchSize
+		if end > len(uuids) {
+			end = len(uuids)
+		}
+		batch := uuids[start:end]
+
+		tx := octopus.Db.Begin()
+		if err := tx.Error; err != nil {
+			return deleted, err
+		}
+
+		result := tx.Debug().Where("uuid IN (?) AND name = ? AND transitioned_at <= ?", batch, name, cutoff).Delete(Event{})
+		if result.Error != nil {
+			tx.Rollback()
+			return deleted, result.Error
+		}
+
+		// only the history of the removed events
+		remaining := tx.Table("events").Select("uuid").Where("uuid IN (?)", batch).SubQuery()
+		if err := tx.Debug().Where("event_uuid IN (?) AND event_uuid NOT IN ?", batch, remaining).Delete(EventHistory{}).Error; err != nil {
+			tx.Rollback()
+			return deleted, err
+		}
+
+		if err := tx.Commit().Error; err != nil {
+			return deleted, err
+		}
+		deleted += int(result.RowsAffected)
+	}
+
+	return deleted, nil
+}
+
+// startJanitor enforces the retention rules at the start and every retentionInterval seconds, or only at the start when the interval is 0
+func (octopus *EventOctopus) startJanitor() {
+	if len(octopus.RetentionStatus().Rules) == 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	octopus.janitorStop = stop
+
+	go func() {
+		octopus.runJanitor(stop)
+
+		if octopus.Config.RetentionInterval <= 0 {
+			return
+		}
+
+		ticker := time.NewTicker(time.Duration(octopus.Config.RetentionInterval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				octopus.runJanitor(stop)
+			}
+		}
+	}()
+}
+
+func (octopus *EventOctopus) runJanitor(stop <-chan struct{}) {
+	octopus.inFlight.start()
+	defer octopus.inFlight.done()
+
+	_, err := octopus.purge(octopus.Config.RetentionDryRun, stop)
+	if errors.Is(err, errPurgeStopped) {
+		logrus.Info("Retention stopped, remaining events are purged at the next run")
+	} else if err != nil {
+		logrus.WithError(err).Error("Failed to enforce retention rules")
+	}
+}
+
+// stopJanitor stops enforcing the retention rules
+func (octopus *EventOctopus) stopJanitor() {
+	if octopus.janitorStop != nil {
+		close(octopus.janitorStop)
+		octopus.janitorStop = nil
+	}
+}
+
+func formatPurged(purged map[string]int) string {
+	var names []string
+	for name := range purged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	total := 0
+	var parts []string
+	for _, name := range names {
+		total += purged[name]
+		parts = append(parts, fmt.Sprintf("%s: %d", name, purged[name]))
+	}
+
+	return fmt.Sprintf("%d events (%s)", total, strings.Join(parts, ", "))
+}
@@ -0,0 +1,246 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetentionRules(t *testing.T) {
+	t.Run("parses rules", func(t *testing.T) {
+		rules, err := parseRetentionRules("completed=168h, error=2160h/1000,consentRequest nacked=/10 ,closed")
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, []RetentionRule{
+				{Name: EventCompleted, MaxAge: 168 * time.Hour},
+				{Name: EventErrored, MaxAge: 2160 * time.Hour, MaxCount: 1000},
+				{Name: EventConsentRequestNacked, MaxCount: 10},
+				{Name: EventClosed},
+			}, rules)
+		}
+	})
+
+	t.Run("empty rules", func(t *testing.T) {
+		rules, err := parseRetentionRules("")
+
+		assert.NoError(t, err)
+		assert.Empty(t, rules)
+	})
+
+	t.Run("returns error for invalid rules", func(t *testing.T) {
+		for _, rules := range []string{
+			"unknown=1h",
+			"completed=1d",
+			"completed=-1h",
+			"completed=1h/many",
+			"completed=/-1",
+			"completed=1h,completed=2h",
+		} {
+			_, err := parseRetentionRules(rules)
+			assert.Error(t, err, rules)
+		}
+	})
+}
+
+func TestEventOctopus_configureRetention(t *testing.T) {
+	t.Run("purgeCompleted adds a rule for completed events", func(t *testing.T) {
+		i := testEventOctopus()
+		i.Config.RetentionRules = "error=1h"
+		i.Config.PurgeCompleted = true
+
+		assert.NoError(t, i.configureRetention())
+		assert.Equal(t, []RetentionRule{{Name: EventErrored, MaxAge: time.Hour}, {Name: EventCompleted}}, i.RetentionStatus().Rules)
+	})
+
+	t.Run("purgeCompleted keeps an existing rule for completed events", func(t *testing.T) {
+		i := testEventOctopus()
+		i.Config.RetentionRules = "completed=1h"
+		i.Config.PurgeCompleted = true
+
+		assert.NoError(t, i.configureRetention())
+		assert.Equal(t, []RetentionRule{{Name: EventCompleted, MaxAge: time.Hour}}, i.RetentionStatus().Rules)
+	})
+}
+
+func TestEventOctopus_Purge(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:retention?mode=memory&cache=shared"
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	// store saves events that entered their state the given time ago
+	store := func(name string, ages ...time.Duration) []string {
+		var uuids []string
+		for _, age := range ages {
+			e := event()
+			e.UUID = uuid.NewV4().String()
+			e.Name = name
+			_ = i.SaveOrUpdateEvent(e)
+			i.Db.Model(&Event{}).Where("uuid = ?", e.UUID).UpdateColumn("transitioned_at", now().Add(-age))
+			uuids = append(uuids, e.UUID)
+		}
+		return uuids
+	}
+
+	exists := func(u string) bool {
+		e, _ := i.GetEvent(u)
+		return e != nil
+	}
+
+	setRules := func(t *testing.T, rules string) {
+		i.Config.RetentionRules = rules
+		if err := i.configureRetention(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("events older than max age are purged", func(t *testing.T) {
+		setRules(t, "completed=24h")
+		old := store(EventCompleted, 48*time.Hour)
+		recent := store(EventCompleted, time.Hour)
+		other := store(EventConsentDistributed, 48*time.Hour)
+
+		report, err := i.Purge(false)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, map[string]int{EventCompleted: 1}, report.Purged)
+			assert.False(t, report.DryRun)
+		}
+		assert.False(t, exists(old[0]))
+		assert.True(t, exists(recent[0]))
+		assert.True(t, exists(other[0]))
+
+		history, _ := i.GetEventHistory(old[0])
+		assert.Empty(t, history)
+	})
+
+	t.Run("events exceeding max count are purged, the oldest first", func(t *testing.T) {
+		setRules(t, "error=/2")
+		uuids := store(EventErrored, 3*time.Hour, time.Hour, 2*time.Hour, 4*time.Hour)
+
+		report, _ := i.Purge(false)
+
+		assert.Equal(t, 2, report.Purged[EventErrored])
+		assert.True(t, exists(uuids[1]))
+		assert.True(t, exists(uuids[2]))
+		assert.False(t, exists(uuids[0]))
+		assert.False(t, exists(uuids[3]))
+	})
+
+	t.Run("errored events can be kept longer than completed events", func(t *testing.T) {
+		setRules(t, "completed=24h,error=720h")
+		completed := store(EventCompleted, 48*time.Hour)
+		errored := store(EventErrored, 48*time.Hour)
+
+		_, _ = i.Purge(false)
+
+		assert.False(t, exists(completed[0]))
+		assert.True(t, exists(errored[0]))
+	})
+
+	t.Run("events that changed state after selection are kept", func(t *testing.T) {
+		rule := RetentionRule{Name: EventErrored, MaxAge: 24 * time.Hour}
+		uuids := store(EventErrored, 48*time.Hour, 48*time.Hour, 48*time.Hour)
+
+		candidates, cutoff, err := i.retentionCandidates(rule)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		// retried manually
+		moved, _ := i.GetEvent(uuids[0])
+		moved.Name = EventConsentRequestInFlight
+		_ = i.SaveOrUpdateEvent(*moved)
+		// retried and errored again
+		again, _ := i.GetEvent(uuids[1])
+		again.Name = EventConsentRequestInFlight
+		_ = i.SaveOrUpdateEvent(*again)
+		again.Name = EventErrored
+		_ = i.SaveOrUpdateEvent(*again)
+
+		deleted, err := i.deleteEvents(rule.Name, cutoff, candidates, nil)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, len(candidates)-2, deleted)
+		}
+		assert.True(t, exists(uuids[0]))
+		assert.True(t, exists(uuids[1]))
+		assert.False(t, exists(uuids[2]))
+		history, _ := i.GetEventHistory(uuids[0])
+		assert.NotEmpty(t, history)
+	})
+
+	t.Run("stopped janitor leaves the remaining batches", func(t *testing.T) {
+		setRules(t, "consentRequest nacked")
+		uuids := store(EventConsentRequestNacked, time.Hour)
+		stop := make(chan struct{})
+		close(stop)
+
+		report, err := i.purge(false, stop)
+
+		assert.True(t, errors.Is(err, errPurgeStopped))
+		assert.Equal(t, 0, report.Purged[EventConsentRequestNacked])
+		assert.True(t, exists(uuids[0]))
+	})
+
+	t.Run("dry run does not purge", func(t *testing.T) {
+		setRules(t, "closed")
+		uuids := store(EventClosed, time.Hour, time.Hour)
+
+		report, err := i.Purge(true)
+
+		if assert.NoError(t, err) {
+			assert.True(t, report.DryRun)
+			assert.Equal(t, 2, report.Purged[EventClosed])
+		}
+		assert.True(t, exists(uuids[0]))
+		assert.True(t, exists(uuids[1]))
+
+		status := i.RetentionStatus()
+		assert.Equal(t, &report, status.LastRun)
+		assert.Equal(t, 0, status.TotalPurged[EventClosed])
+
+		_, _ = i.Purge(false)
+
+		assert.False(t, exists(uuids[0]))
+		assert.Equal(t, 2, i.RetentionStatus().TotalPurged[EventClosed])
+	})
+
+	t.Run("janitor enforces the rules at the start", func(t *testing.T) {
+		setRules(t, "completed")
+		uuids := store(EventCompleted, time.Hour)
+		i.Config.RetentionInterval = 0
+
+		i.startJanitor()
+		defer i.stopJanitor()
+
+		for j := 0; j < 100 && exists(uuids[0]); j++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.False(t, exists(uuids[0]))
+	})
+}
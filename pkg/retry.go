@@ -20,19 +20,30 @@ package pkg
 
 import (
 	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
 	"github.com/nats-io/stan.go"
 	"github.com/sirupsen/logrus"
-	"time"
 )
 
-// DelayedConsumer holds info for creating a subscription on Nats for consuming events and re-publishing them with a certain delay
+// maxRetryJitter is the max value of retryJitter, a jitter of 100% at most doubles the delay
+const maxRetryJitter = 100
+
+// delayedConsumerAckWait is the time a DelayedConsumer has to store a message before Nats redelivers it
+const delayedConsumerAckWait = 30 * time.Second
+
+// DelayedConsumer holds info for creating a subscription on Nats for consuming events and re-publishing them with a certain delay.
+// The messages are acked once they are stored by the Scheduler, which publishes them when they are due.
 type DelayedConsumer struct {
 	consumeSubject string        // Channel/topic to read from
 	publishSubject string        // Channel to publish to
-	delay          time.Duration // time to wait for sending ack
-	conn           stan.Conn     // ackWait must match!
+	delay          time.Duration // time between publishing on the consume subject and on the publish subject
+	jitter         time.Duration // max random time added to the delay
+	conn           stan.Conn
+	scheduler      Scheduler
 	subscription   stan.Subscription
-	shutdown 	   bool
 }
 
 // Start starts the subscription on the given connection
@@ -40,10 +51,13 @@ func (dc *DelayedConsumer) Start() error {
 	var err error
 
 	dc.subscription, err = dc.conn.Subscribe(dc.consumeSubject, func(msg *stan.Msg) {
-		// delegate to go procedure
-		go dc.delayedPublishAndAck(msg)
+		if msg.Redelivered {
+			retryRedeliveries.WithLabelValues(dc.consumeSubject).Inc()
+		}
+
+		dc.scheduleAndAck(msg)
 	}, stan.DurableName(fmt.Sprintf("%s-%s", dc.consumeSubject, "durable")),
-		stan.AckWait(time.Second+dc.delay), // some extra time for publishing
+		stan.AckWait(delayedConsumerAckWait),
 		stan.SetManualAckMode(),
 		stan.StartWithLastReceived(),
 	)
@@ -57,56 +71,121 @@ func (dc *DelayedConsumer) Start() error {
 	return nil
 }
 
-func (dc *DelayedConsumer) delayedPublishAndAck(msg *stan.Msg) {
-	targetTime := time.Now().Add(dc.delay)
+// scheduleAndAck schedules the message for the moment it was published plus the delay, it is only acked once it is stored
+func (dc *DelayedConsumer) scheduleAndAck(msg *stan.Msg) {
+	dueAt := time.Unix(0, msg.Timestamp).Add(dc.delay)
+	if dc.jitter > 0 {
+		dueAt = dueAt.Add(time.Duration(rand.Int63n(int64(dc.jitter))))
+	}
 
-	for {
-		time.Sleep(10 * time.Millisecond)
-		if dc.shutdown {
-			return
-		}
-		if time.Now().After(targetTime) {
-			break
-		}
+	err := dc.scheduler.Schedule(ScheduledMessage{
+		Queue:    dc.consumeSubject,
+		Sequence: msg.Sequence,
+		Subject:  dc.publishSubject,
+		Data:     string(msg.Data),
+		DueAt:    dueAt,
+	})
+	if err != nil {
+		logrus.WithError(err).Errorf("Failed to schedule message %d from %s, awaiting redelivery", msg.Sequence, dc.consumeSubject)
+		messageFailures.WithLabelValues(dc.consumeSubject).Inc()
+		return
 	}
 
-	if dc.conn.NatsConn() != nil {
-		if dc.conn.NatsConn().IsConnected() {
-			if err := dc.conn.Publish(dc.publishSubject, msg.Data); err != nil {
-				logrus.WithError(err).Fatal("failed to publish delayed message")
-			}
-			if err := msg.Ack(); err != nil {
-				logrus.WithError(err).Fatal("failed to ack retry message")
-			}
-		} else {
-			logrus.Warnf("ignoring retry message, no connection available, current status: %d", dc.conn.NatsConn().Status())
-		}
+	if err := msg.Ack(); err != nil {
+		logrus.WithError(err).Errorf("Failed to ack retry message %d from %s", msg.Sequence, dc.consumeSubject)
 	}
 }
 
 // NewDelayedConsumerSet creates a set of DelayedConsumer where each successive poller has a interval which is exponent times bigger than the previous one
-func NewDelayedConsumerSet(consumeSubject string, publishSubject string, count int, interval time.Duration, exponent int, conn stan.Conn) []*DelayedConsumer {
+func NewDelayedConsumerSet(consumeSubject string, publishSubject string, count int, interval time.Duration, exponent int, conn stan.Conn, scheduler Scheduler) []*DelayedConsumer {
+	return NewDelayedConsumers(consumeSubject, publishSubject, geometricDelays(count, interval, exponent), 0, conn, scheduler)
+}
+
+// NewDelayedConsumers creates a DelayedConsumer for every delay, consuming from {consumeSubject}-{index}.
+// Jitter is the max percentage of the delay that is randomly added to it, spreading retries that failed at the same time.
+func NewDelayedConsumers(consumeSubject string, publishSubject string, delays []time.Duration, jitter int, conn stan.Conn, scheduler Scheduler) []*DelayedConsumer {
 	var pollers []*DelayedConsumer
 
-	expInterval := interval
-	for i := 0; i < count; i++ {
+	for i, delay := range delays {
 		name := fmt.Sprintf("%s-%d", consumeSubject, i)
 
 		pollers = append(pollers, &DelayedConsumer{
 			consumeSubject: name,
 			publishSubject: publishSubject,
-			delay:          expInterval,
+			delay:          delay,
+			jitter:         delay * time.Duration(jitter) / 100,
 			conn:           conn,
+			scheduler:      scheduler,
 		})
+	}
+
+	return pollers
+}
+
+// geometricDelays returns count delays, starting at interval and each one exponent times bigger than the previous one
+func geometricDelays(count int, interval time.Duration, exponent int) []time.Duration {
+	var delays []time.Duration
 
+	expInterval := interval
+	for i := 0; i < count; i++ {
+		delays = append(delays, expInterval)
 		expInterval *= time.Duration(exponent)
 	}
 
-	return pollers
+	return delays
 }
 
-// Stop stops the consumer
+// parseDelays parses comma separated delays like "1s,1m,15m,1h,24h,168h", one for every retry. The config name is used in errors.
+func parseDelays(config string, schedule string) ([]time.Duration, error) {
+	var delays []time.Duration
+
+	for _, d := range strings.Split(schedule, ",") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+
+		delay, err := time.ParseDuration(d)
+		if err != nil || delay <= 0 {
+			return nil, fmt.Errorf("invalid %s: invalid delay %s", config, d)
+		}
+
+		delays = append(delays, delay)
+	}
+
+	return delays, nil
+}
+
+// configureRetrySchedule validates the retry schedule and jitter. Without a schedule, the delays follow from
+// maxRetryCount and incrementalBackoff.
+func (octopus *EventOctopus) configureRetrySchedule() error {
+	if octopus.Config.RetryJitter < 0 || octopus.Config.RetryJitter > maxRetryJitter {
+		return fmt.Errorf("invalid %s: %d, must be between 0 and %d", ConfigRetryJitter, octopus.Config.RetryJitter, maxRetryJitter)
+	}
+
+	schedule, err := parseDelays(ConfigRetrySchedule, octopus.Config.RetrySchedule)
+	if err != nil {
+		return err
+	}
+
+	octopus.retrySchedule = schedule
+
+	return nil
+}
+
+// retryDelays returns the delay of every retry queue, the number of delays is the max number of retries of an event
+func (octopus *EventOctopus) retryDelays() []time.Duration {
+	if len(octopus.retrySchedule) > 0 {
+		return octopus.retrySchedule
+	}
+
+	return geometricDelays(octopus.Config.MaxRetryCount, time.Second, octopus.Config.IncrementalBackoff)
+}
+
+// Stop stops the consumer, messages that are already scheduled are still published when they are due
 func (dc *DelayedConsumer) Stop() error {
-	dc.shutdown = true
+	if dc.subscription == nil {
+		return nil
+	}
 	return dc.subscription.Close()
 }
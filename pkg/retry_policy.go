@@ -0,0 +1,177 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nats-io/stan.go"
+)
+
+// RetryPolicy decides how often and with which delays events with a name, and optionally an error class, are retried
+type RetryPolicy struct {
+	// Name of the events the policy applies to, empty for the default policy
+	Name string
+	// ErrorClass of the events the policy applies to, empty applies the policy to all errors
+	ErrorClass string
+	// Delays holds the delay of every retry, the number of delays is the max number of retries
+	Delays []time.Duration
+	// Terminal is the name an event gets when it reached the max number of retries
+	Terminal string
+}
+
+// errorClassPattern restricts error classes to characters that are valid in a Nats channel name
+var errorClassPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// subject returns the prefix of the retry channels of the policy, the default policy uses ChannelConsentRetry
+func (p RetryPolicy) subject() string {
+	if p.Name == "" {
+		return ChannelConsentRetry
+	}
+
+	subject := fmt.Sprintf("%s-%s", ChannelConsentRetry, strings.ReplaceAll(p.Name, " ", "_"))
+	if p.ErrorClass != "" {
+		subject = fmt.Sprintf("%s-%s", subject, p.ErrorClass)
+	}
+
+	return subject
+}
+
+// parseRetryPolicies parses semicolon separated policies formatted as {name}[/{errorClass}]=[{delays}][>{terminal}], for instance:
+// "consentRequest constructed/corda=1s,1m,15m>error;consentRequest in flight=10s,30s". The delays are comma separated durations,
+// a policy without delays doesn't retry. Events get the error state after the last retry unless a terminal name is given.
+func parseRetryPolicies(policies string) ([]RetryPolicy, error) {
+	var parsed []RetryPolicy
+
+	for _, p := range strings.Split(policies, ";") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		i := strings.Index(p, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid %s: missing delays for %s", ConfigRetryPolicies, p)
+		}
+		key, value := strings.TrimSpace(p[:i]), strings.TrimSpace(p[i+1:])
+
+		policy := RetryPolicy{Name: key, Terminal: EventErrored}
+		if i := strings.Index(key, "/"); i >= 0 {
+			policy.Name, policy.ErrorClass = strings.TrimSpace(key[:i]), strings.TrimSpace(key[i+1:])
+			if !errorClassPattern.MatchString(policy.ErrorClass) {
+				return nil, fmt.Errorf("invalid %s: invalid error class for %s: %s", ConfigRetryPolicies, policy.Name, policy.ErrorClass)
+			}
+		}
+
+		if !isState(policy.Name) {
+			return nil, fmt.Errorf("invalid %s: unknown state %s", ConfigRetryPolicies, policy.Name)
+		}
+		for _, other := range parsed {
+			if other.Name == policy.Name && other.ErrorClass == policy.ErrorClass {
+				return nil, fmt.Errorf("invalid %s: more than one policy for %s", ConfigRetryPolicies, key)
+			}
+		}
+
+		delays := value
+		if i := strings.Index(value, ">"); i >= 0 {
+			delays, policy.Terminal = value[:i], strings.TrimSpace(value[i+1:])
+			if !isState(policy.Terminal) {
+				return nil, fmt.Errorf("invalid %s: unknown terminal state for %s: %s", ConfigRetryPolicies, key, policy.Terminal)
+			}
+		}
+
+		var err error
+		if policy.Delays, err = parseDelays(ConfigRetryPolicies, delays); err != nil {
+			return nil, err
+		}
+
+		parsed = append(parsed, policy)
+	}
+
+	return parsed, nil
+}
+
+// configureRetryPolicies parses the retry policies
+func (octopus *EventOctopus) configureRetryPolicies() error {
+	policies, err := parseRetryPolicies(octopus.Config.RetryPolicies)
+	if err != nil {
+		return err
+	}
+
+	octopus.retryPolicies = policies
+
+	return nil
+}
+
+// retryPolicy returns the policy for the event. A policy for the name and error class of the event goes before a policy
+// for its name only, events without a policy follow the default policy of retrySchedule or maxRetryCount and incrementalBackoff.
+func (octopus *EventOctopus) retryPolicy(event Event) RetryPolicy {
+	var forName *RetryPolicy
+
+	for i, p := range octopus.retryPolicies {
+		if p.Name != event.Name {
+			continue
+		}
+		if p.ErrorClass == "" {
+			forName = &octopus.retryPolicies[i]
+		} else if event.ErrorClass != nil && *event.ErrorClass == p.ErrorClass {
+			return p
+		}
+	}
+
+	if forName != nil {
+		return *forName
+	}
+
+	return RetryPolicy{
+		Delays:   octopus.retryDelays(),
+		Terminal: EventErrored,
+	}
+}
+
+// errMaxRetryCount is the error of an event that reached the max retry count of its policy
+const errMaxRetryCount = "max retry count reached"
+
+// exhausted returns the event in the terminal state of its retry policy and true when it reached the max retry count of the policy
+func (octopus *EventOctopus) exhausted(event Event) (Event, bool) {
+	policy := octopus.retryPolicy(event)
+	if event.RetryCount < len(policy.Delays) {
+		return event, false
+	}
+
+	errStr := errMaxRetryCount
+	event.Name = policy.Terminal
+	event.Error = &errStr
+
+	return event, true
+}
+
+// retryConsumers creates the retry queues of the default policy followed by the queues of the configured policies
+func (octopus *EventOctopus) retryConsumers(conn stan.Conn) []*DelayedConsumer {
+	consumers := NewDelayedConsumers(ChannelConsentRetry, ChannelConsentRequest, octopus.retryDelays(), octopus.Config.RetryJitter, conn, octopus)
+
+	for _, p := range octopus.retryPolicies {
+		consumers = append(consumers, NewDelayedConsumers(p.subject(), ChannelConsentRequest, p.Delays, octopus.Config.RetryJitter, conn, octopus)...)
+	}
+
+	return consumers
+}
@@ -0,0 +1,190 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	natsClient "github.com/nats-io/stan.go"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryPolicies(t *testing.T) {
+	t.Run("parses policies", func(t *testing.T) {
+		policies, err := parseRetryPolicies("consentRequest constructed/corda=1s,1m,15m > consentRequest nacked; consentRequest in flight=10s ;completed=")
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, []RetryPolicy{
+				{Name: EventConsentRequestConstructed, ErrorClass: "corda", Delays: []time.Duration{time.Second, time.Minute, 15 * time.Minute}, Terminal: EventConsentRequestNacked},
+				{Name: EventConsentRequestInFlight, Delays: []time.Duration{10 * time.Second}, Terminal: EventErrored},
+				{Name: EventCompleted, Terminal: EventErrored},
+			}, policies)
+		}
+	})
+
+	t.Run("empty policies", func(t *testing.T) {
+		policies, err := parseRetryPolicies("")
+
+		assert.NoError(t, err)
+		assert.Empty(t, policies)
+	})
+
+	t.Run("returns error for invalid policies", func(t *testing.T) {
+		for _, policies := range []string{
+			"completed",
+			"unknown=1s",
+			"completed/corda network=1s",
+			"completed/=1s",
+			"completed=1d",
+			"completed=1s>unknown",
+			"completed/corda=1s;completed/corda=2s",
+		} {
+			_, err := parseRetryPolicies(policies)
+			assert.Error(t, err, policies)
+		}
+	})
+}
+
+func TestRetryPolicy_subject(t *testing.T) {
+	assert.Equal(t, "consentRequestRetry", RetryPolicy{}.subject())
+	assert.Equal(t, "consentRequestRetry-consentRequest_constructed", RetryPolicy{Name: EventConsentRequestConstructed}.subject())
+	assert.Equal(t, "consentRequestRetry-consentRequest_constructed-corda", RetryPolicy{Name: EventConsentRequestConstructed, ErrorClass: "corda"}.subject())
+}
+
+func TestEventOctopus_retryPolicy(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.RetrySchedule = "1s,1m"
+	i.Config.RetryPolicies = "consentRequest constructed/corda=1h>consentRequest nacked;consentRequest constructed=10s,20s,30s"
+	if err := i.configureRetrySchedule(); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.configureRetryPolicies(); err != nil {
+		t.Fatal(err)
+	}
+
+	corda := "corda"
+	vendor := "vendor"
+
+	t.Run("policy for name and error class goes first", func(t *testing.T) {
+		p := i.retryPolicy(Event{Name: EventConsentRequestConstructed, ErrorClass: &corda})
+
+		assert.Equal(t, []time.Duration{time.Hour}, p.Delays)
+		assert.Equal(t, EventConsentRequestNacked, p.Terminal)
+	})
+
+	t.Run("policy for name applies to other error classes", func(t *testing.T) {
+		p := i.retryPolicy(Event{Name: EventConsentRequestConstructed, ErrorClass: &vendor})
+
+		assert.Equal(t, []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second}, p.Delays)
+		assert.Equal(t, EventErrored, p.Terminal)
+	})
+
+	t.Run("default policy applies to other names", func(t *testing.T) {
+		p := i.retryPolicy(Event{Name: EventConsentRequestInFlight, ErrorClass: &corda})
+
+		assert.Equal(t, RetryPolicy{Delays: []time.Duration{time.Second, time.Minute}, Terminal: EventErrored}, p)
+	})
+
+	t.Run("retry queues of all policies are created", func(t *testing.T) {
+		var subjects []string
+		for _, dc := range i.retryConsumers(nil) {
+			subjects = append(subjects, dc.consumeSubject)
+		}
+
+		assert.Equal(t, []string{
+			"consentRequestRetry-0",
+			"consentRequestRetry-1",
+			"consentRequestRetry-consentRequest_constructed-corda-0",
+			"consentRequestRetry-consentRequest_constructed-0",
+			"consentRequestRetry-consentRequest_constructed-1",
+			"consentRequestRetry-consentRequest_constructed-2",
+		}, subjects)
+	})
+}
+
+func TestEventOctopus_RetryPolicies(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:retry_policies?mode=memory&cache=shared"
+	i.Config.RetryPolicies = "consentRequest constructed/corda=10ms>consentRequest nacked"
+	if err := i.configure(); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	corda := "corda"
+
+	t.Run("event is retried with the delays of its policy", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.ErrorClass = &corda
+		received := make(chan Event, 1)
+
+		sc := stanConnection()
+		defer sc.Close()
+		sub, _ := sc.Subscribe(ChannelConsentRequest, func(msg *natsClient.Msg) {
+			r := Event{}
+			if err := json.Unmarshal(msg.Data, &r); err == nil && r.UUID == e.UUID {
+				received <- r
+			}
+		})
+		defer sub.Unsubscribe()
+
+		if err := i.publishEventToChannel(e, ChannelConsentRetry); err != nil {
+			t.Fatal(err)
+		}
+
+		// the default policy retries after a second
+		select {
+		case r := <-received:
+			assert.Equal(t, 1, r.RetryCount)
+		case <-time.After(500 * time.Millisecond):
+			assert.Fail(t, "event not retried")
+		}
+	})
+
+	t.Run("event gets the terminal name of its policy after the last retry", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.ErrorClass = &corda
+		e.RetryCount = 1
+
+		if err := i.publishEventToChannel(e, ChannelConsentRetry); err != nil {
+			t.Fatal(err)
+		}
+
+		var stored *Event
+		for j := 0; j < 50 && stored == nil; j++ {
+			time.Sleep(10 * time.Millisecond)
+			stored, _ = i.GetEvent(e.UUID)
+		}
+
+		if assert.NotNil(t, stored) {
+			assert.Equal(t, EventConsentRequestNacked, stored.Name)
+			if assert.NotNil(t, stored.ErrorClass) {
+				assert.Equal(t, corda, *stored.ErrorClass)
+			}
+		}
+	})
+}
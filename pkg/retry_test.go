@@ -27,7 +27,11 @@ import (
 
 func TestDelayedConsumer(t *testing.T) {
 	i := testEventOctopus()
-	_ = i.startStanServer()
+	i.Config.Connectionstring = "file:delayed_consumer?mode=memory&cache=shared"
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
 	defer i.Shutdown()
 
 	t.Run("delayed event is received on correct publish channel", func(t *testing.T) {
@@ -38,23 +42,59 @@ func TestDelayedConsumer(t *testing.T) {
 			consumeSubject: "channelIn",
 			publishSubject: "channelOut",
 			conn:           sc,
-			delay:          10 * time.Millisecond,
+			scheduler:      i,
+			delay:          100 * time.Millisecond,
 		}
 
 		if assert.Nil(t, dc.Start()) {
 			defer dc.subscription.Unsubscribe()
-			found := false
+			received := make(chan time.Time, 1)
 
 			sub, _ := sc.Subscribe("channelOut", func(msg *stan.Msg) {
-				found = true
+				received <- time.Now()
 			})
 			defer sub.Unsubscribe()
 
+			published := time.Now()
 			sc.Publish("channelIn", []byte("test"))
 
-			assert.False(t, found)
-			time.Sleep(15 * time.Millisecond)
-			assert.True(t, found)
+			select {
+			case at := <-received:
+				assert.True(t, at.Sub(published) >= 100*time.Millisecond)
+			case <-time.After(time.Second):
+				assert.Fail(t, "delayed event not received")
+			}
+		}
+	})
+
+	t.Run("message is stored until it is due and acked", func(t *testing.T) {
+		sc := conn("stored")
+		defer sc.Close()
+
+		dc := DelayedConsumer{
+			consumeSubject: "channelStored",
+			publishSubject: "channelStoredOut",
+			conn:           sc,
+			scheduler:      i,
+			delay:          time.Hour,
+		}
+
+		if assert.Nil(t, dc.Start()) {
+			defer dc.subscription.Unsubscribe()
+
+			sc.Publish("channelStored", []byte("test"))
+
+			var stored []ScheduledMessage
+			for j := 0; j < 50 && len(stored) == 0; j++ {
+				time.Sleep(10 * time.Millisecond)
+				i.Db.Where("queue = ?", "channelStored").Find(&stored)
+			}
+
+			if assert.Len(t, stored, 1) {
+				assert.Equal(t, "channelStoredOut", stored[0].Subject)
+				assert.Equal(t, "test", stored[0].Data)
+				assert.WithinDuration(t, time.Now().Add(time.Hour), stored[0].DueAt, 5*time.Second)
+			}
 		}
 	})
 }
@@ -83,7 +123,7 @@ func TestDelayedConsumer_Stop(t *testing.T) {
 }
 
 func TestNewDelayedConsumerSet(t *testing.T) {
-	set := NewDelayedConsumerSet("in", "out", 3, time.Millisecond, 2, nil)
+	set := NewDelayedConsumerSet("in", "out", 3, time.Millisecond, 2, nil, nil)
 
 	t.Run("gives the correct number of DelayedConsumer", func(t *testing.T) {
 		assert.Equal(t, 3, len(set))
@@ -108,6 +148,85 @@ func TestNewDelayedConsumerSet(t *testing.T) {
 	})
 }
 
+func TestNewDelayedConsumers(t *testing.T) {
+	set := NewDelayedConsumers("in", "out", []time.Duration{time.Second, time.Minute}, 10, nil, nil)
+
+	t.Run("creates a DelayedConsumer for every delay", func(t *testing.T) {
+		if assert.Len(t, set, 2) {
+			assert.Equal(t, "in-0", set[0].consumeSubject)
+			assert.Equal(t, time.Second, set[0].delay)
+			assert.Equal(t, "in-1", set[1].consumeSubject)
+			assert.Equal(t, time.Minute, set[1].delay)
+		}
+	})
+
+	t.Run("sets jitter as percentage of the delay", func(t *testing.T) {
+		assert.Equal(t, 100*time.Millisecond, set[0].jitter)
+		assert.Equal(t, 6*time.Second, set[1].jitter)
+	})
+}
+
+func TestParseDelays(t *testing.T) {
+	t.Run("parses delays", func(t *testing.T) {
+		delays, err := parseDelays(ConfigRetrySchedule, "1s, 1m,15m,1h,24h,168h")
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, []time.Duration{time.Second, time.Minute, 15 * time.Minute, time.Hour, 24 * time.Hour, 168 * time.Hour}, delays)
+		}
+	})
+
+	t.Run("empty schedule", func(t *testing.T) {
+		delays, err := parseDelays(ConfigRetrySchedule, "")
+
+		assert.NoError(t, err)
+		assert.Empty(t, delays)
+	})
+
+	t.Run("returns error for invalid delays", func(t *testing.T) {
+		for _, schedule := range []string{"1d", "1s,soon", "0s", "-1m"} {
+			_, err := parseDelays(ConfigRetrySchedule, schedule)
+			assert.Error(t, err, schedule)
+		}
+	})
+}
+
+func TestEventOctopus_configureRetrySchedule(t *testing.T) {
+	t.Run("schedule overrides maxRetryCount and incrementalBackoff", func(t *testing.T) {
+		i := testEventOctopus()
+		i.Config.RetrySchedule = "1s,1m"
+
+		if assert.NoError(t, i.configureRetrySchedule()) {
+			assert.Equal(t, []time.Duration{time.Second, time.Minute}, i.retryDelays())
+		}
+	})
+
+	t.Run("without schedule delays follow from maxRetryCount and incrementalBackoff", func(t *testing.T) {
+		i := testEventOctopus()
+		i.Config.MaxRetryCount = 3
+		i.Config.IncrementalBackoff = 10
+
+		if assert.NoError(t, i.configureRetrySchedule()) {
+			assert.Equal(t, []time.Duration{time.Second, 10 * time.Second, 100 * time.Second}, i.retryDelays())
+		}
+	})
+
+	t.Run("returns error for invalid schedule", func(t *testing.T) {
+		i := testEventOctopus()
+		i.Config.RetrySchedule = "1s,never"
+
+		assert.EqualError(t, i.configureRetrySchedule(), "invalid retrySchedule: invalid delay never")
+	})
+
+	t.Run("returns error for invalid jitter", func(t *testing.T) {
+		for _, jitter := range []int{-1, 101} {
+			i := testEventOctopus()
+			i.Config.RetryJitter = jitter
+
+			assert.Error(t, i.configureRetrySchedule())
+		}
+	})
+}
+
 func conn(id string) stan.Conn {
 	sc, err := stan.Connect(
 		"nuts",
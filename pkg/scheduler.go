@@ -0,0 +1,280 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	natsClient "github.com/nats-io/stan.go"
+	uuid "github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// schedulerPollInterval is the max time between two checks for due messages. Messages scheduled by this node wake the
+// scheduler earlier, the interval picks up messages scheduled by other nodes sharing the event store.
+const schedulerPollInterval = time.Second
+
+// schedulerBatchSize is the max number of due messages read at once
+const schedulerBatchSize = 100
+
+// schedulerLease is the time a node has for publishing a message it claimed, after that the message is due again
+const schedulerLease = 30 * time.Second
+
+// ScheduledMessage is a message from a retry queue, stored in the event store until it is due for publishing
+type ScheduledMessage struct {
+	ID string `gorm:"PRIMARY_KEY" json:"id"`
+	// Queue is the retry channel the message was received on
+	Queue string `gorm:"not null" json:"queue"`
+	// Sequence is the Nats sequence number of the message on its queue
+	Sequence uint64 `gorm:"not null" json:"sequence"`
+	// Subject is the channel the message is published to when it is due
+	Subject string `gorm:"not null" json:"subject"`
+	// Data is the message, it is encrypted in the event store when payload encryption is enabled
+	Data      string    `gorm:"not null" json:"data"`
+	DueAt     time.Time `gorm:"not null" json:"dueAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// TableName returns the name of the scheduled message table
+func (ScheduledMessage) TableName() string {
+	return "scheduled_messages"
+}
+
+// Scheduler holds messages until they are due for publishing
+type Scheduler interface {
+	// Schedule stores the message, a message that has been scheduled before is ignored
+	Schedule(msg ScheduledMessage) error
+}
+
+// Schedule stores the message in the event store until it is due and wakes the scheduler.
+// A redelivered message that is already stored is ignored.
+func (octopus *EventOctopus) Schedule(msg ScheduledMessage) error {
+	octopus.inFlight.start()
+	defer octopus.inFlight.done()
+
+	var err error
+	msg.ID = uuid.NewV4().String()
+	if msg.Data, err = octopus.encryptPayload(msg.Data, msg.ID); err != nil {
+		return err
+	}
+	msg.DueAt = msg.DueAt.UTC()
+	msg.CreatedAt = now()
+
+	if err := octopus.Db.Debug().Create(&msg).Error; err != nil {
+		// the unique index on queue and sequence rejects a message that has already been scheduled
+		if octopus.scheduled(msg.Queue, msg.Sequence) {
+			return nil
+		}
+		return err
+	}
+
+	retryQueueDepth.WithLabelValues(msg.Queue).Inc()
+
+	// don't block when the scheduler has already been woken
+	select {
+	case octopus.schedulerWake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// scheduled returns true when the message with the given sequence is stored for the queue
+func (octopus *EventOctopus) scheduled(queue string, sequence uint64) bool {
+	var count int
+	err := octopus.Db.Debug().Model(&ScheduledMessage{}).Where("queue = ? AND sequence = ?", queue, sequence).Count(&count).Error
+	return err == nil && count > 0
+}
+
+// startScheduler publishes the scheduled messages on the connection when they are due, until stopScheduler is called
+func (octopus *EventOctopus) startScheduler(conn natsClient.Conn) {
+	wake := make(chan struct{}, 1)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	octopus.schedulerWake, octopus.schedulerStop, octopus.schedulerDone = wake, stop, done
+
+	if err := octopus.countScheduled(); err != nil {
+		logrus.WithError(err).Error("Failed to count scheduled messages")
+	}
+
+	go func() {
+		defer close(done)
+
+		for {
+			wait := schedulerPollInterval
+
+			next, err := octopus.publishDue(conn)
+			if err != nil {
+				logrus.WithError(err).Error("Failed to publish scheduled messages")
+			} else if next != nil && next.After(now()) && next.Sub(now()) < wait {
+				wait = next.Sub(now())
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-stop:
+				timer.Stop()
+				return
+			case <-wake:
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// stopScheduler stops publishing scheduled messages and waits for a running publication to finish
+func (octopus *EventOctopus) stopScheduler() {
+	if octopus.schedulerStop == nil {
+		return
+	}
+
+	close(octopus.schedulerStop)
+	<-octopus.schedulerDone
+
+	octopus.schedulerStop = nil
+	octopus.schedulerDone = nil
+}
+
+// countScheduled sets the depth of the retry queues to the number of messages stored per queue
+func (octopus *EventOctopus) countScheduled() error {
+	var counts []struct {
+		Queue string
+		Count int
+	}
+
+	err := octopus.Db.Debug().Model(&ScheduledMessage{}).Select("queue, count(*) AS count").Group("queue").Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	for _, c := range counts {
+		retryQueueDepth.WithLabelValues(c.Queue).Set(float64(c.Count))
+	}
+
+	return nil
+}
+
+// publishDue publishes all due messages and returns the moment the next message is due, nil when there are no messages
+func (octopus *EventOctopus) publishDue(conn natsClient.Conn) (*time.Time, error) {
+	for {
+		var due []ScheduledMessage
+
+		err := octopus.Db.Debug().Where("due_at <= ?", now()).Order("due_at, sequence").Limit(schedulerBatchSize).Find(&due).Error
+		if err != nil {
+			return nil, err
+		}
+
+		published := 0
+		for _, msg := range due {
+			ok, err := octopus.publishScheduled(conn, msg)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				published++
+			}
+		}
+
+		// stop when all due messages are read or when the remaining ones can't be published
+		if len(due) < schedulerBatchSize || published == 0 {
+			break
+		}
+	}
+
+	next := ScheduledMessage{}
+	err := octopus.Db.Debug().Order("due_at").First(&next).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &next.DueAt, nil
+}
+
+// publishScheduled claims the message, publishes it and then removes it from the event store. The claim makes the message due
+// after the schedulerLease, so other nodes skip it without a transaction being held open while publishing. A published message
+// that could not be removed is published again when the claim expires.
+// A message that can't be decrypted is moved to the dead letter channel, it would otherwise be read again in every batch.
+// It returns false for a message that has been claimed by another node.
+func (octopus *EventOctopus) publishScheduled(conn natsClient.Conn, msg ScheduledMessage) (bool, error) {
+	data, err := octopus.decryptPayload(msg.Data, msg.ID)
+	publish := func() error {
+		return conn.Publish(msg.Subject, []byte(data))
+	}
+	if err != nil {
+		reason := fmt.Sprintf("Error while decrypting scheduled message: %v", err)
+		publish = func() error {
+			return octopus.deadLetterScheduled(msg, reason)
+		}
+	}
+
+	claim := octopus.Db.Debug().Model(&ScheduledMessage{}).
+		Where("id = ? AND due_at <= ?", msg.ID, now()).
+		UpdateColumn("due_at", now().Add(schedulerLease))
+	if claim.Error != nil {
+		return false, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return false, nil
+	}
+
+	if err := publish(); err != nil {
+		messageFailures.WithLabelValues(msg.Queue).Inc()
+		// the message is due again for the next batch
+		if err := octopus.Db.Debug().Model(&ScheduledMessage{}).Where("id = ?", msg.ID).UpdateColumn("due_at", msg.DueAt).Error; err != nil {
+			logrus.WithError(err).Warnf("Failed to release scheduled message %s, it is published after the lease of %s", msg.ID, schedulerLease)
+		}
+		return false, fmt.Errorf("Error while publishing scheduled message %s to %s: %v", msg.ID, msg.Subject, err)
+	}
+
+	if err := octopus.Db.Debug().Where("id = ?", msg.ID).Delete(&ScheduledMessage{}).Error; err != nil {
+		return false, fmt.Errorf("Error while removing published scheduled message %s, it is published again after the lease of %s: %w", msg.ID, schedulerLease, err)
+	}
+
+	retryQueueDepth.WithLabelValues(msg.Queue).Dec()
+
+	return true, nil
+}
+
+// deadLetterScheduled publishes the stored message to the dead letter channel, the data is kept as stored.
+// The dead letter takes the ID of the message, since the data is encrypted for that ID.
+func (octopus *EventOctopus) deadLetterScheduled(msg ScheduledMessage, reason string) error {
+	logrus.Warnf("Dead-lettering scheduled message %d from %s: %s", msg.Sequence, msg.Queue, reason)
+
+	err := octopus.publishDeadLetter(DeadLetter{
+		ID:        msg.ID,
+		Subject:   msg.Queue,
+		Sequence:  msg.Sequence,
+		Reason:    reason,
+		Data:      []byte(msg.Data),
+		CreatedAt: now(),
+		Encrypted: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	eventsDeadLettered.WithLabelValues(msg.Queue).Inc()
+	return nil
+}
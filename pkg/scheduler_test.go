@@ -0,0 +1,221 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	natsClient "github.com/nats-io/stan.go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventOctopus_Schedule(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:schedule?mode=memory&cache=shared"
+	i.configure()
+	if err := i.openDb(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	stored := func(queue string) []ScheduledMessage {
+		var messages []ScheduledMessage
+		i.Db.Where("queue = ?", queue).Find(&messages)
+		return messages
+	}
+
+	t.Run("message is stored", func(t *testing.T) {
+		dueAt := time.Now().Add(time.Minute)
+
+		err := i.Schedule(ScheduledMessage{Queue: "stored-0", Sequence: 1, Subject: "out", Data: "data", DueAt: dueAt})
+
+		if assert.NoError(t, err) {
+			messages := stored("stored-0")
+			if assert.Len(t, messages, 1) {
+				assert.NotEmpty(t, messages[0].ID)
+				assert.Equal(t, "out", messages[0].Subject)
+				assert.Equal(t, "data", messages[0].Data)
+				assert.WithinDuration(t, dueAt, messages[0].DueAt, time.Millisecond)
+			}
+			assert.Equal(t, float64(1), testutil.ToFloat64(retryQueueDepth.WithLabelValues("stored-0")))
+		}
+	})
+
+	t.Run("redelivered message is stored once", func(t *testing.T) {
+		msg := ScheduledMessage{Queue: "redelivered-0", Sequence: 1, Subject: "out", Data: "data", DueAt: time.Now()}
+
+		assert.NoError(t, i.Schedule(msg))
+		assert.NoError(t, i.Schedule(msg))
+
+		assert.Len(t, stored("redelivered-0"), 1)
+	})
+
+	t.Run("message is stored once per queue and sequence", func(t *testing.T) {
+		assert.NoError(t, i.Db.Create(&ScheduledMessage{ID: "unique-1", Queue: "unique-0", Sequence: 1, Subject: "out", Data: "data", DueAt: time.Now()}).Error)
+
+		assert.Error(t, i.Db.Create(&ScheduledMessage{ID: "unique-2", Queue: "unique-0", Sequence: 1, Subject: "out", Data: "data", DueAt: time.Now()}).Error)
+	})
+
+	t.Run("message is encrypted", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "schedule")
+		defer os.RemoveAll(dir)
+		i.KeyProvider, _ = NewLocalKeyProvider(writeKeyFile(t, dir, "key-1 "+randomKey()))
+		defer func() { i.KeyProvider = nil }()
+
+		assert.NoError(t, i.Schedule(ScheduledMessage{Queue: "encrypted-0", Sequence: 1, Subject: "out", Data: "personal data", DueAt: time.Now()}))
+
+		messages := stored("encrypted-0")
+		if assert.Len(t, messages, 1) {
+			assert.True(t, strings.HasPrefix(messages[0].Data, encryptedPrefix))
+		}
+	})
+
+	t.Run("messages stored in plain text are encrypted", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "schedule")
+		defer os.RemoveAll(dir)
+		i.KeyProvider, _ = NewLocalKeyProvider(writeKeyFile(t, dir, "key-1 "+randomKey()))
+		defer func() { i.KeyProvider = nil }()
+
+		updated, err := i.EncryptPayloads()
+
+		if assert.NoError(t, err) {
+			// the plain text messages of the previous tests
+			assert.Equal(t, 3, updated)
+			assert.True(t, strings.HasPrefix(stored("stored-0")[0].Data, encryptedPrefix))
+		}
+	})
+}
+
+func TestEventOctopus_scheduler(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:scheduler?mode=memory&cache=shared"
+	i.configure()
+	if err := i.openDb(); err != nil {
+		t.Fatal(err)
+	}
+
+	// messages scheduled before the start, for instance before a restart
+	_ = i.Schedule(ScheduledMessage{Queue: "scheduler-0", Sequence: 2, Subject: "schedulerOut", Data: "second", DueAt: now().Add(-time.Second)})
+	_ = i.Schedule(ScheduledMessage{Queue: "scheduler-0", Sequence: 1, Subject: "schedulerOut", Data: "first", DueAt: now().Add(-time.Minute)})
+	_ = i.Schedule(ScheduledMessage{Queue: "scheduler-0", Sequence: 3, Subject: "schedulerOut", Data: "later", DueAt: now().Add(time.Hour)})
+
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	sc, _ := i.client("scheduler-test")
+	received := make(chan string, 10)
+	sub, _ := sc.Subscribe("schedulerOut", func(msg *natsClient.Msg) {
+		received <- string(msg.Data)
+	}, natsClient.DeliverAllAvailable())
+	defer sub.Unsubscribe()
+
+	receive := func() string {
+		select {
+		case data := <-received:
+			return data
+		case <-time.After(time.Second):
+			return ""
+		}
+	}
+
+	t.Run("due messages are published in order after the start", func(t *testing.T) {
+		assert.Equal(t, "first", receive())
+		assert.Equal(t, "second", receive())
+
+		var remaining []ScheduledMessage
+		i.Db.Where("queue = ?", "scheduler-0").Find(&remaining)
+		if assert.Len(t, remaining, 1) {
+			assert.Equal(t, "later", remaining[0].Data)
+		}
+	})
+
+	t.Run("message is published when it is due", func(t *testing.T) {
+		scheduled := time.Now()
+		_ = i.Schedule(ScheduledMessage{Queue: "scheduler-1", Sequence: 1, Subject: "schedulerOut", Data: "soon", DueAt: now().Add(50 * time.Millisecond)})
+
+		assert.Equal(t, "soon", receive())
+		assert.True(t, time.Since(scheduled) >= 50*time.Millisecond)
+	})
+
+	t.Run("message that can't be decrypted is dead-lettered", func(t *testing.T) {
+		// encrypted with a key that is no longer configured
+		i.Db.Create(&ScheduledMessage{ID: "undecryptable", Queue: "scheduler-3", Sequence: 1, Subject: "schedulerOut", Data: encryptedPrefix + "gone:a2V5:ZGF0YQ==", DueAt: now().Add(-time.Minute), CreatedAt: now()})
+		_ = i.Schedule(ScheduledMessage{Queue: "scheduler-3", Sequence: 2, Subject: "schedulerOut", Data: "after", DueAt: now()})
+
+		assert.Equal(t, "after", receive())
+		assert.False(t, i.scheduled("scheduler-3", 1))
+
+		var deadLetters []DeadLetter
+		assert.Eventually(t, func() bool {
+			deadLetters, _ = i.DeadLetters()
+			return len(deadLetters) == 1
+		}, time.Second, 10*time.Millisecond)
+		if assert.Len(t, deadLetters, 1) {
+			assert.Equal(t, "scheduler-3", deadLetters[0].Subject)
+			assert.Equal(t, uint64(1), deadLetters[0].Sequence)
+			assert.Contains(t, deadLetters[0].Reason, "decrypting")
+		}
+	})
+
+	t.Run("stopped scheduler doesn't publish", func(t *testing.T) {
+		i.stopScheduler()
+		_ = i.Schedule(ScheduledMessage{Queue: "scheduler-2", Sequence: 1, Subject: "schedulerOut", Data: "stopped", DueAt: now()})
+
+		assert.Equal(t, "", receive())
+	})
+
+	t.Run("message claimed by another node is skipped", func(t *testing.T) {
+		// another node moved the message past due while it publishes it
+		msg := ScheduledMessage{ID: "claimed", Queue: "scheduler-4", Sequence: 1, Subject: "schedulerOut", Data: "claimed", DueAt: now().Add(schedulerLease), CreatedAt: now()}
+		i.Db.Create(&msg)
+		msg.DueAt = now().Add(-time.Minute)
+
+		published, err := i.publishScheduled(sc, msg)
+
+		assert.NoError(t, err)
+		assert.False(t, published)
+		assert.True(t, i.scheduled("scheduler-4", 1))
+		assert.Equal(t, "", receive())
+	})
+
+	t.Run("message that can't be published is due again", func(t *testing.T) {
+		dueAt := now().Add(-time.Minute)
+		_ = i.Schedule(ScheduledMessage{Queue: "scheduler-5", Sequence: 1, Subject: "schedulerOut", Data: "unpublished", DueAt: dueAt})
+		msg := ScheduledMessage{}
+		i.Db.Where("queue = ?", "scheduler-5").First(&msg)
+
+		closed := stanConnection()
+		closed.Close()
+
+		published, err := i.publishScheduled(closed, msg)
+
+		assert.Error(t, err)
+		assert.False(t, published)
+		stored := ScheduledMessage{}
+		i.Db.Where("id = ?", msg.ID).First(&stored)
+		assert.False(t, stored.DueAt.After(now()))
+	})
+}
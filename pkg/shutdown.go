@@ -0,0 +1,175 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"sync"
+	"time"
+
+	natsClient "github.com/nats-io/stan.go"
+	"github.com/sirupsen/logrus"
+)
+
+// inFlight counts the messages being handled and the publications awaiting an ack, Shutdown waits for them to finish
+type inFlight struct {
+	mutex sync.Mutex
+	count int
+	// idle is closed when the count drops to 0 while waiting
+	idle chan struct{}
+}
+
+func (f *inFlight) start() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.count++
+}
+
+func (f *inFlight) done() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.count--
+	if f.count == 0 && f.idle != nil {
+		close(f.idle)
+		f.idle = nil
+	}
+}
+
+// wait waits until nothing is in flight or the timeout passed, it returns the number of messages and publications still in flight
+func (f *inFlight) wait(timeout time.Duration) int {
+	f.mutex.Lock()
+	if f.count == 0 {
+		f.mutex.Unlock()
+		return 0
+	}
+	if f.idle == nil {
+		f.idle = make(chan struct{})
+	}
+	idle := f.idle
+	f.mutex.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-idle:
+		return 0
+	case <-timer.C:
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.count
+}
+
+// tracked wraps the message handler so Shutdown waits for it to finish
+func (octopus *EventOctopus) tracked(handler natsClient.MsgHandler) natsClient.MsgHandler {
+	return func(msg *natsClient.Msg) {
+		octopus.inFlight.start()
+		defer octopus.inFlight.done()
+
+		handler(msg)
+	}
+}
+
+// storeAckWait is the time the event store has to process a message before Nats redelivers it
+var storeAckWait = subscriptionAckWait
+
+// storeHandler processes a message for the event store, an error leaves the message for redelivery
+type storeHandler func(msg *natsClient.Msg) error
+
+// subscribe makes a subscription of the event store, Shutdown closes it and waits for the messages it is handling.
+// Messages are only acked when the handler succeeds, failures count towards the circuit breaker.
+// While the breaker is open, messages are not handled and left for redelivery.
+func (octopus *EventOctopus) subscribe(sc natsClient.Conn, subject string, handler storeHandler, opts ...natsClient.SubscriptionOption) error {
+	opts = append(opts, natsClient.SetManualAckMode(), natsClient.AckWait(storeAckWait))
+
+	sub, err := sc.Subscribe(subject, octopus.tracked(func(msg *natsClient.Msg) {
+		if !octopus.breaker.allow() {
+			logrus.Debugf("Circuit breaker open, leaving message %d on %s for redelivery", msg.Sequence, msg.Subject)
+			return
+		}
+
+		if err := handler(msg); err != nil {
+			logrus.WithError(err).Errorf("Failed to process message %d on %s, leaving it for redelivery", msg.Sequence, msg.Subject)
+			messageFailures.WithLabelValues(msg.Subject).Inc()
+			octopus.breaker.failure(err)
+			return
+		}
+
+		octopus.breaker.success()
+		ack(msg)
+	}), opts...)
+	if err != nil {
+		return err
+	}
+
+	octopus.subscriptions = append(octopus.subscriptions, sub)
+
+	return nil
+}
+
+// closeSubscriptions stops receiving messages. Subscriptions are closed instead of unsubscribed,
+// so durable subscriptions resume where they left off after a restart.
+func (octopus *EventOctopus) closeSubscriptions() {
+	for _, sub := range octopus.subscriptions {
+		if err := sub.Close(); err != nil {
+			logrus.WithError(err).Warn("Failed to close subscription")
+		}
+	}
+	octopus.subscriptions = nil
+
+	for _, dc := range append(octopus.delayedConsumers, octopus.webhookConsumers...) {
+		if err := dc.Stop(); err != nil {
+			logrus.WithError(err).Warnf("Failed to stop delayed consumer for %s", dc.consumeSubject)
+		}
+	}
+	octopus.delayedConsumers = nil
+	octopus.webhookConsumers = nil
+
+	for service, subjects := range octopus.channelHandlers {
+		for subject, handlers := range subjects {
+			if err := handlers.subscription.Close(); err != nil {
+				logrus.WithError(err).Warnf("Failed to close subscription of %s.%s", service, subject)
+			}
+		}
+	}
+}
+
+// cancelHandlers ends the context of the running handlers and removes the handlers
+func (octopus *EventOctopus) cancelHandlers() {
+	for _, subjects := range octopus.channelHandlers {
+		for _, handlers := range subjects {
+			handlers.cancel()
+		}
+	}
+	octopus.channelHandlers = make(map[string]map[string]ChannelHandlers)
+}
+
+// closeClients closes the connections to Nats
+func (octopus *EventOctopus) closeClients() {
+	for clientID, client := range octopus.stanClients {
+		if err := client.Close(); err != nil {
+			logrus.WithError(err).Warnf("Failed to close Nats client %s", clientID)
+		}
+	}
+	octopus.stanClients = make(map[string]natsClient.Conn)
+}
@@ -0,0 +1,144 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInFlight_wait(t *testing.T) {
+	t.Run("returns immediately when nothing is in flight", func(t *testing.T) {
+		f := inFlight{}
+
+		assert.Equal(t, 0, f.wait(time.Hour))
+	})
+
+	t.Run("waits until everything is done", func(t *testing.T) {
+		f := inFlight{}
+		f.start()
+		f.start()
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			f.done()
+			f.done()
+		}()
+
+		assert.Equal(t, 0, f.wait(time.Second))
+	})
+
+	t.Run("returns the number in flight after the timeout", func(t *testing.T) {
+		f := inFlight{}
+		f.start()
+
+		assert.Equal(t, 1, f.wait(10*time.Millisecond))
+	})
+}
+
+func TestEventOctopus_GracefulShutdown(t *testing.T) {
+	start := func(t *testing.T) *EventOctopus {
+		i := testEventOctopus()
+		i.Config.Connectionstring = "file:shutdown?mode=memory&cache=shared"
+		i.configure()
+		if err := i.Start(); err != nil {
+			t.Fatal(err)
+		}
+		return i
+	}
+
+	// handle subscribes the handler and publishes an event, it returns when the handler has been called
+	handle := func(t *testing.T, i *EventOctopus, handler EventHandler) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		started := make(chan bool)
+
+		_ = i.SubscribeHandlers("shutdown", "shutdownTest", map[string]EventHandler{
+			e.Name: func(ctx context.Context, event *Event) error {
+				close(started)
+				return handler(ctx, event)
+			},
+		})
+
+		publisher, _ := i.EventPublisher("shutdown-test")
+		_ = publisher.Publish("shutdownTest", e)
+
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("handler not called")
+		}
+	}
+
+	t.Run("waits for handlers that are running", func(t *testing.T) {
+		i := start(t)
+		i.Config.DrainTimeout = 5
+		finished := false
+
+		handle(t, i, func(ctx context.Context, event *Event) error {
+			time.Sleep(100 * time.Millisecond)
+			finished = true
+			return nil
+		})
+
+		assert.NoError(t, i.Shutdown())
+		assert.True(t, finished)
+	})
+
+	t.Run("abandons handlers after the drain timeout", func(t *testing.T) {
+		i := start(t)
+		cancelled := make(chan bool, 1)
+
+		handle(t, i, func(ctx context.Context, event *Event) error {
+			<-ctx.Done()
+			cancelled <- true
+			return nil
+		})
+
+		err := i.Shutdown()
+
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "1 messages still in flight")
+		}
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			assert.Fail(t, "context of abandoned handler not cancelled")
+		}
+	})
+
+	t.Run("closes subscriptions and clients", func(t *testing.T) {
+		i := start(t)
+		handle(t, i, func(ctx context.Context, event *Event) error {
+			return nil
+		})
+		sc, _ := i.client(ClientID)
+
+		_ = i.Shutdown()
+
+		assert.Empty(t, i.subscriptions)
+		assert.Empty(t, i.delayedConsumers)
+		assert.Empty(t, i.channelHandlers)
+		assert.Empty(t, i.stanClients)
+		assert.Error(t, sc.Publish("shutdownTest", []byte("{}")))
+	})
+}
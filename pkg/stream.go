@@ -0,0 +1,180 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	natsClient "github.com/nats-io/stan.go"
+	"github.com/sirupsen/logrus"
+)
+
+// streamBufferSize is the number of events buffered per stream before the Nats subscription waits for the receiver
+const streamBufferSize = 64
+
+// streamClientID is the Nats client of the event streams, so streams don't hold up the messages of the event store
+const streamClientID = ClientID + "-stream"
+
+// ErrTooManyStreams is returned when a stream is opened while maxStreams streams are open
+var ErrTooManyStreams = errors.New("too many streams")
+
+// StreamFilter selects the events of a stream, empty fields match all events
+type StreamFilter struct {
+	ExternalID           string
+	InitiatorLegalEntity string
+}
+
+func (f StreamFilter) matches(event Event) bool {
+	if f.ExternalID != "" && f.ExternalID != event.ExternalID {
+		return false
+	}
+	if f.InitiatorLegalEntity != "" && f.InitiatorLegalEntity != event.InitiatorLegalEntity {
+		return false
+	}
+	return true
+}
+
+// StreamedEvent is an event together with the channel it was published on
+type StreamedEvent struct {
+	Channel string
+	Event   Event
+}
+
+// Stream returns all events published to ChannelConsentRequest and ChannelConsentErrored from now on that match the filter.
+// The returned channel is closed when the context is done or at shutdown. ErrTooManyStreams is returned when maxStreams streams are open.
+func (octopus *EventOctopus) Stream(ctx context.Context, filter StreamFilter) (<-chan StreamedEvent, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	id, err := octopus.openStream(cancel)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	conn, err := octopus.client(streamClientID)
+	if err != nil {
+		octopus.closeStream(id)
+		return nil, err
+	}
+
+	out := make(chan StreamedEvent, streamBufferSize)
+
+	// guards out against sends after it has been closed
+	var mu sync.Mutex
+	closed := false
+
+	handler := func(channel string) natsClient.MsgHandler {
+		return func(msg *natsClient.Msg) {
+			event := Event{}
+			if err := json.Unmarshal(msg.Data, &event); err != nil {
+				logrus.WithError(err).Debugf("Not streaming unreadable message from %s", channel)
+				return
+			}
+			if !filter.matches(event) {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if closed {
+				return
+			}
+			select {
+			case out <- StreamedEvent{Channel: channel, Event: event}:
+			case <-ctx.Done():
+			}
+		}
+	}
+
+	// non-durable subscriptions only receive new events
+	var subscriptions []natsClient.Subscription
+	for _, channel := range []string{ChannelConsentRequest, ChannelConsentErrored} {
+		s, err := conn.Subscribe(channel, handler(channel))
+		if err != nil {
+			for _, s := range subscriptions {
+				_ = s.Unsubscribe()
+			}
+			octopus.closeStream(id)
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		for _, s := range subscriptions {
+			if err := s.Unsubscribe(); err != nil {
+				logrus.WithError(err).Debug("Error while unsubscribing event stream")
+			}
+		}
+
+		mu.Lock()
+		closed = true
+		close(out)
+		mu.Unlock()
+
+		octopus.closeStream(id)
+	}()
+
+	return out, nil
+}
+
+// openStream registers the cancel func of a new stream and returns its ID
+func (octopus *EventOctopus) openStream(cancel context.CancelFunc) (int, error) {
+	octopus.streamMutex.Lock()
+	defer octopus.streamMutex.Unlock()
+
+	if octopus.Config.MaxStreams > 0 && len(octopus.streams) >= octopus.Config.MaxStreams {
+		return 0, fmt.Errorf("%w: %d streams are open", ErrTooManyStreams, len(octopus.streams))
+	}
+
+	if octopus.streams == nil {
+		octopus.streams = map[int]context.CancelFunc{}
+	}
+	octopus.lastStream++
+	octopus.streams[octopus.lastStream] = cancel
+
+	return octopus.lastStream, nil
+}
+
+// closeStream cancels the stream and removes it from the open streams
+func (octopus *EventOctopus) closeStream(id int) {
+	octopus.streamMutex.Lock()
+	defer octopus.streamMutex.Unlock()
+
+	if cancel, ok := octopus.streams[id]; ok {
+		cancel()
+		delete(octopus.streams, id)
+	}
+}
+
+// closeStreams cancels all open streams, their channels are closed once the subscriptions are removed
+func (octopus *EventOctopus) closeStreams() {
+	octopus.streamMutex.Lock()
+	defer octopus.streamMutex.Unlock()
+
+	for id, cancel := range octopus.streams {
+		cancel()
+		delete(octopus.streams, id)
+	}
+}
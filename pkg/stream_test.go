@@ -0,0 +1,138 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamFilter_matches(t *testing.T) {
+	e := Event{ExternalID: "ext", InitiatorLegalEntity: "urn"}
+
+	assert.True(t, StreamFilter{}.matches(e))
+	assert.True(t, StreamFilter{ExternalID: "ext", InitiatorLegalEntity: "urn"}.matches(e))
+	assert.False(t, StreamFilter{ExternalID: "other"}.matches(e))
+	assert.False(t, StreamFilter{InitiatorLegalEntity: "other"}.matches(e))
+}
+
+func TestEventOctopus_Stream(t *testing.T) {
+	i := testEventOctopus()
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	stanClient := stanConnection()
+	defer stanClient.Close()
+
+	publish := func(channel string, externalID string) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.ExternalID = externalID
+		je, _ := json.Marshal(e)
+		_ = stanClient.Publish(channel, je)
+	}
+
+	receive := func(events <-chan StreamedEvent) *StreamedEvent {
+		select {
+		case e := <-events:
+			return &e
+		case <-time.After(time.Second):
+			return nil
+		}
+	}
+
+	t.Run("matching events of both channels are streamed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events, err := i.Stream(ctx, StreamFilter{ExternalID: "stream"})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		publish(ChannelConsentRequest, "other")
+		publish(ChannelConsentRequest, "stream")
+		publish(ChannelConsentErrored, "stream")
+
+		received := map[string]bool{}
+		for j := 0; j < 2; j++ {
+			if e := receive(events); assert.NotNil(t, e) {
+				assert.Equal(t, "stream", e.Event.ExternalID)
+				received[e.Channel] = true
+			}
+		}
+		assert.True(t, received[ChannelConsentRequest])
+		assert.True(t, received[ChannelConsentErrored])
+	})
+
+	t.Run("stream is closed when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		events, err := i.Stream(ctx, StreamFilter{})
+		cancel()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		select {
+		case _, ok := <-events:
+			assert.False(t, ok)
+		case <-time.After(time.Second):
+			t.Error("stream not closed")
+		}
+	})
+	t.Run("streams beyond maxStreams are refused", func(t *testing.T) {
+		i.Config.MaxStreams = 1
+		defer func() { i.Config.MaxStreams = 0 }()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		_, err := i.Stream(ctx, StreamFilter{})
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = i.Stream(context.Background(), StreamFilter{})
+
+		assert.True(t, errors.Is(err, ErrTooManyStreams))
+	})
+
+	t.Run("streams are closed at shutdown", func(t *testing.T) {
+		events, err := i.Stream(context.Background(), StreamFilter{})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		i.closeStreams()
+
+		select {
+		case _, ok := <-events:
+			assert.False(t, ok)
+		case <-time.After(time.Second):
+			t.Error("stream not closed")
+		}
+	})
+}
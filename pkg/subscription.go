@@ -0,0 +1,135 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"time"
+
+	natsClient "github.com/nats-io/stan.go"
+)
+
+// SubscribeOption configures the subscription made by Subscribe or SubscribeHandlers.
+// Options only apply to the first subscription for a service and subject, later calls only add handlers.
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	// manualAck acks events after they have been handled instead of when they are received
+	manualAck bool
+	// resume stores a checkpoint per event and starts after it when the service subscribes again
+	resume     bool
+	queueGroup string
+	// start is nil when the subscription resumes from the checkpoint of the service
+	start natsClient.SubscriptionOption
+	stan  []natsClient.SubscriptionOption
+	// ackWait is the time a handler has to process an event before Nats redelivers it
+	ackWait time.Duration
+}
+
+func newSubscribeOptions(opts []SubscribeOption) subscribeOptions {
+	options := subscribeOptions{ackWait: subscriptionAckWait}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// ManualAck acks an event after the handler returned instead of when it is received, so Nats redelivers it when
+// the service crashes while handling it. SubscribeHandlers always acks manually, for Subscribe it is opt-in.
+func ManualAck() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.manualAck = true
+	}
+}
+
+// ResumeFromCheckpoint stores the sequence of the last handled event, a service that subscribes again without
+// start option resumes after it. SubscribeHandlers always resumes from the checkpoint, for Subscribe it is opt-in.
+func ResumeFromCheckpoint() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.resume = true
+	}
+}
+
+// DurableName makes the subscription durable. The position is remembered when the service disconnects,
+// so events published while the service is down are delivered when it subscribes again with the same name.
+func DurableName(name string) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.stan = append(o.stan, natsClient.DurableName(name))
+	}
+}
+
+// QueueGroup adds the subscription to a queue group, each event is only delivered to one member of the group.
+// Instances of the same service use the same group to share the work.
+func QueueGroup(group string) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.queueGroup = group
+	}
+}
+
+// StartAtSequence delivers the events from the given sequence number on
+func StartAtSequence(sequence uint64) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.start = natsClient.StartAtSequence(sequence)
+	}
+}
+
+// StartAtTime delivers the events published from the given moment on
+func StartAtTime(start time.Time) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.start = natsClient.StartAtTime(start)
+	}
+}
+
+// StartWithLastReceived delivers the last published event and the events published after it
+func StartWithLastReceived() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.start = natsClient.StartWithLastReceived()
+	}
+}
+
+// MaxInFlight limits the number of events delivered to the service that have not been handled yet
+func MaxInFlight(max int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.stan = append(o.stan, natsClient.MaxInflight(max))
+	}
+}
+
+// AckWait sets the time a handler has to process an event, 30 seconds by default. The context of the handler is cancelled
+// after this time and Nats redelivers the event, so a handler that runs longer processes the same event twice.
+func AckWait(ackWait time.Duration) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.ackWait = ackWait
+	}
+}
+
+// subscribe makes the subscription, as member of the queue group when configured
+func (o subscribeOptions) subscribe(conn natsClient.Conn, subject string, cb natsClient.MsgHandler) (natsClient.Subscription, error) {
+	stanOptions := append([]natsClient.SubscriptionOption{natsClient.AckWait(o.ackWait)}, o.stan...)
+	if o.manualAck {
+		stanOptions = append(stanOptions, natsClient.SetManualAckMode())
+	}
+	if o.start != nil {
+		stanOptions = append(stanOptions, o.start)
+	}
+
+	if o.queueGroup != "" {
+		return conn.QueueSubscribe(subject, o.queueGroup, cb, stanOptions...)
+	}
+
+	return conn.Subscribe(subject, cb, stanOptions...)
+}
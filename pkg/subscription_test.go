@@ -0,0 +1,187 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventOctopus_SubscribeOptions(t *testing.T) {
+	i := testEventOctopus()
+	_ = i.startStanServer() // use startStanServer() instead of Start() so there will not be a service for the event-store
+	defer i.Shutdown()
+
+	publisher, _ := i.EventPublisher("event-octopus-test")
+
+	publish := func(subject string) Event {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = publisher.Publish(subject, e)
+		return e
+	}
+
+	// receive collects the uuids of the received events
+	receive := func(received chan string) map[string]EventHandlerCallback {
+		return map[string]EventHandlerCallback{
+			event().Name: func(event *Event) {
+				received <- event.UUID
+			},
+		}
+	}
+
+	// disconnect closes the connection of the service as if it stopped, without unsubscribing
+	disconnect := func(service string) {
+		_ = i.stanClients[service].Close()
+		delete(i.stanClients, service)
+		delete(i.channelHandlers, service)
+	}
+
+	t.Run("durable subscription receives the events published while disconnected", func(t *testing.T) {
+		received := make(chan string, 10)
+
+		if !assert.NoError(t, i.Subscribe("durable-service", "durable", receive(received), DurableName("durable-test"))) {
+			return
+		}
+		first := publish("durable")
+		assert.Equal(t, first.UUID, <-received)
+
+		disconnect("durable-service")
+		missed := publish("durable")
+
+		if !assert.NoError(t, i.Subscribe("durable-service", "durable", receive(received), DurableName("durable-test"))) {
+			return
+		}
+
+		select {
+		case u := <-received:
+			assert.Equal(t, missed.UUID, u)
+		case <-time.After(time.Second):
+			assert.Fail(t, "missed event not delivered")
+		}
+	})
+
+	t.Run("queue group members share the events", func(t *testing.T) {
+		received := make(chan string, 20)
+
+		for _, service := range []string{"queue-service-1", "queue-service-2"} {
+			if !assert.NoError(t, i.Subscribe(service, "queue", receive(received), QueueGroup("queue-test"))) {
+				return
+			}
+		}
+
+		for j := 0; j < 10; j++ {
+			publish("queue")
+		}
+
+		count := 0
+		for done := false; !done; {
+			select {
+			case <-received:
+				count++
+			case <-time.After(100 * time.Millisecond):
+				done = true
+			}
+		}
+		assert.Equal(t, 10, count)
+	})
+
+	t.Run("subscription starts at sequence", func(t *testing.T) {
+		var published []Event
+		for j := 0; j < 3; j++ {
+			published = append(published, publish("sequence"))
+		}
+
+		received := make(chan string, 10)
+		if !assert.NoError(t, i.Subscribe("sequence-service", "sequence", receive(received), StartAtSequence(2))) {
+			return
+		}
+
+		for _, e := range published[1:] {
+			select {
+			case u := <-received:
+				assert.Equal(t, e.UUID, u)
+			case <-time.After(time.Second):
+				assert.Fail(t, "event not delivered")
+			}
+		}
+	})
+
+	t.Run("subscription starts at time", func(t *testing.T) {
+		publish("time")
+		time.Sleep(10 * time.Millisecond)
+		start := time.Now()
+		e := publish("time")
+
+		received := make(chan string, 10)
+		if !assert.NoError(t, i.Subscribe("time-service", "time", receive(received), StartAtTime(start))) {
+			return
+		}
+
+		select {
+		case u := <-received:
+			assert.Equal(t, e.UUID, u)
+		case <-time.After(time.Second):
+			assert.Fail(t, "event not delivered")
+		}
+	})
+
+	t.Run("subscription starts with last received", func(t *testing.T) {
+		publish("last")
+		e := publish("last")
+
+		received := make(chan string, 10)
+		if !assert.NoError(t, i.Subscribe("last-service", "last", receive(received), StartWithLastReceived())) {
+			return
+		}
+
+		select {
+		case u := <-received:
+			assert.Equal(t, e.UUID, u)
+		case <-time.After(time.Second):
+			assert.Fail(t, "event not delivered")
+		}
+	})
+	t.Run("ack wait sets the deadline of the handler", func(t *testing.T) {
+		deadlines := make(chan time.Duration, 10)
+		err := i.SubscribeHandlers("ack-wait-service", "ackWait", map[string]EventHandler{
+			event().Name: func(ctx context.Context, event *Event) error {
+				deadline, _ := ctx.Deadline()
+				deadlines <- time.Until(deadline)
+				return nil
+			},
+		}, AckWait(2*time.Second))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		publish("ackWait")
+
+		select {
+		case d := <-deadlines:
+			assert.True(t, d > time.Second && d <= 2*time.Second, "unexpected deadline %s", d)
+		case <-time.After(time.Second):
+			assert.Fail(t, "event not delivered")
+		}
+	})
+}
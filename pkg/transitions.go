@@ -0,0 +1,102 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import "errors"
+
+// ErrInvalidTransition is returned when an event is stored with a state that can not follow its current state
+var ErrInvalidTransition = errors.New("invalid transition")
+
+// Transition holds the states that can follow a state of the consent request flow
+type Transition struct {
+	From string   `json:"from"`
+	To   []string `json:"to"`
+}
+
+// flow is the consent request flow in order, without the transitions that are valid for every state
+var flow = []Transition{
+	{EventConsentRequestConstructed, []string{EventConsentRequestInFlight, EventConsentRequestFlowErrored}},
+	{EventConsentRequestInFlight, []string{EventConsentRequestFlowSuccess, EventConsentRequestFlowErrored, EventDistributedConsentRequestReceived, EventClosed}},
+	{EventConsentRequestFlowErrored, []string{EventConsentRequestInFlight, EventClosed}},
+	{EventConsentRequestFlowSuccess, []string{EventDistributedConsentRequestReceived}},
+	{EventDistributedConsentRequestReceived, []string{EventAllSignaturesPresent, EventConsentRequestValid, EventClosed}},
+	{EventConsentRequestValid, []string{EventConsentRequestAcked, EventConsentRequestNacked}},
+	{EventConsentRequestAcked, []string{EventAttachmentSigned}},
+	{EventConsentRequestNacked, []string{EventInFinalFlight, EventClosed}},
+	{EventAttachmentSigned, []string{EventConsentRequestInFlight}},
+	{EventAllSignaturesPresent, []string{EventInFinalFlight}},
+	{EventInFinalFlight, []string{EventConsentDistributed, EventConsentRequestFlowErrored, EventClosed}},
+	{EventConsentDistributed, []string{EventCompleted}},
+	{EventCompleted, nil},
+	{EventClosed, nil},
+	{EventErrored, nil},
+}
+
+// States returns all states of the consent request flow
+func States() []string {
+	states := make([]string, len(flow))
+	for i, t := range flow {
+		states[i] = t.From
+	}
+	return states
+}
+
+// Transitions returns the complete consent request flow: for each state the states that can follow it.
+// Every state can be followed by itself, which happens when an event is retried, and by EventErrored.
+// An errored event can be followed by any state, so it can be retried from the state it had before the error.
+func Transitions() []Transition {
+	transitions := make([]Transition, len(flow))
+	for i, t := range flow {
+		transitions[i] = Transition{From: t.From}
+		for _, to := range States() {
+			if ValidTransition(t.From, to) {
+				transitions[i].To = append(transitions[i].To, to)
+			}
+		}
+	}
+	return transitions
+}
+
+// ValidTransition returns true if an event in the from state can be stored with the to state.
+// States that are not part of the flow, for instance those of other modules, can follow any state and can get any state.
+func ValidTransition(from, to string) bool {
+	if from == to || to == EventErrored || from == EventErrored || !isState(from) || !isState(to) {
+		return true
+	}
+	for _, t := range flow {
+		if t.From != from {
+			continue
+		}
+		for _, next := range t.To {
+			if next == to {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isState(state string) bool {
+	for _, t := range flow {
+		if t.From == state {
+			return true
+		}
+	}
+	return false
+}
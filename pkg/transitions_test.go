@@ -0,0 +1,156 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		valid    bool
+	}{
+		{EventConsentRequestConstructed, EventConsentRequestInFlight, true},
+		{EventConsentRequestInFlight, EventDistributedConsentRequestReceived, true},
+		{EventConsentRequestAcked, EventAttachmentSigned, true},
+		{EventConsentDistributed, EventCompleted, true},
+		{EventCompleted, EventCompleted, true},
+		{EventCompleted, EventErrored, true},
+		{EventErrored, EventConsentRequestInFlight, true},
+		{"unknown", EventConsentRequestInFlight, true},
+		{EventCompleted, EventConsentRequestInFlight, false},
+		{EventConsentRequestConstructed, EventCompleted, false},
+		{EventConsentRequestValid, EventAttachmentSigned, false},
+		{EventConsentRequestConstructed, "unknown", true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.valid, ValidTransition(test.from, test.to), "%s -> %s", test.from, test.to)
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	transitions := Transitions()
+
+	assert.Len(t, transitions, len(States()))
+	assert.Equal(t, EventConsentRequestConstructed, transitions[0].From)
+	assert.Equal(t, []string{EventConsentRequestConstructed, EventConsentRequestInFlight, EventConsentRequestFlowErrored, EventErrored}, transitions[0].To)
+
+	for _, transition := range transitions {
+		switch transition.From {
+		case EventCompleted:
+			assert.Equal(t, []string{EventCompleted, EventErrored}, transition.To)
+		case EventErrored:
+			assert.Equal(t, States(), transition.To)
+		}
+	}
+}
+
+func TestEventOctopus_SaveOrUpdateEvent_transitions(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:transitions?mode=memory&cache=shared"
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	t.Run("invalid transition is rejected and recorded", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Name = EventConsentDistributed
+		_ = i.SaveOrUpdateEvent(e)
+		e.Name = EventCompleted
+		_ = i.SaveOrUpdateEvent(e)
+
+		e.Name = EventConsentRequestInFlight
+		err := i.SaveOrUpdateEvent(e)
+
+		assert.True(t, errors.Is(err, ErrInvalidTransition))
+		stored, _ := i.GetEvent(e.UUID)
+		assert.Equal(t, EventCompleted, stored.Name)
+
+		history, _ := i.GetEventHistory(e.UUID)
+		if assert.Len(t, history, 3) {
+			rejected := history[2]
+			assert.True(t, rejected.Rejected)
+			assert.Equal(t, EventConsentRequestInFlight, rejected.Name)
+			if assert.NotNil(t, rejected.Error) {
+				assert.Equal(t, "invalid transition from completed to consentRequest in flight", *rejected.Error)
+			}
+			assert.False(t, history[1].Rejected)
+		}
+	})
+
+	t.Run("rejection is recorded once", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Name = EventCompleted
+		_ = i.SaveOrUpdateEvent(e)
+
+		// redeliveries of the same message
+		e.Name = EventConsentRequestInFlight
+		_ = i.SaveOrUpdateEvent(e)
+		err := i.SaveOrUpdateEvent(e)
+
+		assert.True(t, errors.Is(err, ErrInvalidTransition))
+		history, _ := i.GetEventHistory(e.UUID)
+		assert.Len(t, history, 2)
+	})
+
+	t.Run("states of other modules are accepted", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		_ = i.SaveOrUpdateEvent(e)
+		e.Name = "vendor state"
+
+		assert.NoError(t, i.SaveOrUpdateEvent(e))
+		e.Name = EventConsentRequestInFlight
+		assert.NoError(t, i.SaveOrUpdateEvent(e))
+	})
+
+	t.Run("new event can start in any state", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Name = EventDistributedConsentRequestReceived
+
+		assert.NoError(t, i.SaveOrUpdateEvent(e))
+	})
+
+	t.Run("manual retry ignores rejected transitions", func(t *testing.T) {
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Name = EventConsentRequestInFlight
+		_ = i.SaveOrUpdateEvent(e)
+		e.Name = EventAttachmentSigned
+		_ = i.SaveOrUpdateEvent(e)
+		e.Name = EventErrored
+		_ = i.SaveOrUpdateEvent(e)
+
+		name, err := i.lastStateBefore(e.UUID, EventErrored)
+
+		assert.NoError(t, err)
+		assert.Equal(t, EventConsentRequestInFlight, name)
+	})
+}
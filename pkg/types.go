@@ -18,7 +18,11 @@
 
 package pkg
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"time"
+)
 
 // Event is the type used for Gorm
 type Event struct {
@@ -31,15 +35,54 @@ type Event struct {
 	RetryCount           int     `json:"retryCount"`
 	Name                 string  `gorm:"not null" json:"name"`
 	UUID                 string  `gorm:"PRIMARY_KEY" json:"uuid"`
+	// CreatedAt, UpdatedAt and TransitionedAt are maintained by the event store
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	TransitionedAt time.Time `json:"transitionedAt"`
+	// Version is incremented on every change, saving an event with a stale version fails with a ConflictError
+	Version int `gorm:"not null" json:"version"`
+	// ErrorClass classifies the error, together with the name it selects the retry policy of the event
+	ErrorClass *string `json:"errorClass"`
 }
 
 func (e Event) String() string {
 	return fmt.Sprintf("Name: %v, uuid: %v, externalId: %v, retryCount: %v, error: %v", e.Name, e.UUID, e.ExternalID, e.RetryCount, e.Error)
 }
 
+// EventHistory is a single state transition of an Event, stored in an append-only log
+type EventHistory struct {
+	ID         uint      `gorm:"PRIMARY_KEY" json:"-"`
+	UUID       string    `gorm:"column:event_uuid;not null" json:"uuid"`
+	Name       string    `gorm:"not null" json:"name"`
+	RetryCount int       `json:"retryCount"`
+	Error      *string   `json:"error"`
+	Channel    string    `json:"channel"`
+	CreatedAt  time.Time `json:"timestamp"`
+	// Rejected is true when the transition was invalid and did not change the event
+	Rejected bool `json:"rejected"`
+}
+
+// TableName returns the name of the append-only event history table
+func (EventHistory) TableName() string {
+	return "event_history"
+}
+
 // EventHandlerCallback defines the signature of an event handler method.
 type EventHandlerCallback func(event *Event)
 
+// handler converts the callback to an EventHandler that never fails
+func (callback EventHandlerCallback) handler() EventHandler {
+	return func(ctx context.Context, event *Event) error {
+		callback(event)
+		return nil
+	}
+}
+
+// EventHandler defines the signature of an event handler method that can fail.
+// The context is cancelled when the handler runs out of time to process the event or when the subscription ends.
+// A returned error sends the event to ChannelConsentRetry, unless it is a NonRetryableError.
+type EventHandler func(ctx context.Context, event *Event) error
+
 // EventConsentRequestConstructed is the event emitted directly after consent request creation to start the flow
 const EventConsentRequestConstructed = "consentRequest constructed"
 
@@ -0,0 +1,331 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	natsClient "github.com/nats-io/stan.go"
+	uuid "github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ChannelWebhookDelivery is the channel on which webhook notifications are queued for delivery
+const ChannelWebhookDelivery = "webhookDelivery"
+
+// ChannelWebhookRetry is the prefix of the channels on which failed webhook deliveries wait for their next attempt
+const ChannelWebhookRetry = "webhookRetry"
+
+// WebhookSignatureHeader holds the hex encoded HMAC-SHA256 of the timestamp and the body, signed with the secret of the webhook
+const WebhookSignatureHeader = "X-Nuts-Signature"
+
+// WebhookTimestampHeader holds the moment of delivery in seconds since the Unix epoch, it is part of the signature
+const WebhookTimestampHeader = "X-Nuts-Timestamp"
+
+// WebhookIDHeader holds the ID of the webhook a notification is delivered for
+const WebhookIDHeader = "X-Nuts-Webhook-Id"
+
+// webhookTimeout is the maximum duration of a single delivery
+const webhookTimeout = 10 * time.Second
+
+// ErrInvalidWebhook is returned when a webhook is registered with an invalid URL
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// Webhook is a registration for being notified of event state changes
+type Webhook struct {
+	ID  string `gorm:"PRIMARY_KEY" json:"id"`
+	URL string `gorm:"not null" json:"url"`
+	// EventNames is a comma separated list of event names to be notified of, empty for all events
+	EventNames           string    `json:"eventNames"`
+	InitiatorLegalEntity string    `json:"initiatorLegalEntity"`
+	Secret               string    `json:"-"`
+	CreatedAt            time.Time `json:"createdAt"`
+}
+
+// Names returns the event names of the webhook
+func (w Webhook) Names() []string {
+	if w.EventNames == "" {
+		return nil
+	}
+	return strings.Split(w.EventNames, ",")
+}
+
+func (w Webhook) matches(event Event) bool {
+	if w.InitiatorLegalEntity != "" && w.InitiatorLegalEntity != event.InitiatorLegalEntity {
+		return false
+	}
+	names := w.Names()
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if n == event.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// sign returns the hex encoded HMAC-SHA256 of the timestamp and the body, joined by a dot
+func (w Webhook) sign(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(w.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookDelivery is a single notification queued for delivery
+type webhookDelivery struct {
+	WebhookID string `json:"webhookId"`
+	Attempt   int    `json:"attempt"`
+	Event     Event  `json:"event"`
+}
+
+// RegisterWebhook stores a new webhook, an ID is generated. The secret is stored encrypted when a KeyProvider is set.
+func (octopus *EventOctopus) RegisterWebhook(webhook Webhook) (*Webhook, error) {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http(s) url: %s", ErrInvalidWebhook, webhook.URL)
+	}
+	for _, n := range webhook.Names() {
+		if n == "" {
+			return nil, fmt.Errorf("%w: empty event name", ErrInvalidWebhook)
+		}
+	}
+
+	webhook.ID = uuid.NewV4().String()
+	webhook.CreatedAt = now()
+
+	stored := webhook
+	if webhook.Secret != "" {
+		if stored.Secret, err = octopus.encryptPayload(webhook.Secret, webhook.ID); err != nil {
+			return nil, fmt.Errorf("Error while encrypting secret of webhook %s: %w", webhook.ID, err)
+		}
+	}
+
+	if err := octopus.Db.Debug().Create(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// Webhooks returns all registered webhooks.
+// A webhook of which the secret can't be decrypted keeps its stored secret, so it does not hide the other webhooks.
+func (octopus *EventOctopus) Webhooks() ([]Webhook, error) {
+	var webhooks []Webhook
+
+	if err := octopus.Db.Debug().Order("created_at").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range webhooks {
+		if err := octopus.decryptSecret(&webhooks[i]); err != nil {
+			logrus.WithError(err).Error("Returning webhook with its secret as stored")
+		}
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhook returns a single webhook or nil when not found
+func (octopus *EventOctopus) GetWebhook(id string) (*Webhook, error) {
+	webhook := &Webhook{}
+
+	err := octopus.Db.Debug().Where("id = ?", id).First(webhook).Error
+
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := octopus.decryptSecret(webhook); err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// decryptSecret decrypts the secret of a webhook read from the event store in place
+func (octopus *EventOctopus) decryptSecret(webhook *Webhook) error {
+	secret, err := octopus.decryptPayload(webhook.Secret, webhook.ID)
+	if err != nil {
+		return fmt.Errorf("Error while decrypting secret of webhook %s: %w", webhook.ID, err)
+	}
+
+	webhook.Secret = secret
+	return nil
+}
+
+// DeleteWebhook removes a webhook, it returns false when the webhook did not exist
+func (octopus *EventOctopus) DeleteWebhook(id string) (bool, error) {
+	db := octopus.Db.Debug().Where("id = ?", id).Delete(Webhook{})
+
+	return db.RowsAffected > 0, db.Error
+}
+
+// notifyWebhooks queues a delivery for every webhook matching the event. The event store selects the webhooks for the legal
+// entity that may be interested in the event name, matches checks the names exactly.
+func (octopus *EventOctopus) notifyWebhooks(event Event) error {
+	var webhooks []Webhook
+
+	err := octopus.Db.Debug().Select("id, event_names, initiator_legal_entity").
+		Where("initiator_legal_entity IS NULL OR initiator_legal_entity = '' OR initiator_legal_entity = ?", event.InitiatorLegalEntity).
+		Where("event_names IS NULL OR event_names = '' OR ',' || event_names || ',' LIKE ?", "%,"+event.Name+",%").
+		Order("created_at").Find(&webhooks).Error
+	if err != nil {
+		return err
+	}
+
+	for _, w := range webhooks {
+		if !w.matches(event) {
+			continue
+		}
+		if err := octopus.publishWebhookDelivery(ChannelWebhookDelivery, webhookDelivery{WebhookID: w.ID, Event: event}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (octopus *EventOctopus) publishWebhookDelivery(channel string, delivery webhookDelivery) error {
+	conn, err := octopus.client(ClientID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	return conn.Publish(channel, data)
+}
+
+// startWebhookSubscribers subscribes to the delivery channel and starts the delayed consumers of the retry channels
+func (octopus *EventOctopus) startWebhookSubscribers(sc natsClient.Conn) error {
+	err := octopus.subscribe(sc, ChannelWebhookDelivery, func(msg *natsClient.Msg) error {
+		delivery := webhookDelivery{}
+		if err := json.Unmarshal(msg.Data, &delivery); err != nil {
+			logrus.WithError(err).Error("Error unmarshalling webhook delivery")
+			return octopus.deadLetter(msg, err.Error())
+		}
+
+		// the message is acked when the event has been delivered or the failed delivery is queued for its next attempt
+		return octopus.deliverWebhook(delivery)
+	}, natsClient.DurableName("webhook-delivery-durable"),
+		natsClient.StartWithLastReceived(),
+	)
+	if err != nil {
+		return err
+	}
+
+	octopus.webhookConsumers = NewDelayedConsumerSet(ChannelWebhookRetry, ChannelWebhookDelivery, octopus.Config.WebhookMaxRetryCount, time.Second, octopus.Config.WebhookIncrementalBackoff, sc, octopus)
+	for _, dc := range octopus.webhookConsumers {
+		if err := dc.Start(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deliverWebhook posts the event to the webhook, failed deliveries are published to the retry channel for their attempt.
+// An error is returned when the webhook could not be read or the failed delivery could not be queued, so it is delivered again.
+func (octopus *EventOctopus) deliverWebhook(delivery webhookDelivery) error {
+	webhook, err := octopus.GetWebhook(delivery.WebhookID)
+	if err != nil {
+		return fmt.Errorf("Error while fetching webhook %s: %w", delivery.WebhookID, err)
+	}
+	if webhook == nil {
+		logrus.Debugf("Dropping delivery for removed webhook %s", delivery.WebhookID)
+		return nil
+	}
+
+	err = webhook.post(delivery.Event)
+	if err == nil {
+		logrus.Debugf("Delivered event %s to webhook %s", delivery.Event.UUID, webhook.ID)
+		return nil
+	}
+
+	if delivery.Attempt >= octopus.Config.WebhookMaxRetryCount {
+		logrus.WithError(err).Errorf("Giving up delivery of event %s to webhook %s after %d attempts", delivery.Event.UUID, webhook.ID, delivery.Attempt+1)
+		return nil
+	}
+
+	logrus.WithError(err).Warnf("Delivery of event %s to webhook %s failed, retrying", delivery.Event.UUID, webhook.ID)
+
+	channel := fmt.Sprintf("%s-%d", ChannelWebhookRetry, delivery.Attempt)
+	delivery.Attempt++
+	if err := octopus.publishWebhookDelivery(channel, delivery); err != nil {
+		return fmt.Errorf("Error while publishing webhook delivery to retry channel %s: %w", channel, err)
+	}
+
+	return nil
+}
+
+// post sends the event as JSON, any response other than 2xx is an error
+func (w Webhook) post(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, w.ID)
+	if w.Secret != "" {
+		timestamp := strconv.FormatInt(now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+w.sign(timestamp, body))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,254 @@
+/*
+ * Nuts event octopus
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pkg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// webhookCall is a single request received by a test webhook server
+type webhookCall struct {
+	signature string
+	timestamp string
+	body      []byte
+}
+
+// webhookServer returns a server that responds with the given status codes in order and 200 after that
+func webhookServer(statusCodes ...int) (*httptest.Server, chan webhookCall) {
+	calls := make(chan webhookCall, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		calls <- webhookCall{signature: r.Header.Get(WebhookSignatureHeader), timestamp: r.Header.Get(WebhookTimestampHeader), body: body}
+
+		if len(statusCodes) > 0 {
+			w.WriteHeader(statusCodes[0])
+			statusCodes = statusCodes[1:]
+		}
+	}))
+	return server, calls
+}
+
+func receiveCall(calls chan webhookCall, timeout time.Duration) *webhookCall {
+	select {
+	case c := <-calls:
+		return &c
+	case <-time.After(timeout):
+		return nil
+	}
+}
+
+func TestWebhook_matches(t *testing.T) {
+	e := Event{Name: EventCompleted, InitiatorLegalEntity: "urn"}
+
+	assert.True(t, Webhook{}.matches(e))
+	assert.True(t, Webhook{EventNames: EventErrored + "," + EventCompleted, InitiatorLegalEntity: "urn"}.matches(e))
+	assert.False(t, Webhook{EventNames: EventErrored}.matches(e))
+	assert.False(t, Webhook{InitiatorLegalEntity: "other"}.matches(e))
+}
+
+func TestEventOctopus_Webhooks(t *testing.T) {
+	i := testEventOctopus()
+	i.Config.Connectionstring = "file:webhooks?mode=memory&cache=shared"
+	i.configure()
+	if err := i.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Shutdown()
+
+	t.Run("registration is stored", func(t *testing.T) {
+		w, err := i.RegisterWebhook(Webhook{URL: "http://localhost/hook", EventNames: EventCompleted, Secret: "secret"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NotEmpty(t, w.ID)
+
+		stored, err := i.GetWebhook(w.ID)
+		if assert.NoError(t, err) && assert.NotNil(t, stored) {
+			assert.Equal(t, "http://localhost/hook", stored.URL)
+			assert.Equal(t, []string{EventCompleted}, stored.Names())
+			assert.Equal(t, "secret", stored.Secret)
+		}
+
+		deleted, err := i.DeleteWebhook(w.ID)
+		assert.NoError(t, err)
+		assert.True(t, deleted)
+
+		stored, err = i.GetWebhook(w.ID)
+		assert.NoError(t, err)
+		assert.Nil(t, stored)
+	})
+
+	t.Run("secret is stored encrypted", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "webhooks")
+		defer os.RemoveAll(dir)
+		i.KeyProvider, _ = NewLocalKeyProvider(writeKeyFile(t, dir, "key-1 "+randomKey()))
+		defer func() { i.KeyProvider = nil }()
+
+		w, err := i.RegisterWebhook(Webhook{URL: "http://localhost/hook", Secret: "secret"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer i.DeleteWebhook(w.ID)
+		assert.Equal(t, "secret", w.Secret)
+
+		stored := Webhook{}
+		i.Db.Where("id = ?", w.ID).First(&stored)
+		assert.True(t, strings.HasPrefix(stored.Secret, encryptedPrefix))
+
+		read, err := i.GetWebhook(w.ID)
+		if assert.NoError(t, err) && assert.NotNil(t, read) {
+			assert.Equal(t, "secret", read.Secret)
+		}
+	})
+
+	t.Run("secrets stored in plain text are encrypted", func(t *testing.T) {
+		w, _ := i.RegisterWebhook(Webhook{URL: "http://localhost/hook", Secret: "secret"})
+		defer i.DeleteWebhook(w.ID)
+
+		dir, _ := ioutil.TempDir("", "webhooks")
+		defer os.RemoveAll(dir)
+		i.KeyProvider, _ = NewLocalKeyProvider(writeKeyFile(t, dir, "key-1 "+randomKey()))
+		defer func() { i.KeyProvider = nil }()
+
+		_, err := i.EncryptPayloads()
+
+		if assert.NoError(t, err) {
+			stored := Webhook{}
+			i.Db.Where("id = ?", w.ID).First(&stored)
+			assert.True(t, strings.HasPrefix(stored.Secret, encryptedPrefix))
+		}
+	})
+
+	t.Run("invalid url gives error", func(t *testing.T) {
+		for _, u := range []string{"", "localhost/hook", "ftp://localhost/hook"} {
+			_, err := i.RegisterWebhook(Webhook{URL: u})
+			assert.True(t, errors.Is(err, ErrInvalidWebhook), u)
+		}
+	})
+
+	t.Run("deleting unknown webhook returns false", func(t *testing.T) {
+		deleted, err := i.DeleteWebhook(uuid.NewV4().String())
+
+		assert.NoError(t, err)
+		assert.False(t, deleted)
+	})
+
+	t.Run("matching transitions are delivered with signature", func(t *testing.T) {
+		server, calls := webhookServer()
+		defer server.Close()
+		w, _ := i.RegisterWebhook(Webhook{URL: server.URL, EventNames: EventCompleted, Secret: "secret"})
+		defer i.DeleteWebhook(w.ID)
+
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Name = EventConsentDistributed
+		i.SaveOrUpdateEvent(e)
+		e.Name = EventCompleted
+		i.SaveOrUpdateEvent(e)
+		// no transition
+		i.SaveOrUpdateEvent(e)
+
+		call := receiveCall(calls, time.Second)
+		if !assert.NotNil(t, call) {
+			return
+		}
+		timestamp, err := strconv.ParseInt(call.timestamp, 10, 64)
+		if assert.NoError(t, err) {
+			assert.WithinDuration(t, time.Now(), time.Unix(timestamp, 0), time.Minute)
+		}
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte(call.timestamp + "."))
+		mac.Write(call.body)
+		assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), call.signature)
+
+		delivered := Event{}
+		if assert.NoError(t, json.Unmarshal(call.body, &delivered)) {
+			assert.Equal(t, e.UUID, delivered.UUID)
+			assert.Equal(t, EventCompleted, delivered.Name)
+		}
+
+		assert.Nil(t, receiveCall(calls, 200*time.Millisecond))
+	})
+
+	t.Run("webhooks of other legal entities and events are not notified", func(t *testing.T) {
+		server, calls := webhookServer()
+		defer server.Close()
+		other, _ := i.RegisterWebhook(Webhook{URL: server.URL, InitiatorLegalEntity: "other"})
+		defer i.DeleteWebhook(other.ID)
+		names, _ := i.RegisterWebhook(Webhook{URL: server.URL, EventNames: EventErrored + "," + EventCompleted + "d"})
+		defer i.DeleteWebhook(names.ID)
+
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		e.Name = EventCompleted
+		i.SaveOrUpdateEvent(e)
+
+		assert.Nil(t, receiveCall(calls, 200*time.Millisecond))
+	})
+
+	t.Run("delivery that can't be queued for a retry is not acked", func(t *testing.T) {
+		server, _ := webhookServer(http.StatusInternalServerError)
+		defer server.Close()
+		w, _ := i.RegisterWebhook(Webhook{URL: server.URL})
+		defer i.DeleteWebhook(w.ID)
+
+		// no Nats server to publish the retry to
+		unconnected := testEventOctopus()
+		unconnected.Db = i.Db
+		unconnected.Config.NatsPort = 1
+
+		err := unconnected.deliverWebhook(webhookDelivery{WebhookID: w.ID, Event: event()})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("failed delivery is retried", func(t *testing.T) {
+		server, calls := webhookServer(http.StatusInternalServerError)
+		defer server.Close()
+		w, _ := i.RegisterWebhook(Webhook{URL: server.URL})
+		defer i.DeleteWebhook(w.ID)
+
+		e := event()
+		e.UUID = uuid.NewV4().String()
+		i.SaveOrUpdateEvent(e)
+
+		assert.NotNil(t, receiveCall(calls, time.Second))
+		// first retry queue waits for a second
+		if call := receiveCall(calls, 3*time.Second); assert.NotNil(t, call) {
+			delivered := Event{}
+			json.Unmarshal(call.body, &delivered)
+			assert.Equal(t, e.UUID, delivered.UUID)
+		}
+	})
+}